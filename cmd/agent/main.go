@@ -9,6 +9,7 @@ import (
 	"github.com/desepticon55/metrics-collector/internal/common"
 	"go.uber.org/zap"
 	"golang.org/x/time/rate"
+	"io"
 	"log"
 	"net/http"
 	_ "net/http/pprof"
@@ -125,13 +126,18 @@ func main() {
 	}()
 
 	wg.Wait()
+	if closer, ok := sender.(io.Closer); ok {
+		if err := closer.Close(); err != nil {
+			logger.Error("Error during close sender", zap.Error(err))
+		}
+	}
 	logger.Info("Graceful shutdown completed")
 
 }
 
 func makeSender(config agent.Config) agent.MetricsSender {
 	if config.EnabledGRPC {
-		return agent.NewGRPCSender(config)
+		return agent.NewGRPCStreamSender(config)
 	}
 	return agent.NewHTTPSender(config)
 }
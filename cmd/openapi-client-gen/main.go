@@ -0,0 +1,26 @@
+package main
+
+import (
+	"flag"
+	"github.com/desepticon55/metrics-collector/internal/server/api/openapi"
+	"log"
+	"os"
+)
+
+func main() {
+	output := flag.String("o", "client.gen.go", "path of generated file")
+	packageName := flag.String("package", "client", "package of generated file")
+	flag.Parse()
+
+	document, err := openapi.Load()
+	if err != nil {
+		log.Fatal(err)
+	}
+	source, err := openapi.GenerateClient(document, *packageName)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if err := os.WriteFile(*output, source, 0o644); err != nil {
+		log.Fatal(err)
+	}
+}
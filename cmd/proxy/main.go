@@ -0,0 +1,131 @@
+package main
+
+import (
+	"encoding/json"
+	"flag"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/proxy"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/api/interceptors"
+	handler "github.com/desepticon55/metrics-collector/internal/server/api/metrics/grpc"
+	metricsApi "github.com/desepticon55/metrics-collector/internal/server/api/metrics/http"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics/influx"
+	customMiddleware "github.com/desepticon55/metrics-collector/internal/server/api/middleware"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	"github.com/desepticon55/metrics-collector/proto/metrics"
+	metricsv2 "github.com/desepticon55/metrics-collector/proto/metrics/v2"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"log"
+	"net"
+	"net/http"
+	"os"
+	"time"
+)
+
+var (
+	buildVersion = "N/A"
+	buildDate    = "N/A"
+	buildCommit  = "N/A"
+)
+
+func main() {
+	fmt.Printf("Build version: %s\n", buildVersion)
+	fmt.Printf("Build date: %s\n", buildDate)
+	fmt.Printf("Build commit: %s\n", buildCommit)
+
+	logger, err := common.NewLogger()
+	if err != nil {
+		log.Fatal("Error during initialise logger", err)
+	}
+	defer logger.Sync()
+
+	config := extractConfig()
+	flag.Parse()
+	logger.Info("Current config:", zap.String("config", config.String()))
+
+	service := proxy.NewService(config.VirtualNodes, config.Backends, config.HashKey, logger)
+	// incoming requests are verified with the same key which signs requests to backends
+	serverConfig := server.Config{HashKey: config.HashKey}
+	verifier := signing.NewDefaultVerifier(config.HashKey)
+	runGRPCServer(config, serverConfig, verifier, service, logger)
+
+	router := chi.NewRouter()
+	router.Use(middleware.Recoverer)
+	router.Use(middleware.RequestID)
+	router.Use(middleware.RealIP)
+	router.Use(customMiddleware.LoggingMiddleware(logger))
+	router.Use(customMiddleware.CompressingMiddleware())
+	router.Use(customMiddleware.DecompressingMiddleware())
+	router.Use(middleware.Timeout(60 * time.Second))
+
+	router.Method(http.MethodGet, "/", metricsApi.NewFindAllMetricsHandler(service, logger))
+	router.Method(http.MethodGet, "/value/{type}/{name}", metricsApi.NewFindMetricValueHandler(service, logger))
+	router.Method(http.MethodPost, "/value/", metricsApi.NewFindOneMetricHandler(service, service, logger))
+	router.Method(http.MethodPost, "/update/{type}/{name}/{value}", metricsApi.NewCreateMetricHandler(service, logger))
+	router.Method(http.MethodPost, "/update/", metricsApi.NewCreateMetricHandlerFromJSON(service, logger))
+	router.Method(http.MethodPost, "/updates/", metricsApi.NewCreateListMetricsHandlerFromJSON(serverConfig, verifier, service, logger))
+	router.Method(http.MethodPost, "/write", influx.NewWriteHandler(service, logger))
+	router.Method(http.MethodGet, "/rate/counter/{name}", metricsApi.NewFindCounterRateHandler(service, logger))
+	router.Method(http.MethodGet, "/backends", proxy.NewFindBackendsHandler(service, logger))
+	// backends can be changed only from trusted subnet with signed body, route is not registered without both of them
+	if config.TrustedSubnet != "" && verifier != nil {
+		router.With(customMiddleware.TrustedSubnetMiddleware(config.TrustedSubnet)).
+			Method(http.MethodPut, "/backends", proxy.NewUpdateBackendsHandler(service, verifier, logger))
+	} else {
+		logger.Warn("Backends can't be changed by request without trusted subnet and hash key")
+	}
+
+	logger.Debug("Proxy is running", zap.String("Server address", config.ServerAddress), zap.Strings("Backends", service.Backends()))
+	if err := http.ListenAndServe(config.ServerAddress, router); err != nil {
+		logger.Error("Error during start proxy", zap.Error(err))
+	}
+}
+
+func runGRPCServer(config proxy.Config, serverConfig server.Config, verifier *signing.Verifier, service *proxy.Service, logger *zap.Logger) {
+	if config.GRPCAddress == "" {
+		return
+	}
+
+	lis, err := net.Listen("tcp", config.GRPCAddress)
+	if err != nil {
+		logger.Fatal("Failed start GRPC server", zap.Error(err))
+	}
+
+	s := grpc.NewServer(interceptors.ServerOptions(serverConfig, verifier, logger)...)
+	metrics.RegisterMetricsServiceServer(s, &handler.MetricsServer{
+		Service: service,
+		Logger:  logger,
+	})
+	metricsv2.RegisterMetricsServiceServer(s, &handler.MetricsServerV2{
+		Service: service,
+		Logger:  logger,
+	})
+
+	go func() {
+		logger.Debug("gRPC server is running", zap.String("gRPC address", config.GRPCAddress))
+		if err := s.Serve(lis); err != nil {
+			logger.Error("Failed to serve", zap.Error(err))
+		}
+	}()
+}
+
+func extractConfig() proxy.Config {
+	return proxy.GetConfig(func(filePath string) (proxy.Config, error) {
+		var config proxy.Config
+		fileContent, err := os.ReadFile(filePath)
+		if err != nil {
+			return config, fmt.Errorf("could not read config file: %w", err)
+		}
+
+		err = json.Unmarshal(fileContent, &config)
+		if err != nil {
+			return config, fmt.Errorf("could not unmarshal config JSON: %w", err)
+		}
+
+		return config, nil
+	})
+}
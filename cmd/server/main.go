@@ -3,18 +3,40 @@ package main
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"flag"
 	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/agent"
 	"github.com/desepticon55/metrics-collector/internal/common"
 	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/api/interceptors"
+	metricsContract "github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics/graphite"
 	handler "github.com/desepticon55/metrics-collector/internal/server/api/metrics/grpc"
 	metricsApi "github.com/desepticon55/metrics-collector/internal/server/api/metrics/http"
+	metricsApiV2 "github.com/desepticon55/metrics-collector/internal/server/api/metrics/http/v2"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics/influx"
 	customMiddleware "github.com/desepticon55/metrics-collector/internal/server/api/middleware"
+	"github.com/desepticon55/metrics-collector/internal/server/api/openapi"
+	"github.com/desepticon55/metrics-collector/internal/server/expr"
 	metricsMappers "github.com/desepticon55/metrics-collector/internal/server/mapper/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/alerts"
+	"github.com/desepticon55/metrics-collector/internal/server/service/anomaly"
+	"github.com/desepticon55/metrics-collector/internal/server/service/federation"
+	"github.com/desepticon55/metrics-collector/internal/server/service/forecast"
+	"github.com/desepticon55/metrics-collector/internal/server/service/history"
 	metricsServices "github.com/desepticon55/metrics-collector/internal/server/service/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/notify"
+	"github.com/desepticon55/metrics-collector/internal/server/service/recording"
+	"github.com/desepticon55/metrics-collector/internal/server/service/replication"
+	"github.com/desepticon55/metrics-collector/internal/server/service/silences"
+	"github.com/desepticon55/metrics-collector/internal/server/service/slo"
+	"github.com/desepticon55/metrics-collector/internal/server/service/stream"
 	"github.com/desepticon55/metrics-collector/internal/server/storage/memory"
 	"github.com/desepticon55/metrics-collector/internal/server/storage/postgres"
+	"github.com/desepticon55/metrics-collector/internal/signing"
 	"github.com/desepticon55/metrics-collector/proto/metrics"
+	metricsv2 "github.com/desepticon55/metrics-collector/proto/metrics/v2"
 	"github.com/go-chi/chi/v5"
 	"github.com/go-chi/chi/v5/middleware"
 	"github.com/go-playground/validator/v10"
@@ -24,14 +46,37 @@ import (
 	"github.com/pressly/goose/v3"
 	"go.uber.org/zap"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/reflection"
 	"log"
 	"net"
 	"net/http"
 	_ "net/http/pprof"
 	"os"
+	"os/signal"
+	"strconv"
+	"sync"
+	"syscall"
 	"time"
 )
 
+// Number of the last metric changes kept to resume interrupted streams
+const (
+	streamHistorySize = 10000
+	historyRetention  = time.Hour
+	upstreamQueueSize = 10000
+)
+
+// Storage health reported by gRPC health service is checked with this interval.
+// Servers are stopped forcibly when active requests and streams don't finish during shutdown timeout
+const (
+	healthCheckInterval = 5 * time.Second
+	shutdownTimeout     = 10 * time.Second
+)
+
 var (
 	buildVersion = "N/A"
 	buildDate    = "N/A"
@@ -51,80 +96,441 @@ func main() {
 
 	config := extractConfig(logger)
 	flag.Parse()
+	if err := config.Validate(); err != nil {
+		logger.Fatal("Incorrect config", zap.Error(err))
+	}
 	v := initValidator()
 	mapper := initMapper(v)
 
 	logger.Info("Current config:", zap.String("config", config.String()))
-	if config.EnabledGRPC {
-		runGRPCServer(config, mapper, logger)
-	} else {
-		runHTTPServer(config, mapper, logger)
+	rulesConfig, err := server.LoadRulesConfig(config.RulesFile)
+	if err != nil {
+		logger.Fatal("Error during load rules", zap.Error(err))
+	}
+	app := newApp(config, rulesConfig, mapper, logger)
+	app.start(config, rulesConfig, logger)
+
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGTERM, syscall.SIGINT, syscall.SIGQUIT)
+	defer stop()
+
+	var wg sync.WaitGroup
+	if config.GRPCAddress != "" {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			runGRPCServer(ctx, config, app, logger)
+		}()
+	}
+	runHTTPServer(ctx, config, app, logger)
+	wg.Wait()
+	app.flush(logger)
+	logger.Info("Graceful shutdown completed")
+}
+
+// app is storage selected by configuration and services built on top of it. It's shared by HTTP and gRPC servers
+type app struct {
+	pool            *pgxpool.Pool
+	broker          *stream.Broker
+	metricsService  metricsContract.MetricsService
+	metricsHistory  *history.History
+	forecaster      *forecast.Forecaster
+	alertsEngine    *alerts.Engine
+	silencesService *silences.Service
+	notifier        *notify.Dispatcher
+	detector        *anomaly.Detector
+	sloTracker      *slo.Tracker
+	recorder        *recording.Recorder
+	follower        *replication.Follower
+	streamPositions metricsContract.StreamPositionStorage
+	flushers        []flusher
+	// verifier is shared by HTTP and gRPC APIs, so signed batch is accepted only once by any of them
+	verifier *signing.Verifier
+	listen   func(ctx context.Context)
+}
+
+// flusher is file storage which writes its state on shutdown
+type flusher interface {
+	Flush() error
+}
+
+// flush writes state of file storages after servers are stopped, so changes made after the last save are not lost
+func (a *app) flush(logger *zap.Logger) {
+	for _, f := range a.flushers {
+		if err := f.Flush(); err != nil {
+			logger.Error("Error during flush storage to file", zap.Error(err))
+		}
 	}
 }
 
-func runHTTPServer(config server.Config, mapper metricsMappers.Mapper, logger *zap.Logger) {
-	var metricsService metricsServices.Service
+// newApp uses Postgres when database is available and memory/file storage otherwise
+func newApp(config server.Config, rulesConfig server.RulesConfig, mapper metricsMappers.Mapper, logger *zap.Logger) *app {
+	objectives, err := slo.NewObjectives(rulesConfig.SLOs)
+	if err != nil {
+		logger.Fatal("Error during parse SLOs", zap.Error(err))
+	}
+	alertRules, err := alerts.NewRules(append(rulesConfig.AlertRules, slo.AlertRules(objectives)...))
+	if err != nil {
+		logger.Fatal("Error during parse alert rules", zap.Error(err))
+	}
+	recordingRules, err := recording.NewRules(rulesConfig.RecordingRules)
+	if err != nil {
+		logger.Fatal("Error during parse recording rules", zap.Error(err))
+	}
+	channels, err := notify.NewChannels(rulesConfig.Notifications.Channels)
+	if err != nil {
+		logger.Fatal("Error during create notification channels", zap.Error(err))
+	}
+
+	a := &app{
+		broker:         stream.New(mapper, streamHistorySize),
+		metricsHistory: history.New(historyRetention),
+		verifier:       signing.NewDefaultVerifier(config.HashKey),
+	}
+	a.forecaster = forecast.New(a.metricsHistory)
+	retrier := server.NewRetrier(3, 1*time.Second, 5*time.Second)
 	pool, err := createConnectionPool(context.Background(), config.DatabaseConnString)
 	if err != nil {
 		logger.Debug("Run with memory/file storage")
 		storage := memory.New(config.FileStoragePath, config.Restore, time.Duration(config.StoreInterval)*time.Second)
-		metricsService = metricsServices.New(storage, mapper, server.NewRetrier(3, 1*time.Second, 5*time.Second))
+		storage.AddListener(a.broker.Publish)
+		storage.AddListener(a.metricsHistory.Observe)
+		a.metricsService = withUpstream(config, metricsServices.New(storage, mapper, retrier), logger)
+		if config.ReplicaOf != "" {
+			a.follower = newFollower(config, storage, a.metricsService, mapper, logger)
+			a.metricsService = a.follower
+		}
+		// alert states and silences are stored next to metrics file
+		alertStorage := memory.NewAlertStorage(config.FileStoragePath + ".alerts")
+		silenceStorage := memory.NewSilenceStorage(config.FileStoragePath + ".silences")
+		sloStorage := memory.NewSLOStorage(config.FileStoragePath + ".slo")
+		streamPositions := memory.NewStreamPositionStorage(config.FileStoragePath + ".streams")
+		a.flushers = []flusher{storage, alertStorage, silenceStorage, sloStorage, streamPositions}
+		a.silencesService = silences.New(silenceStorage)
+		a.notifier = newNotifier(rulesConfig.Notifications, channels, a.silencesService, logger)
+		a.detector = anomaly.New(rulesConfig.AnomalyDetection, a.notifier, logger)
+		storage.AddListener(a.detector.Observe)
+		a.alertsEngine = alerts.New(alertRules, newEvaluator(a.metricsService, a.forecaster), alertStorage, a.notifier, logger)
+		a.sloTracker = slo.New(objectives, a.metricsService, sloStorage, logger)
+		a.streamPositions = streamPositions
 	} else {
 		logger.Debug("Run with Postgres storage")
+		if config.ReplicaOf != "" {
+			logger.Fatal("Replication follower requires memory storage")
+		}
 		runMigrations(config.DatabaseConnString, logger)
+		a.pool = pool
 		storage := postgres.New(pool, logger)
-		metricsService = metricsServices.New(storage, mapper, server.NewRetrier(3, 1*time.Second, 5*time.Second))
+		storage.AddListener(a.broker.Publish)
+		storage.AddListener(a.metricsHistory.Observe)
+		a.metricsService = withUpstream(config, metricsServices.New(storage, mapper, retrier), logger)
+		a.silencesService = silences.New(postgres.NewSilenceStorage(pool, logger))
+		a.notifier = newNotifier(rulesConfig.Notifications, channels, a.silencesService, logger)
+		a.detector = anomaly.New(rulesConfig.AnomalyDetection, a.notifier, logger)
+		storage.AddListener(a.detector.Observe)
+		a.alertsEngine = alerts.New(alertRules, newEvaluator(a.metricsService, a.forecaster), postgres.NewAlertStorage(pool, logger), a.notifier, logger)
+		a.sloTracker = slo.New(objectives, a.metricsService, postgres.NewSLOStorage(pool, logger), logger)
+		a.streamPositions = postgres.NewStreamPositionStorage(pool, logger)
+		a.listen = storage.Listen
+	}
+	a.recorder = recording.New(recordingRules, newEvaluator(a.metricsService, a.forecaster), a.metricsService, logger)
+	return a
+}
+
+// start restores state and runs background tasks and listeners which don't depend on transport
+func (a *app) start(config server.Config, rulesConfig server.RulesConfig, logger *zap.Logger) {
+	if err := a.silencesService.Restore(context.Background()); err != nil {
+		logger.Error("Error during restore silences", zap.Error(err))
+	}
+	if a.listen != nil {
+		go a.listen(context.Background())
+	}
+	runGraphiteServer(config, a.metricsService, logger)
+	runReplicationServer(config, a.broker, a.metricsService, a.verifier, logger)
+	if a.follower != nil {
+		go a.follower.Run(context.Background())
+	}
+
+	interval := time.Duration(rulesConfig.EvaluationInterval) * time.Second
+	// follower gets notifications and derived metrics from primary, so it starts them only after promotion
+	whenPrimary(a.follower, func() { a.notifier.Run(context.Background()) })
+	whenPrimary(a.follower, func() { a.alertsEngine.Run(context.Background(), interval) })
+	whenPrimary(a.follower, func() { a.recorder.Run(context.Background(), interval) })
+	whenPrimary(a.follower, func() { a.sloTracker.Run(context.Background(), interval) })
+}
+
+// newRouter registers HTTP API, every route should be described by OpenAPI specification
+func newRouter(config server.Config, a *app, logger *zap.Logger) chi.Router {
+	document, err := openapi.Load()
+	if err != nil {
+		logger.Fatal("Error during load OpenAPI specification", zap.Error(err))
 	}
 
 	router := chi.NewRouter()
 	router.Use(middleware.Recoverer)
 	router.Use(middleware.RequestID)
 	router.Use(middleware.RealIP)
-	router.Use(middleware.Timeout(60 * time.Second))
 	router.Use(customMiddleware.LoggingMiddleware(logger))
 	router.Use(customMiddleware.CompressingMiddleware())
 	router.Use(customMiddleware.DecompressingMiddleware())
 	router.Use(customMiddleware.TrustedSubnetMiddleware(config.TrustedSubnet))
+	router.Use(openapi.NewValidationMiddleware(document, logger))
 
-	router.Method(http.MethodGet, "/", metricsApi.NewFindAllMetricsHandler(metricsService, logger))
-	router.Method(http.MethodGet, "/ping", metricsApi.NewPingHandler(pool, logger))
-	router.Method(http.MethodGet, "/value/{type}/{name}", metricsApi.NewFindMetricValueHandler(metricsService, logger))
-	router.Method(http.MethodPost, "/value/", metricsApi.NewFindOneMetricHandler(metricsService, logger))
-	router.Method(http.MethodPost, "/update/{type}/{name}/{value}", metricsApi.NewCreateMetricHandler(metricsService, logger))
-	router.Method(http.MethodPost, "/update/", metricsApi.NewCreateMetricHandlerFromJSON(metricsService, logger))
-	router.Method(http.MethodPost, "/updates/", metricsApi.NewCreateListMetricsHandlerFromJSON(config, metricsService, logger))
+	// long-lived stream connections should not be interrupted by request timeout
+	router.Method(http.MethodGet, "/stream", metricsApi.NewStreamHandler(a.broker, logger))
 
-	if config.EnabledHTTPS {
-		e := http.ListenAndServeTLS(config.ServerAddress, "./cmd/cert/server.crt", config.CryptoKey, router)
-		if e != nil {
-			logger.Error("Error during start server", zap.Error(e))
+	router.Group(func(router chi.Router) {
+		router.Use(middleware.Timeout(60 * time.Second))
+
+		router.Method(http.MethodGet, "/", metricsApi.NewFindAllMetricsHandler(a.metricsService, logger))
+		router.Method(http.MethodGet, openapi.SpecPath, openapi.NewSpecHandler(logger))
+		router.Method(http.MethodGet, "/ping", metricsApi.NewPingHandler(a.pool, logger))
+		router.Method(http.MethodGet, "/value/{type}/{name}", metricsApi.NewFindMetricValueHandler(a.metricsService, logger))
+		router.Method(http.MethodPost, "/value/", metricsApi.NewFindOneMetricHandler(a.metricsService, a.metricsHistory, logger))
+		router.Method(http.MethodPost, "/update/{type}/{name}/{value}", metricsApi.NewCreateMetricHandler(a.metricsService, logger))
+		router.Method(http.MethodPost, "/update/", metricsApi.NewCreateMetricHandlerFromJSON(a.metricsService, logger))
+		router.Method(http.MethodPost, "/updates/", metricsApi.NewCreateListMetricsHandlerFromJSON(config, a.verifier, a.metricsService, logger))
+		router.Method(http.MethodPost, "/write", influx.NewWriteHandler(a.metricsService, logger))
+		router.Method(http.MethodGet, "/rate/counter/{name}", metricsApi.NewFindCounterRateHandler(a.metricsHistory, logger))
+		router.Method(http.MethodGet, "/forecast/gauge/{name}", metricsApi.NewForecastHandler(a.forecaster, logger))
+		router.Method(http.MethodGet, "/alerts", metricsApi.NewFindAllAlertsHandler(a.alertsEngine, logger))
+		router.Method(http.MethodGet, "/notifications", metricsApi.NewFindAllNotificationsHandler(a.notifier, logger))
+		router.Method(http.MethodGet, "/slo", metricsApi.NewFindAllSLOsHandler(a.sloTracker, logger))
+		router.Method(http.MethodGet, "/anomalies", metricsApi.NewFindAllAnomaliesHandler(a.detector, logger))
+		router.Method(http.MethodPost, "/silences", metricsApi.NewCreateSilenceHandler(a.silencesService, logger))
+		router.Method(http.MethodGet, "/silences", metricsApi.NewFindAllSilencesHandler(a.silencesService, logger))
+		router.Method(http.MethodDelete, "/silences/{id}", metricsApi.NewExpireSilenceHandler(a.silencesService, logger))
+		router.Mount(metricsApiV2.Prefix, metricsApiV2.NewRouter(a.verifier, a.metricsService, logger))
+		if a.follower != nil {
+			router.Method(http.MethodGet, "/replication", metricsApi.NewReplicationStatusHandler(a.follower, logger))
+			router.Method(http.MethodPost, "/replication/promote", metricsApi.NewPromoteHandler(a.follower, logger))
 		}
+	})
+	return router
+}
+
+// runHTTPServer serves until context is done, then waits for active requests
+func runHTTPServer(ctx context.Context, config server.Config, a *app, logger *zap.Logger) {
+	httpServer := &http.Server{Addr: config.ServerAddress, Handler: newRouter(config, a, logger)}
+	// ListenAndServe returns as soon as shutdown starts, so active requests are awaited by shutdown
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+		if err := httpServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("Error during shutdown server", zap.Error(err))
+		}
+	}()
+
+	var err error
+	if config.EnabledHTTPS {
+		err = httpServer.ListenAndServeTLS(config.Certificate, config.CryptoKey)
 	} else {
-		http.ListenAndServe(config.ServerAddress, router)
+		err = httpServer.ListenAndServe()
+	}
+	if errors.Is(err, http.ErrServerClosed) {
+		<-drained
+	} else if err != nil {
+		logger.Error("Error during start server", zap.Error(err))
+	}
+}
+
+// newEvaluator creates evaluator of rule expressions with forecast functions
+func newEvaluator(service metricsContract.MetricsService, forecaster *forecast.Forecaster) *expr.Evaluator {
+	evaluator := expr.NewEvaluator(service)
+	forecaster.RegisterFunctions(evaluator)
+	return evaluator
+}
+
+func newNotifier(config server.NotificationConfig, channels []notify.Channel, silencesService *silences.Service, logger *zap.Logger) *notify.Dispatcher {
+	retrier := server.NewRetrier(config.MaxRetries, 1*time.Second, 30*time.Second)
+	return notify.New(channels, retrier, silencesService, time.Duration(config.ThrottleInterval)*time.Second, logger)
+}
+
+// withUpstream wraps service so accepted metrics are forwarded to upstream collector when it is configured.
+// Undelivered batches are queued next to metrics file
+func withUpstream(config server.Config, service metricsContract.MetricsService, logger *zap.Logger) metricsContract.MetricsService {
+	if config.UpstreamAddress == "" {
+		return service
+	}
+
+	senderConfig := agent.Config{HashKey: config.UpstreamHashKey, Encoding: agent.EncodingJSON}
+	var sender agent.MetricsSender
+	var destination string
+	switch config.UpstreamProtocol {
+	case "http":
+		sender = agent.NewHTTPSender(senderConfig)
+		destination = fmt.Sprintf("http://%s/updates/", config.UpstreamAddress)
+	case "grpc":
+		// servers of federation share CA, so upstream is verified by the bundle verifying clients
+		// and this server presents its own certificate
+		if config.EnabledHTTPS {
+			senderConfig.EnabledHTTPS = true
+			senderConfig.CryptoKey = config.ClientCA
+			senderConfig.Certificate = config.Certificate
+			senderConfig.PrivateKey = config.CryptoKey
+		}
+		sender = agent.NewGRPCSender(senderConfig)
+		destination = config.UpstreamAddress
+	default:
+		logger.Fatal("Unknown upstream protocol", zap.String("protocol", config.UpstreamProtocol))
+	}
+
+	queue, err := federation.NewQueue(config.FileStoragePath+".upstream", upstreamQueueSize)
+	if err != nil {
+		logger.Fatal("Error during open upstream queue", zap.Error(err))
 	}
+	forwarder := federation.New(service, queue, sender, destination, config.SourceName, logger)
+	go forwarder.Run(context.Background())
+	return forwarder
 }
 
-func runGRPCServer(config server.Config, mapper metricsMappers.Mapper, logger *zap.Logger) {
-	lis, err := net.Listen("tcp", config.ServerAddress)
+// newFollower connects to primary server. Replication starts when follower is run
+func newFollower(config server.Config, storage *memory.Storage, service metricsContract.MetricsService, mapper metricsMappers.Mapper, logger *zap.Logger) *replication.Follower {
+	var transport credentials.TransportCredentials = insecure.NewCredentials()
+	// primary is verified by CA bundle of clients, follower presents certificate of this server
+	if config.EnabledHTTPS {
+		tlsConfig, err := common.ClientTLSConfig(config.ClientCA, config.Certificate, config.CryptoKey)
+		if err != nil {
+			logger.Fatal("Error during load TLS configuration of replication client", zap.Error(err))
+		}
+		transport = credentials.NewTLS(tlsConfig)
+	}
+	options := append(signing.DialOptions(config.HashKey), grpc.WithTransportCredentials(transport))
+	conn, err := grpc.NewClient(config.ReplicaOf, options...)
 	if err != nil {
-		logger.Fatal("Failed start GRPC server", zap.Error(err))
+		logger.Fatal("Failed connect to primary server", zap.Error(err))
+	}
+	return replication.New(config.ReplicaOf, metrics.NewReplicationServiceClient(conn), service, storage, mapper, logger)
+}
+
+// whenPrimary runs task in background at once or, on replication follower, after its promotion
+func whenPrimary(follower *replication.Follower, task func()) {
+	go func() {
+		if follower != nil {
+			<-follower.Promoted()
+		}
+		task()
+	}()
+}
+
+// runReplicationServer streams writes applied by this server to followers. Every start of server is a new epoch,
+// so followers of previous process request a snapshot
+func runReplicationServer(config server.Config, broker *stream.Broker, metricsService metricsContract.MetricsService, verifier *signing.Verifier, logger *zap.Logger) {
+	if config.ReplicationAddress == "" {
+		return
 	}
 
-	storage := memory.New(config.FileStoragePath, config.Restore, time.Duration(config.StoreInterval)*time.Second)
-	metricsService := metricsServices.New(storage, mapper, server.NewRetrier(3, 1*time.Second, 5*time.Second))
+	lis, err := net.Listen("tcp", config.ReplicationAddress)
+	if err != nil {
+		logger.Fatal("Failed start replication server", zap.Error(err))
+	}
 
-	s := grpc.NewServer()
-	metricsServer := &handler.MetricsServer{
+	s := grpc.NewServer(grpcServerOptions(config, verifier, logger)...)
+	metrics.RegisterReplicationServiceServer(s, &handler.ReplicationServer{
+		Stream:  broker,
 		Service: metricsService,
-		Config:  config,
+		Epoch:   strconv.FormatInt(time.Now().UnixNano(), 36),
 		Logger:  logger,
+	})
+
+	go func() {
+		logger.Debug("Replication server is running", zap.String("Replication address", config.ReplicationAddress))
+		if err := s.Serve(lis); err != nil {
+			logger.Error("Failed to serve replication", zap.Error(err))
+		}
+	}()
+}
+
+// grpcServerOptions are shared by gRPC and replication servers. They use the same certificate as HTTPS,
+// clients are verified when CA bundle is configured
+func grpcServerOptions(config server.Config, verifier *signing.Verifier, logger *zap.Logger) []grpc.ServerOption {
+	options := interceptors.ServerOptions(config, verifier, logger)
+	if config.EnabledHTTPS {
+		tlsConfig, err := common.ServerTLSConfig(config.Certificate, config.CryptoKey, config.ClientCA)
+		if err != nil {
+			logger.Fatal("Error during load TLS configuration of gRPC server", zap.Error(err))
+		}
+		options = append(options, grpc.Creds(credentials.NewTLS(tlsConfig)))
+	}
+	return options
+}
+
+// runGRPCServer serves until context is done. Health service reports storage status, reflection service
+// describes API for tools like grpcurl. On shutdown server reports NOT_SERVING and waits for active calls
+func runGRPCServer(ctx context.Context, config server.Config, a *app, logger *zap.Logger) {
+	lis, err := net.Listen("tcp", config.GRPCAddress)
+	if err != nil {
+		logger.Fatal("Failed start GRPC server", zap.Error(err))
+	}
+
+	s := grpc.NewServer(grpcServerOptions(config, a.verifier, logger)...)
+	metricsServer := &handler.MetricsServer{
+		Service:   a.metricsService,
+		Stream:    a.broker,
+		Positions: a.streamPositions,
+		Logger:    logger,
+	}
+	// nil pool can't be put into interface, database is reported as unavailable then
+	if a.pool != nil {
+		metricsServer.Pool = a.pool
 	}
 
 	metrics.RegisterMetricsServiceServer(s, metricsServer)
-	logger.Debug("gRPC server is running", zap.String("Server address", config.ServerAddress))
+	metricsv2.RegisterMetricsServiceServer(s, &handler.MetricsServerV2{Service: a.metricsService, Logger: logger})
+	healthServer := health.NewServer()
+	healthpb.RegisterHealthServer(s, healthServer)
+	reflection.Register(s)
+	go handler.ReportHealth(ctx, healthServer, metricsServer.Pool, healthCheckInterval, logger)
+
+	// Serve returns as soon as shutdown starts, so active calls are awaited by shutdown
+	drained := make(chan struct{})
+	go func() {
+		defer close(drained)
+		<-ctx.Done()
+		healthServer.Shutdown()
+		stopped := make(chan struct{})
+		go func() {
+			s.GracefulStop()
+			close(stopped)
+		}()
+		select {
+		case <-stopped:
+		case <-time.After(shutdownTimeout):
+			logger.Warn("gRPC calls are not finished during shutdown timeout, stopping server")
+			s.Stop()
+		}
+	}()
+
+	logger.Debug("gRPC server is running", zap.String("gRPC address", config.GRPCAddress))
 	if err := s.Serve(lis); err != nil {
 		logger.Fatal("Failed to serve", zap.Error(err))
 	}
+	<-drained
+}
+
+func runGraphiteServer(config server.Config, metricsService metricsContract.MetricsService, logger *zap.Logger) {
+	if config.GraphiteAddress == "" {
+		return
+	}
+
+	lis, err := net.Listen("tcp", config.GraphiteAddress)
+	if err != nil {
+		logger.Fatal("Failed start Graphite server", zap.Error(err))
+	}
+
+	graphiteServer := &graphite.MetricsServer{
+		Service: metricsService,
+		Logger:  logger,
+	}
+
+	go func() {
+		logger.Debug("Graphite server is running", zap.String("Graphite address", config.GraphiteAddress))
+		if err := graphiteServer.Serve(lis); err != nil {
+			logger.Error("Failed to serve Graphite", zap.Error(err))
+		}
+	}()
 }
 
 func extractConfig(logger *zap.Logger) server.Config {
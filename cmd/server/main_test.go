@@ -0,0 +1,38 @@
+package main
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/api/openapi"
+	"github.com/desepticon55/metrics-collector/internal/server/service/replication"
+	"github.com/go-chi/chi/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"testing"
+)
+
+func TestNewRouter_RoutesAreDescribed(t *testing.T) {
+	document, err := openapi.Load()
+	require.NoError(t, err)
+
+	described := map[string]bool{}
+	for _, route := range document.Routes() {
+		described[route.Method+" "+route.Path] = true
+	}
+
+	router := newRouter(server.Config{}, &app{follower: &replication.Follower{}}, zap.NewNop())
+	registered := map[string]bool{}
+	err = chi.Walk(router, func(method string, route string, handler http.Handler, middlewares ...func(http.Handler) http.Handler) error {
+		registered[method+" "+route] = true
+		return nil
+	})
+	require.NoError(t, err)
+
+	for route := range registered {
+		assert.True(t, described[route], "route %s is not described by OpenAPI specification", route)
+	}
+	for route := range described {
+		assert.True(t, registered[route], "route %s is described but not registered", route)
+	}
+}
@@ -8,6 +8,12 @@ import (
 	"strconv"
 )
 
+// Supported HTTP body encodings
+const (
+	EncodingJSON     = "json"
+	EncodingProtobuf = "protobuf"
+)
+
 // Agent configuration
 type Config struct {
 	ServerAddress  string `json:"address"`
@@ -18,6 +24,9 @@ type Config struct {
 	EnabledHTTPS   bool   `json:"enabled_https"`
 	EnabledGRPC    bool   `json:"enabled_grpc"`
 	CryptoKey      string `json:"crypto_key"`
+	Certificate    string `json:"certificate"`
+	PrivateKey     string `json:"private_key"`
+	Encoding       string `json:"encoding"`
 }
 
 func (c Config) String() string {
@@ -48,7 +57,10 @@ func GetConfig(loadConfig func(filePath string) (Config, error)) Config {
 	rateLimit := getIntValue(os.Getenv("RATE_LIMIT"), *flag.Int("l", 1, "Rate limit"), fileConfig.RateLimit)
 	enableHTTPS := getBooleanValue(os.Getenv("ENABLE_HTTPS"), *flag.Bool("s", false, "Enabled HTTP or not"), fileConfig.EnabledHTTPS)
 	cryptoKey := getStringValue(os.Getenv("CRYPTO_KEY"), *flag.String("crypto-key", "", "Crypto key"), fileConfig.CryptoKey, "")
+	certificate := getStringValue(os.Getenv("CERTIFICATE"), *flag.String("certificate", "", "Path to client certificate presented to gRPC server"), fileConfig.Certificate, "")
+	privateKey := getStringValue(os.Getenv("PRIVATE_KEY"), *flag.String("private-key", "", "Path to private key of client certificate"), fileConfig.PrivateKey, "")
 	enabledGRPC := getBooleanValue(os.Getenv("ENABLE_GRPC"), *flag.Bool("g", false, "Enabled GRPC or not"), fileConfig.EnabledGRPC)
+	encoding := getStringValue(os.Getenv("ENCODING"), *flag.String("e", "", "HTTP body encoding (json or protobuf)"), fileConfig.Encoding, EncodingJSON)
 
 	return Config{
 		ServerAddress:  address,
@@ -58,7 +70,10 @@ func GetConfig(loadConfig func(filePath string) (Config, error)) Config {
 		RateLimit:      rateLimit,
 		EnabledHTTPS:   enableHTTPS,
 		CryptoKey:      cryptoKey,
+		Certificate:    certificate,
+		PrivateKey:     privateKey,
 		EnabledGRPC:    enabledGRPC,
+		Encoding:       encoding,
 	}
 }
 
@@ -25,6 +25,7 @@ func TestGetConfig_Defaults(t *testing.T) {
 	assert.Equal(t, 1, config.RateLimit)
 	assert.False(t, config.EnabledHTTPS)
 	assert.Equal(t, "", config.CryptoKey)
+	assert.Equal(t, "", config.Certificate)
 }
 
 func TestGetConfig_EnvVars(t *testing.T) {
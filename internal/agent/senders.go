@@ -4,22 +4,28 @@ import (
 	"bytes"
 	"compress/gzip"
 	"context"
-	"crypto/sha256"
 	"crypto/tls"
 	"crypto/x509"
-	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/signing"
 	metrics2 "github.com/desepticon55/metrics-collector/proto/metrics"
+	metricsv2 "github.com/desepticon55/metrics-collector/proto/metrics/v2"
 	"github.com/gojek/heimdall/v7"
 	"github.com/gojek/heimdall/v7/httpclient"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials"
 	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"io"
 	"log"
 	"net"
 	"net/http"
 	"os"
+	"strings"
 	"time"
 )
 
@@ -79,20 +85,33 @@ func (s HTTPMetricsSender) SendMetrics(url string, metrics []common.MetricReques
 	}
 
 	headers := make(http.Header)
-	headers.Add("Content-Type", "application/json")
 	headers.Add("Content-Encoding", "gzip")
 	headers.Add("X-Real-IP", hostIP)
 
-	requestBody, err := json.Marshal(metrics)
-	if err != nil {
-		log.Printf("Error during JSON marshaling: %v", err)
-		return err
+	var requestBody []byte
+	if s.config.Encoding == EncodingProtobuf {
+		headers.Add("Content-Type", "application/x-protobuf")
+		requestBody, err = proto.Marshal(&metrics2.MetricsRequest{Metrics: toProtoMetrics(metrics)})
+		if err != nil {
+			log.Printf("Error during protobuf marshaling: %v", err)
+			return err
+		}
+	} else {
+		headers.Add("Content-Type", "application/json")
+		requestBody, err = json.Marshal(metrics)
+		if err != nil {
+			log.Printf("Error during JSON marshaling: %v", err)
+			return err
+		}
 	}
 
 	if s.config.HashKey != "" {
-		hash := sha256.Sum256(append(requestBody, []byte(s.config.HashKey)...))
-		hashStr := hex.EncodeToString(hash[:])
-		headers.Add("HashSHA256", hashStr)
+		payload, err := signing.MetricsPayload(toProtoMetrics(metrics))
+		if err != nil {
+			log.Printf("Error during serialize signed payload: %v", err)
+			return err
+		}
+		signing.Sign(s.config.HashKey, payload).SetHeaders(headers)
 	}
 
 	var compressedRequest bytes.Buffer
@@ -108,19 +127,53 @@ func (s HTTPMetricsSender) SendMetrics(url string, metrics []common.MetricReques
 		return err
 	}
 
+	// server errors are retried by client, they are returned as error after the last attempt
 	resp, err := client.Post(url, bytes.NewBuffer(compressedRequest.Bytes()), headers)
 	if err != nil {
 		log.Printf("Error during sending request: %v", err)
+		if resp != nil {
+			resp.Body.Close()
+		}
 		return err
 	}
+	defer func() {
+		if err := resp.Body.Close(); err != nil {
+			log.Printf("Error closing response body: %v", err)
+		}
+	}()
 
-	if err := resp.Body.Close(); err != nil {
-		log.Printf("Error closing response body: %v", err)
+	// saved part of batch can't be sent again without counting it twice, so failed metrics are only reported
+	if resp.StatusCode == http.StatusMultiStatus {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 4096))
+		log.Printf("Metrics were saved partially: %s", strings.TrimSpace(string(body)))
+		return nil
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		body, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+		reason := fmt.Sprintf("status %d: %s", resp.StatusCode, strings.TrimSpace(string(body)))
+		if resp.StatusCode >= 400 && resp.StatusCode < 500 && resp.StatusCode != http.StatusRequestTimeout && resp.StatusCode != http.StatusTooManyRequests {
+			return &RejectedError{Reason: reason}
+		}
+		return fmt.Errorf("server responded with %s", reason)
 	}
-
 	return nil
 }
 
+// RejectedError is returned when server rejects metrics because of request itself, for example invalid
+// signature or metric. Sending the same batch again fails too until configuration or batch is changed
+type RejectedError struct {
+	Reason string
+}
+
+func (e *RejectedError) Error() string {
+	return "metrics are rejected by server, " + e.Reason
+}
+
+// Rejected marks error as permanent for callers which don't depend on agent package
+func (e *RejectedError) Rejected() bool {
+	return true
+}
+
 type GRPCMetricsSender struct {
 	config Config
 }
@@ -129,52 +182,69 @@ func NewGRPCSender(config Config) MetricsSender {
 	return GRPCMetricsSender{config: config}
 }
 
+// SendMetrics sends metrics by v2 API. Upstream which doesn't implement v2 (for example collector of
+// previous version receiving federated metrics) gets them by v1 API
 func (s GRPCMetricsSender) SendMetrics(url string, metrics []common.MetricRequestDto) error {
-	conn, err := grpc.NewClient(url, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	options, err := grpcDialOptions(s.config)
 	if err != nil {
-		log.Fatalf("Failed to connect: %v", err)
 		return err
 	}
+	conn, err := grpc.NewClient(url, options...)
+	if err != nil {
+		return fmt.Errorf("error during connect to %s: %w", url, err)
+	}
 	defer conn.Close()
 
-	client := metrics2.NewMetricsServiceClient(conn)
+	protoMetrics := make([]*metricsv2.Metric, 0, len(metrics))
+	for _, metric := range metrics {
+		protoMetrics = append(protoMetrics, common.MetricRequestToProtoV2(metric))
+	}
+
+	// call is signed by interceptor of connection
+	_, err = metricsv2.NewMetricsServiceClient(conn).SendMetrics(context.Background(), &metricsv2.SendMetricsRequest{Metrics: protoMetrics})
+	if status.Code(err) == codes.Unimplemented {
+		err = sendMetricsV1(conn, metrics)
+	}
+	switch status.Code(err) {
+	case codes.InvalidArgument, codes.Unauthenticated, codes.PermissionDenied, codes.Unimplemented:
+		return &RejectedError{Reason: err.Error()}
+	}
+	return err
+}
+
+func sendMetricsV1(conn *grpc.ClientConn, metrics []common.MetricRequestDto) error {
 	hostIP, err := getCurrentIP()
 	if err != nil {
 		return err
 	}
 
-	var protoMetrics []*metrics2.Metric
-	for _, m := range metrics {
-		protoMetric := &metrics2.Metric{
-			Id:   m.ID,
-			Type: string(m.MType),
-		}
-
-		if m.Delta != nil {
-			protoMetric.Delta = *m.Delta
-		}
+	_, err = metrics2.NewMetricsServiceClient(conn).SendMetrics(context.Background(), &metrics2.MetricsRequest{
+		Metrics: toProtoMetrics(metrics),
+		Ip:      hostIP,
+	})
+	return err
+}
 
-		if m.Value != nil {
-			protoMetric.Value = *m.Value
+// grpcDialOptions sign calls and, when HTTPS is enabled, use TLS. Server is verified by certificate from crypto key,
+// client certificate is presented when it's configured
+func grpcDialOptions(config Config) ([]grpc.DialOption, error) {
+	var transport credentials.TransportCredentials = insecure.NewCredentials()
+	if config.EnabledHTTPS {
+		tlsConfig, err := common.ClientTLSConfig(config.CryptoKey, config.Certificate, config.PrivateKey)
+		if err != nil {
+			return nil, err
 		}
-
-		protoMetrics = append(protoMetrics, protoMetric)
+		transport = credentials.NewTLS(tlsConfig)
 	}
+	return append(signing.DialOptions(config.HashKey), grpc.WithTransportCredentials(transport)), nil
+}
 
-	hash := ""
-	if s.config.HashKey != "" {
-		requestBody, _ := json.Marshal(protoMetrics)
-		hashSum := sha256.Sum256(append(requestBody, []byte(s.config.HashKey)...))
-		hash = hex.EncodeToString(hashSum[:])
+func toProtoMetrics(metrics []common.MetricRequestDto) []*metrics2.Metric {
+	var protoMetrics []*metrics2.Metric
+	for _, m := range metrics {
+		protoMetrics = append(protoMetrics, common.MetricRequestToProto(m))
 	}
-
-	_, err = client.SendMetrics(context.Background(), &metrics2.MetricsRequest{
-		Metrics: protoMetrics,
-		Ip:      hostIP,
-		Hash:    hash,
-	})
-
-	return err
+	return protoMetrics
 }
 
 func getCurrentIP() (string, error) {
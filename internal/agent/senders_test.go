@@ -3,12 +3,18 @@ package agent
 import (
 	"bytes"
 	"compress/gzip"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"github.com/desepticon55/metrics-collector/internal/common"
+	handler "github.com/desepticon55/metrics-collector/internal/server/api/metrics/grpc"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	"github.com/desepticon55/metrics-collector/proto/metrics"
 	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/protobuf/proto"
 	"io"
+	"net"
 	"net/http"
 	"net/http/httptest"
 	"testing"
@@ -32,9 +38,9 @@ func TestHTTPMetricsSender_SendMetrics(t *testing.T) {
 
 			assert.Equal(t, expectedMetrics, decompressedBody)
 
-			hash := sha256.Sum256(append(decompressedBody, []byte("test_key")...))
-			expectedHash := hex.EncodeToString(hash[:])
-			assert.Equal(t, expectedHash, r.Header.Get("HashSHA256"))
+			payload, err := signing.MetricsPayload(toProtoMetrics(getSampleMetrics()))
+			assert.NoError(t, err)
+			assert.NoError(t, signing.NewVerifier("test_key", signing.DefaultMaxSkew).Verify(signing.FromHeaders(r.Header), payload))
 		}
 
 		w.WriteHeader(http.StatusOK)
@@ -55,6 +61,33 @@ func TestHTTPMetricsSender_SendMetrics(t *testing.T) {
 	assert.NoError(t, err)
 }
 
+func TestHTTPMetricsSender_SendMetricsProtobuf(t *testing.T) {
+	testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, "application/x-protobuf", r.Header.Get("Content-Type"))
+
+		gz, err := gzip.NewReader(r.Body)
+		assert.NoError(t, err)
+		defer gz.Close()
+
+		body, _ := io.ReadAll(gz)
+		var request metrics.MetricsRequest
+		assert.NoError(t, proto.Unmarshal(body, &request))
+		assert.Len(t, request.Metrics, 2)
+		assert.Equal(t, "TestGauge", request.Metrics[0].Id)
+		assert.Equal(t, 123.45, request.Metrics[0].Value)
+
+		w.WriteHeader(http.StatusOK)
+	}))
+	t.Cleanup(func() {
+		testServer.Close()
+	})
+
+	sender := HTTPMetricsSender{config: Config{Encoding: EncodingProtobuf}}
+
+	err := sender.SendMetrics(testServer.URL, getSampleMetrics())
+	assert.NoError(t, err)
+}
+
 func getSampleMetrics() []common.MetricRequestDto {
 	value := float64(123.45)
 	return []common.MetricRequestDto{
@@ -70,3 +103,44 @@ func getSampleMetrics() []common.MetricRequestDto {
 		},
 	}
 }
+
+func TestHTTPMetricsSender_SendMetricsRejected(t *testing.T) {
+	tests := []struct {
+		name     string
+		status   int
+		rejected bool
+	}{
+		{name: "bad request", status: http.StatusBadRequest, rejected: true},
+		{name: "unauthorized", status: http.StatusUnauthorized, rejected: true},
+		{name: "too many requests", status: http.StatusTooManyRequests, rejected: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			testServer := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				http.Error(w, "Invalid HashSHA256", tt.status)
+			}))
+			defer testServer.Close()
+
+			err := HTTPMetricsSender{config: Config{HashKey: "wrong_key"}}.SendMetrics(testServer.URL, getSampleMetrics())
+			assert.Error(t, err)
+			var rejected *RejectedError
+			assert.Equal(t, tt.rejected, errors.As(err, &rejected))
+		})
+	}
+}
+
+func TestGRPCMetricsSender_FallsBackToV1(t *testing.T) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	// upstream of previous version implements only v1 API
+	s := grpc.NewServer()
+	service := &recordingService{}
+	metrics.RegisterMetricsServiceServer(s, &handler.MetricsServer{Service: service, Logger: zap.NewNop()})
+	go s.Serve(lis)
+	defer s.Stop()
+
+	value := 1.0
+	request := []common.MetricRequestDto{{ID: "Alloc", MType: common.Gauge, Value: &value}}
+	assert.NoError(t, NewGRPCSender(Config{}).SendMetrics(lis.Addr().String(), request))
+	assert.Equal(t, []string{"Alloc"}, service.savedIDs())
+}
@@ -0,0 +1,177 @@
+package agent
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	metrics2 "github.com/desepticon55/metrics-collector/proto/metrics"
+	"google.golang.org/grpc"
+	"log"
+	"sync"
+	"time"
+)
+
+// Not acknowledged batches above this limit are dropped starting from the oldest one
+const maxPendingBatches = 1000
+
+// Time to wait for the last acknowledgement on close
+const closeTimeout = 5 * time.Second
+
+// GRPCStreamSender keeps one stream to server open and pushes every report as numbered batch.
+// Batches are kept until server acknowledges them and are sent again after reconnect
+type GRPCStreamSender struct {
+	config  Config
+	agentID string
+	mu      sync.Mutex
+	conn    *grpc.ClientConn
+	stream  metrics2.MetricsService_StreamMetricsClient
+	cancel  context.CancelFunc
+	done    chan struct{}
+	nextSeq uint64
+	pending []*metrics2.MetricsBatch
+}
+
+func NewGRPCStreamSender(config Config) *GRPCStreamSender {
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		log.Printf("Error during generate agent ID: %v", err)
+	}
+	return &GRPCStreamSender{config: config, agentID: hex.EncodeToString(id)}
+}
+
+// SendMetrics queues batch and pushes it to stream. Error means that stream could not be restored,
+// batch stays queued and is sent with the next one
+func (s *GRPCStreamSender) SendMetrics(url string, metrics []common.MetricRequestDto) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hostIP, err := getCurrentIP()
+	if err != nil {
+		return err
+	}
+
+	s.nextSeq++
+	batch := &metrics2.MetricsBatch{AgentId: s.agentID, Seq: s.nextSeq, Metrics: toProtoMetrics(metrics), Ip: hostIP}
+	s.pending = append(s.pending, batch)
+	if len(s.pending) > maxPendingBatches {
+		log.Printf("Too many not acknowledged batches, %d oldest are dropped", len(s.pending)-maxPendingBatches)
+		s.pending = append([]*metrics2.MetricsBatch(nil), s.pending[len(s.pending)-maxPendingBatches:]...)
+	}
+
+	if s.stream != nil {
+		if err := s.send(s.stream, batch); err == nil {
+			return nil
+		}
+		s.closeStream()
+	}
+	return s.connect(url)
+}
+
+// connect opens new stream and resends all not acknowledged batches
+func (s *GRPCStreamSender) connect(url string) error {
+	if s.conn == nil {
+		options, err := grpcDialOptions(s.config)
+		if err != nil {
+			return err
+		}
+		conn, err := grpc.NewClient(url, options...)
+		if err != nil {
+			return err
+		}
+		s.conn = conn
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream, err := metrics2.NewMetricsServiceClient(s.conn).StreamMetrics(ctx)
+	if err != nil {
+		cancel()
+		return err
+	}
+	s.stream, s.cancel, s.done = stream, cancel, make(chan struct{})
+	go s.receiveAcks(stream, s.done)
+
+	for _, batch := range s.pending {
+		if err := s.send(stream, batch); err != nil {
+			s.closeStream()
+			return err
+		}
+	}
+	return nil
+}
+
+// send signs batch on every attempt, so resent batch doesn't reuse nonce
+func (s *GRPCStreamSender) send(stream metrics2.MetricsService_StreamMetricsClient, batch *metrics2.MetricsBatch) error {
+	if s.config.HashKey != "" {
+		payload, err := signing.MetricsPayload(batch.Metrics)
+		if err != nil {
+			return err
+		}
+		signature := signing.Sign(s.config.HashKey, payload)
+		batch.Hash, batch.Timestamp, batch.Nonce = signature.Value, signature.Timestamp, signature.Nonce
+	}
+	return stream.Send(batch)
+}
+
+func (s *GRPCStreamSender) receiveAcks(stream metrics2.MetricsService_StreamMetricsClient, done chan struct{}) {
+	defer close(done)
+	for {
+		ack, err := stream.Recv()
+		s.mu.Lock()
+		if err != nil {
+			if s.stream == stream {
+				s.closeStream()
+			}
+			s.mu.Unlock()
+			return
+		}
+
+		acknowledged := 0
+		for acknowledged < len(s.pending) && s.pending[acknowledged].Seq <= ack.LastSeq {
+			acknowledged++
+		}
+		s.pending = s.pending[acknowledged:]
+		s.mu.Unlock()
+	}
+}
+
+func (s *GRPCStreamSender) closeStream() {
+	if s.stream == nil {
+		return
+	}
+	s.cancel()
+	s.stream, s.cancel = nil, nil
+}
+
+// Close finishes stream waiting for the last acknowledgement and closes connection
+func (s *GRPCStreamSender) Close() error {
+	s.mu.Lock()
+	stream, done := s.stream, s.done
+	s.mu.Unlock()
+
+	if stream != nil {
+		if err := stream.CloseSend(); err == nil {
+			select {
+			case <-done:
+			case <-time.After(closeTimeout):
+			}
+		}
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closeStream()
+	if s.conn == nil {
+		return nil
+	}
+	return s.conn.Close()
+}
+
+// Pending returns number of batches which are not acknowledged by server yet
+func (s *GRPCStreamSender) Pending() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return len(s.pending)
+}
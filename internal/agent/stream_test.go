@@ -0,0 +1,184 @@
+package agent
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	handler "github.com/desepticon55/metrics-collector/internal/server/api/metrics/grpc"
+	"github.com/desepticon55/metrics-collector/internal/server/storage/memory"
+	metrics2 "github.com/desepticon55/metrics-collector/proto/metrics"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"net"
+	"path/filepath"
+	"sync"
+	"testing"
+	"time"
+)
+
+type recordingService struct {
+	mu    sync.Mutex
+	saved []string
+}
+
+func (s *recordingService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	for _, metric := range request {
+		s.saved = append(s.saved, metric.ID)
+	}
+	return nil, nil
+}
+
+func (s *recordingService) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error) {
+	return common.MetricResponseDto{}, nil
+}
+
+func (s *recordingService) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	return nil
+}
+
+func (s *recordingService) savedIDs() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]string(nil), s.saved...)
+}
+
+// serve runs gRPC server on address until returned function is called
+func serve(t *testing.T, address string, metricsServer *handler.MetricsServer) (string, func()) {
+	lis, err := net.Listen("tcp", address)
+	assert.NoError(t, err)
+	s := grpc.NewServer()
+	metrics2.RegisterMetricsServiceServer(s, metricsServer)
+	go s.Serve(lis)
+	return lis.Addr().String(), s.Stop
+}
+
+func TestGRPCStreamSender_ResendsAfterReconnect(t *testing.T) {
+	service := &recordingService{}
+	metricsServer := &handler.MetricsServer{Service: service, Logger: zap.NewNop()}
+	address, stop := serve(t, "127.0.0.1:0", metricsServer)
+
+	sender := NewGRPCStreamSender(Config{})
+	value := 1.0
+	send := func(id string) {
+		assert.NoError(t, sender.SendMetrics(address, []common.MetricRequestDto{{ID: id, MType: common.Gauge, Value: &value}}))
+	}
+
+	send("first")
+	send("second")
+	assert.Eventually(t, func() bool {
+		return len(service.savedIDs()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	// batches are not acknowledged yet, server goes down
+	assert.Equal(t, 2, sender.Pending())
+	stop()
+
+	_, stop = serve(t, address, metricsServer)
+	defer stop()
+	assert.Eventually(t, func() bool {
+		send("third")
+		return len(service.savedIDs()) >= 3
+	}, 5*time.Second, 100*time.Millisecond)
+
+	assert.NoError(t, sender.Close())
+	assert.Equal(t, 0, sender.Pending())
+	// resent batches were not applied twice
+	saved := service.savedIDs()
+	assert.Equal(t, []string{"first", "second"}, saved[:2])
+	for _, id := range saved[2:] {
+		assert.Equal(t, "third", id)
+	}
+}
+
+func TestGRPCStreamSender_ResendsAfterServerRestart(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "metrics.json.streams")
+	service := &recordingService{}
+	metricsServer := &handler.MetricsServer{Service: service, Positions: memory.NewStreamPositionStorage(file), Logger: zap.NewNop()}
+	address, stop := serve(t, "127.0.0.1:0", metricsServer)
+
+	sender := NewGRPCStreamSender(Config{})
+	value := 1.0
+	send := func(id string) {
+		assert.NoError(t, sender.SendMetrics(address, []common.MetricRequestDto{{ID: id, MType: common.Gauge, Value: &value}}))
+	}
+
+	send("first")
+	assert.Eventually(t, func() bool {
+		return len(service.savedIDs()) == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, sender.Pending())
+	stop()
+
+	// restarted server knows positions only from storage
+	metricsServer = &handler.MetricsServer{Service: service, Positions: memory.NewStreamPositionStorage(file), Logger: zap.NewNop()}
+	_, stop = serve(t, address, metricsServer)
+	defer stop()
+	assert.Eventually(t, func() bool {
+		send("second")
+		return len(service.savedIDs()) >= 2
+	}, 5*time.Second, 100*time.Millisecond)
+
+	assert.NoError(t, sender.Close())
+	saved := service.savedIDs()
+	assert.Equal(t, "first", saved[0])
+	for _, id := range saved[1:] {
+		assert.Equal(t, "second", id)
+	}
+}
+
+// partialService fails to save metric once, the rest of batch is saved
+type partialService struct {
+	recordingService
+	failed bool
+}
+
+func (s *partialService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	s.mu.Lock()
+	fail := !s.failed
+	s.failed = true
+	s.mu.Unlock()
+	if !fail {
+		return s.recordingService.SaveMetrics(ctx, request)
+	}
+
+	var saved []common.MetricRequestDto
+	partialError := &server.PartialSaveError{}
+	for _, metric := range request {
+		if metric.ID == "flaky" {
+			partialError.Failed = append(partialError.Failed, server.FailedMetric{ID: metric.ID, MType: metric.MType, Error: "shard is unavailable"})
+		} else {
+			saved = append(saved, metric)
+		}
+	}
+	s.recordingService.SaveMetrics(ctx, saved)
+	return nil, partialError
+}
+
+func TestGRPCStreamSender_ResendsPartiallySavedBatch(t *testing.T) {
+	service := &partialService{}
+	address, stop := serve(t, "127.0.0.1:0", &handler.MetricsServer{Service: service, Logger: zap.NewNop()})
+	defer stop()
+
+	sender := NewGRPCStreamSender(Config{})
+	value := 1.0
+	assert.NoError(t, sender.SendMetrics(address, []common.MetricRequestDto{
+		{ID: "stable", MType: common.Gauge, Value: &value},
+		{ID: "flaky", MType: common.Gauge, Value: &value},
+	}))
+	assert.Eventually(t, func() bool {
+		assert.NoError(t, sender.SendMetrics(address, []common.MetricRequestDto{{ID: "next", MType: common.Gauge, Value: &value}}))
+		return len(service.savedIDs()) >= 3
+	}, 5*time.Second, 100*time.Millisecond)
+	assert.NoError(t, sender.Close())
+
+	// failed metric is saved after resend, saved one is not saved twice
+	saved := service.savedIDs()
+	assert.Equal(t, []string{"stable", "flaky"}, saved[:2])
+	for _, id := range saved[2:] {
+		assert.Equal(t, "next", id)
+	}
+}
@@ -0,0 +1,91 @@
+package agent
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	handler "github.com/desepticon55/metrics-collector/internal/server/api/metrics/grpc"
+	metrics2 "github.com/desepticon55/metrics-collector/proto/metrics"
+	metricsv2 "github.com/desepticon55/metrics-collector/proto/metrics/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+type identityService struct {
+	recordingService
+	identities []string
+}
+
+func (s *identityService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	s.identities = append(s.identities, common.PeerIdentity(ctx))
+	return s.recordingService.SaveMetrics(ctx, request)
+}
+
+// issueCertificate writes certificate and key signed by parent, certificate is self-signed without parent
+func issueCertificate(t *testing.T, dir string, name string, template *x509.Certificate, parent *x509.Certificate, parentKey *ecdsa.PrivateKey) (*x509.Certificate, *ecdsa.PrivateKey) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	assert.NoError(t, err)
+	template.SerialNumber = big.NewInt(time.Now().UnixNano())
+	template.Subject = pkix.Name{CommonName: name}
+	template.NotBefore, template.NotAfter = time.Now().Add(-time.Hour), time.Now().Add(time.Hour)
+	if parent == nil {
+		parent, parentKey = template, key
+	}
+
+	der, err := x509.CreateCertificate(rand.Reader, template, parent, &key.PublicKey, parentKey)
+	assert.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name+".crt"), pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0600))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, name+".key"), pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0600))
+
+	certificate, err := x509.ParseCertificate(der)
+	assert.NoError(t, err)
+	return certificate, key
+}
+
+func TestGRPCMetricsSender_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca, caKey := issueCertificate(t, dir, "ca", &x509.Certificate{IsCA: true, BasicConstraintsValid: true, KeyUsage: x509.KeyUsageCertSign}, nil, nil)
+	issueCertificate(t, dir, "server", &x509.Certificate{IPAddresses: []net.IP{net.ParseIP("127.0.0.1")}, ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}}, ca, caKey)
+	issueCertificate(t, dir, "agent-1", &x509.Certificate{ExtKeyUsage: []x509.ExtKeyUsage{x509.ExtKeyUsageClientAuth}}, ca, caKey)
+
+	tlsConfig, err := common.ServerTLSConfig(filepath.Join(dir, "server.crt"), filepath.Join(dir, "server.key"), filepath.Join(dir, "ca.crt"))
+	assert.NoError(t, err)
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := grpc.NewServer(grpc.Creds(credentials.NewTLS(tlsConfig)))
+	service := &identityService{}
+	metrics2.RegisterMetricsServiceServer(s, &handler.MetricsServer{Service: service, Logger: zap.NewNop()})
+	metricsv2.RegisterMetricsServiceServer(s, &handler.MetricsServerV2{Service: service, Logger: zap.NewNop()})
+	go s.Serve(lis)
+	defer s.Stop()
+
+	value := 1.0
+	request := []common.MetricRequestDto{{ID: "Alloc", MType: common.Gauge, Value: &value}}
+	config := Config{EnabledHTTPS: true, CryptoKey: filepath.Join(dir, "ca.crt"), Certificate: filepath.Join(dir, "agent-1.crt"), PrivateKey: filepath.Join(dir, "agent-1.key")}
+	assert.NoError(t, NewGRPCSender(config).SendMetrics(lis.Addr().String(), request))
+	assert.Equal(t, []string{"agent-1"}, service.identities)
+
+	// client without certificate is rejected
+	config.Certificate, config.PrivateKey = "", ""
+	assert.Error(t, NewGRPCSender(config).SendMetrics(lis.Addr().String(), request))
+
+	// server is verified by CA
+	config.CryptoKey = filepath.Join(dir, "agent-1.crt")
+	assert.Error(t, NewGRPCSender(config).SendMetrics(lis.Addr().String(), request))
+	assert.Len(t, service.identities, 1)
+}
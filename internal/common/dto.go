@@ -1,5 +1,7 @@
 package common
 
+import "time"
+
 type MetricType string
 
 const (
@@ -19,4 +21,30 @@ type MetricResponseDto struct {
 	MType MetricType `json:"type"`
 	Delta *int64     `json:"delta,omitempty"`
 	Value *float64   `json:"value,omitempty"`
+	// Rate is per-second rate of counter, filled when history is available
+	Rate *float64 `json:"rate,omitempty"`
+}
+
+type RateResponseDto struct {
+	ID      string     `json:"id"`
+	MType   MetricType `json:"type"`
+	Window  string     `json:"window"`
+	Rate    float64    `json:"rate"`
+	Samples int        `json:"samples"`
+}
+
+// ForecastResponseDto is projected value of gauge. Slope is change of value per second.
+// CrossesAt and CrossesIn are filled when threshold is requested and trend reaches it
+type ForecastResponseDto struct {
+	ID        string     `json:"id"`
+	MType     MetricType `json:"type"`
+	Method    string     `json:"method"`
+	Window    string     `json:"window"`
+	Samples   int        `json:"samples"`
+	Slope     float64    `json:"slope"`
+	At        time.Time  `json:"at"`
+	Value     float64    `json:"value"`
+	Threshold *float64   `json:"threshold,omitempty"`
+	CrossesAt *time.Time `json:"crosses_at,omitempty"`
+	CrossesIn string     `json:"crosses_in,omitempty"`
 }
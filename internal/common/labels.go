@@ -0,0 +1,72 @@
+package common
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// Labels are encoded into the metric ID using the Graphite tagged series syntax:
+// name;key1=value1;key2=value2 with keys sorted alphabetically
+const (
+	labelSeparator      = ";"
+	labelValueSeparator = "="
+)
+
+// ValidateLabels checks that name and labels can be encoded into metric ID. Separators are not escaped,
+// so name, keys or values containing them would make different labels produce the same ID
+func ValidateLabels(name string, labels map[string]string) error {
+	if strings.ContainsAny(name, labelSeparator+labelValueSeparator) {
+		return fmt.Errorf("metric id '%s' contains '%s' or '%s'", name, labelSeparator, labelValueSeparator)
+	}
+	for key, value := range labels {
+		if key == "" {
+			return fmt.Errorf("metric '%s': label key is empty", name)
+		}
+		if strings.ContainsAny(key, labelSeparator+labelValueSeparator) || strings.ContainsAny(value, labelSeparator+labelValueSeparator) {
+			return fmt.Errorf("metric '%s': label '%s' contains '%s' or '%s'", name, key, labelSeparator, labelValueSeparator)
+		}
+	}
+	return nil
+}
+
+// JoinLabels builds canonical metric ID from metric name and labels
+func JoinLabels(name string, labels map[string]string) string {
+	if len(labels) == 0 {
+		return name
+	}
+
+	keys := make([]string, 0, len(labels))
+	for key := range labels {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	var builder strings.Builder
+	builder.WriteString(name)
+	for _, key := range keys {
+		builder.WriteString(labelSeparator)
+		builder.WriteString(key)
+		builder.WriteString(labelValueSeparator)
+		builder.WriteString(labels[key])
+	}
+	return builder.String()
+}
+
+// SplitLabels extracts metric name and labels from metric ID
+func SplitLabels(id string) (string, map[string]string) {
+	parts := strings.Split(id, labelSeparator)
+	if len(parts) == 1 {
+		return id, nil
+	}
+
+	labels := make(map[string]string, len(parts)-1)
+	for _, part := range parts[1:] {
+		key, value, found := strings.Cut(part, labelValueSeparator)
+		if !found || key == "" {
+			continue
+		}
+		labels[key] = value
+	}
+	return parts[0], labels
+}
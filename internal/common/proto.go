@@ -0,0 +1,41 @@
+package common
+
+import "github.com/desepticon55/metrics-collector/proto/metrics"
+
+// MetricRequestToProto converts request DTO to protobuf message
+func MetricRequestToProto(dto MetricRequestDto) *metrics.Metric {
+	protoMetric := &metrics.Metric{
+		Id:   dto.ID,
+		Type: string(dto.MType),
+	}
+	if dto.Delta != nil {
+		protoMetric.Delta = *dto.Delta
+	}
+	if dto.Value != nil {
+		protoMetric.Value = *dto.Value
+	}
+	return protoMetric
+}
+
+// MetricRequestFromProto converts protobuf message to request DTO.
+// Only the field matching to metric type is filled
+func MetricRequestFromProto(protoMetric *metrics.Metric) MetricRequestDto {
+	dto := MetricRequestDto{
+		ID:    protoMetric.GetId(),
+		MType: MetricType(protoMetric.GetType()),
+	}
+	switch dto.MType {
+	case Counter:
+		delta := protoMetric.GetDelta()
+		dto.Delta = &delta
+	case Gauge:
+		value := protoMetric.GetValue()
+		dto.Value = &value
+	}
+	return dto
+}
+
+// MetricResponseToProto converts response DTO to protobuf message
+func MetricResponseToProto(dto MetricResponseDto) *metrics.Metric {
+	return MetricRequestToProto(MetricRequestDto{ID: dto.ID, MType: dto.MType, Delta: dto.Delta, Value: dto.Value})
+}
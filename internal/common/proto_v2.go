@@ -0,0 +1,66 @@
+package common
+
+import (
+	"errors"
+	"fmt"
+	metricsv2 "github.com/desepticon55/metrics-collector/proto/metrics/v2"
+)
+
+var ErrHistogramNotSupported = errors.New("histogram metrics are not supported yet")
+
+// MetricRequestFromProtoV2 converts version 2 protobuf message to request DTO. Type is taken from
+// the set value, labels are joined into ID
+func MetricRequestFromProtoV2(protoMetric *metricsv2.Metric) (MetricRequestDto, error) {
+	if err := ValidateLabels(protoMetric.GetId(), protoMetric.GetLabels()); err != nil {
+		return MetricRequestDto{}, err
+	}
+	dto := MetricRequestDto{ID: JoinLabels(protoMetric.GetId(), protoMetric.GetLabels())}
+	switch value := protoMetric.GetValue().(type) {
+	case *metricsv2.Metric_Gauge:
+		dto.MType = Gauge
+		dto.Value = &value.Gauge
+	case *metricsv2.Metric_Counter:
+		dto.MType = Counter
+		dto.Delta = &value.Counter
+	case *metricsv2.Metric_Histogram:
+		return dto, fmt.Errorf("metric '%s': %w", protoMetric.GetId(), ErrHistogramNotSupported)
+	default:
+		return dto, fmt.Errorf("metric '%s': value is not set", protoMetric.GetId())
+	}
+	return dto, nil
+}
+
+// MetricRequestToProtoV2 converts request DTO to version 2 protobuf message, labels are split from ID.
+// Value is set only when it matches to metric type
+func MetricRequestToProtoV2(dto MetricRequestDto) *metricsv2.Metric {
+	name, labels := SplitLabels(dto.ID)
+	protoMetric := &metricsv2.Metric{Id: name, Labels: labels}
+	switch {
+	case dto.MType == Counter && dto.Delta != nil:
+		protoMetric.Value = &metricsv2.Metric_Counter{Counter: *dto.Delta}
+	case dto.MType == Gauge && dto.Value != nil:
+		protoMetric.Value = &metricsv2.Metric_Gauge{Gauge: *dto.Value}
+	}
+	return protoMetric
+}
+
+// MetricResponseToProtoV2 converts response DTO to version 2 protobuf message
+func MetricResponseToProtoV2(dto MetricResponseDto) *metricsv2.Metric {
+	return MetricRequestToProtoV2(MetricRequestDto{ID: dto.ID, MType: dto.MType, Delta: dto.Delta, Value: dto.Value})
+}
+
+// MetricTypeFromKind converts kind of version 2 to metric type, unspecified kind is empty type
+func MetricTypeFromKind(kind metricsv2.MetricKind) (MetricType, error) {
+	switch kind {
+	case metricsv2.MetricKind_METRIC_KIND_UNSPECIFIED:
+		return "", nil
+	case metricsv2.MetricKind_METRIC_KIND_GAUGE:
+		return Gauge, nil
+	case metricsv2.MetricKind_METRIC_KIND_COUNTER:
+		return Counter, nil
+	case metricsv2.MetricKind_METRIC_KIND_HISTOGRAM:
+		return "", ErrHistogramNotSupported
+	default:
+		return "", fmt.Errorf("unknown metric kind %d", kind)
+	}
+}
@@ -0,0 +1,136 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/service/history"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	"github.com/desepticon55/metrics-collector/proto/metrics"
+	"io"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+const backendTimeout = 5 * time.Second
+
+// Backend is HTTP client of metrics server which is a shard behind proxy
+type Backend struct {
+	address string
+	hashKey string
+	client  *http.Client
+}
+
+func NewBackend(address string, hashKey string) *Backend {
+	return &Backend{address: address, hashKey: hashKey, client: &http.Client{Timeout: backendTimeout}}
+}
+
+func (b *Backend) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	body, err := json.Marshal(request)
+	if err != nil {
+		return nil, err
+	}
+
+	headers := http.Header{"Content-Type": {"application/json"}}
+	if b.hashKey != "" {
+		protoMetrics := make([]*metrics.Metric, 0, len(request))
+		for _, metric := range request {
+			protoMetrics = append(protoMetrics, common.MetricRequestToProto(metric))
+		}
+		payload, err := signing.MetricsPayload(protoMetrics)
+		if err != nil {
+			return nil, err
+		}
+		signing.Sign(b.hashKey, payload).SetHeaders(headers)
+	}
+
+	var response []common.MetricResponseDto
+	err = b.do(ctx, http.MethodPost, "/updates/", headers, body, &response)
+	if statusCode(err) == http.StatusBadRequest {
+		return nil, server.NewValidationError(err)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (b *Backend) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error) {
+	body, err := json.Marshal(common.MetricRequestDto{ID: metricName, MType: metricType})
+	if err != nil {
+		return common.MetricResponseDto{}, err
+	}
+
+	var response common.MetricResponseDto
+	err = b.do(ctx, http.MethodPost, "/value/", http.Header{"Content-Type": {"application/json"}}, body, &response)
+	if statusCode(err) == http.StatusNotFound {
+		return response, server.NewMetricNotFoundError(metricName, metricType)
+	}
+	return response, err
+}
+
+func (b *Backend) FindAllMetrics(ctx context.Context) ([]common.MetricResponseDto, error) {
+	var response []common.MetricResponseDto
+	if err := b.do(ctx, http.MethodGet, "/", nil, nil, &response); err != nil {
+		return nil, err
+	}
+	return response, nil
+}
+
+func (b *Backend) CounterRate(ctx context.Context, name string, window time.Duration) (float64, int, error) {
+	var response common.RateResponseDto
+	path := fmt.Sprintf("/rate/counter/%s?window=%s", url.PathEscape(name), window)
+	err := b.do(ctx, http.MethodGet, path, nil, nil, &response)
+	if statusCode(err) == http.StatusNotFound {
+		return 0, 0, fmt.Errorf("counter '%s': %w", name, history.ErrNotEnoughHistory)
+	}
+	return response.Rate, response.Samples, err
+}
+
+// backendError is unexpected response status of backend
+type backendError struct {
+	address string
+	status  int
+	message string
+}
+
+func (e *backendError) Error() string {
+	return fmt.Sprintf("backend %s responded with status %d: %s", e.address, e.status, e.message)
+}
+
+func statusCode(err error) int {
+	if backendErr, ok := err.(*backendError); ok {
+		return backendErr.status
+	}
+	return 0
+}
+
+func (b *Backend) do(ctx context.Context, method string, path string, headers http.Header, body []byte, result any) error {
+	request, err := http.NewRequestWithContext(ctx, method, "http://"+b.address+path, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	for key, values := range headers {
+		request.Header[key] = values
+	}
+	request.Header.Set("Accept", "application/json")
+
+	response, err := b.client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	content, err := io.ReadAll(response.Body)
+	if err != nil {
+		return err
+	}
+	if response.StatusCode != http.StatusOK {
+		return &backendError{address: b.address, status: response.StatusCode, message: string(bytes.TrimSpace(content))}
+	}
+	return json.Unmarshal(content, result)
+}
@@ -0,0 +1,96 @@
+package proxy
+
+import (
+	"flag"
+	"fmt"
+	"log"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// Proxy configuration
+type Config struct {
+	ServerAddress string   `json:"address"`
+	GRPCAddress   string   `json:"grpc_address"`
+	Backends      []string `json:"backends"`
+	VirtualNodes  int      `json:"virtual_nodes"`
+	HashKey       string   `json:"hash_key"`
+	TrustedSubnet string   `json:"trusted_subnet"`
+}
+
+func (c Config) String() string {
+	return fmt.Sprintf("\nServerAddress: %s\nGRPCAddress: %s\nBackends: %s\nVirtualNodes: %d\nHashKey: %s\nTrustedSubnet: %s\n",
+		c.ServerAddress, c.GRPCAddress, strings.Join(c.Backends, ","), c.VirtualNodes, c.HashKey, c.TrustedSubnet)
+}
+
+func GetConfig(loadConfig func(filePath string) (Config, error)) Config {
+	defaultConfigPath := ""
+	if envConfigPath, exists := os.LookupEnv("CONFIG"); exists {
+		defaultConfigPath = envConfigPath
+	}
+	configPath := flag.String("config", defaultConfigPath, "Path to config file")
+
+	var fileConfig Config
+	if *configPath != "" {
+		var err error
+		fileConfig, err = loadConfig(*configPath)
+		if err != nil {
+			log.Printf("Failed to load config from file: %v", err)
+		}
+	}
+
+	address := getStringValue(os.Getenv("ADDRESS"), *flag.String("a", "", "Proxy address"), fileConfig.ServerAddress, "localhost:8090")
+	grpcAddress := getStringValue(os.Getenv("GRPC_ADDRESS"), *flag.String("grpc-address", "", "Proxy gRPC address"), fileConfig.GRPCAddress, "")
+	backends := getStringValue(os.Getenv("BACKENDS"), *flag.String("b", "", "Comma separated addresses of backend servers"), strings.Join(fileConfig.Backends, ","), "localhost:8080")
+	virtualNodes := getIntValue(os.Getenv("VIRTUAL_NODES"), *flag.Int("vnodes", 0, "Number of virtual nodes of backend on hash ring"), fileConfig.VirtualNodes, 128)
+	hashKey := getStringValue(os.Getenv("KEY"), *flag.String("k", "", "Hash key"), fileConfig.HashKey, "")
+	trustedSubnet := getStringValue(os.Getenv("TRUSTED_SUBNET"), *flag.String("t", "", "Trusted subnet in CIDR format"), fileConfig.TrustedSubnet, "")
+
+	return Config{
+		ServerAddress: address,
+		GRPCAddress:   grpcAddress,
+		Backends:      splitAddresses(backends),
+		VirtualNodes:  virtualNodes,
+		HashKey:       hashKey,
+		TrustedSubnet: trustedSubnet,
+	}
+}
+
+func splitAddresses(value string) []string {
+	var addresses []string
+	for _, address := range strings.Split(value, ",") {
+		if address = strings.TrimSpace(address); address != "" {
+			addresses = append(addresses, address)
+		}
+	}
+	return addresses
+}
+
+func getStringValue(env string, flagValue string, fileValue string, defaultValue string) string {
+	if env != "" {
+		return env
+	}
+	if flagValue != "" {
+		return flagValue
+	}
+	if fileValue != "" {
+		return fileValue
+	}
+	return defaultValue
+}
+
+func getIntValue(envVar string, flagValue int, fileValue int, defaultValue int) int {
+	if envVar != "" {
+		if parsed, err := strconv.Atoi(envVar); err == nil {
+			return parsed
+		}
+	}
+	if flagValue != 0 {
+		return flagValue
+	}
+	if fileValue != 0 {
+		return fileValue
+	}
+	return defaultValue
+}
@@ -0,0 +1,74 @@
+package proxy
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+)
+
+const maxBackendsBodySize = 1 << 20
+
+// Find backend servers handler
+func NewFindBackendsHandler(service *Service, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodGet {
+			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
+			return
+		}
+
+		writeBackends(writer, service.Backends(), logger)
+	}
+}
+
+// Replace backend servers handler. Body is JSON list of backend addresses.
+// With verifier body should be signed with hash key the same way as metrics are signed
+func NewUpdateBackendsHandler(service *Service, verifier *signing.Verifier, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPut {
+			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
+			return
+		}
+
+		body, err := io.ReadAll(http.MaxBytesReader(writer, request.Body, maxBackendsBodySize))
+		if err != nil {
+			http.Error(writer, "Error during read body", http.StatusBadRequest)
+			return
+		}
+
+		if verifier != nil {
+			if err := verifier.Verify(signing.FromHeaders(request.Header), body); err != nil {
+				logger.Error("Signature of backends was rejected", zap.Error(err))
+				http.Error(writer, err.Error(), http.StatusForbidden)
+				return
+			}
+		}
+
+		var addresses []string
+		if err := json.Unmarshal(body, &addresses); err != nil || len(addresses) == 0 {
+			http.Error(writer, "Body should be non-empty JSON list of backend addresses", http.StatusBadRequest)
+			return
+		}
+
+		service.SetBackends(addresses)
+		logger.Info("Backends were changed", zap.Strings("backends", service.Backends()))
+		writeBackends(writer, service.Backends(), logger)
+	}
+}
+
+func writeBackends(writer http.ResponseWriter, backends []string, logger *zap.Logger) {
+	bytes, err := json.Marshal(backends)
+	if err != nil {
+		logger.Error("Error during marshal backends.", zap.Error(err))
+		http.Error(writer, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	if _, err = writer.Write(bytes); err != nil {
+		http.Error(writer, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
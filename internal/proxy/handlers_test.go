@@ -0,0 +1,60 @@
+package proxy
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestUpdateBackendsHandler(t *testing.T) {
+	service := NewService(128, []string{"localhost:8080"}, "key", zap.NewNop())
+	handler := NewUpdateBackendsHandler(service, signing.NewDefaultVerifier("key"), zap.NewNop())
+	body := `["localhost:8081","localhost:8082"]`
+
+	tests := []struct {
+		name     string
+		sign     func(request *http.Request)
+		status   int
+		backends []string
+	}{
+		{
+			name:     "unsigned body is rejected",
+			sign:     func(request *http.Request) {},
+			status:   http.StatusForbidden,
+			backends: []string{"localhost:8080"},
+		},
+		{
+			name: "body signed with other key is rejected",
+			sign: func(request *http.Request) {
+				signing.Sign("other", []byte(body)).SetHeaders(request.Header)
+			},
+			status:   http.StatusForbidden,
+			backends: []string{"localhost:8080"},
+		},
+		{
+			name: "signed body changes backends",
+			sign: func(request *http.Request) {
+				signing.Sign("key", []byte(body)).SetHeaders(request.Header)
+			},
+			status:   http.StatusOK,
+			backends: []string{"localhost:8081", "localhost:8082"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodPut, "/backends", strings.NewReader(body))
+			tt.sign(request)
+			recorder := httptest.NewRecorder()
+
+			handler(recorder, request)
+
+			assert.Equal(t, tt.status, recorder.Code)
+			assert.Equal(t, tt.backends, service.Backends())
+		})
+	}
+}
@@ -0,0 +1,93 @@
+package proxy
+
+import (
+	"hash/fnv"
+	"sort"
+	"strconv"
+	"sync"
+)
+
+type vnode struct {
+	hash uint64
+	node string
+}
+
+// Ring is consistent hash ring. Every node is placed on the ring many times as virtual nodes,
+// so adding or removing node moves only its share of keys and spreads it evenly between other nodes
+type Ring struct {
+	mu           sync.RWMutex
+	virtualNodes int
+	vnodes       []vnode
+	nodes        map[string]struct{}
+}
+
+func NewRing(virtualNodes int, nodes ...string) *Ring {
+	r := &Ring{virtualNodes: max(virtualNodes, 1), nodes: make(map[string]struct{})}
+	r.Set(nodes)
+	return r
+}
+
+// Set replaces ring members
+func (r *Ring) Set(nodes []string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.nodes = make(map[string]struct{}, len(nodes))
+	r.vnodes = r.vnodes[:0]
+	for _, node := range nodes {
+		if _, ok := r.nodes[node]; ok {
+			continue
+		}
+		r.nodes[node] = struct{}{}
+		for i := 0; i < r.virtualNodes; i++ {
+			r.vnodes = append(r.vnodes, vnode{hash: hashKey(node + "#" + strconv.Itoa(i)), node: node})
+		}
+	}
+	sort.Slice(r.vnodes, func(i, j int) bool {
+		if r.vnodes[i].hash == r.vnodes[j].hash {
+			return r.vnodes[i].node < r.vnodes[j].node
+		}
+		return r.vnodes[i].hash < r.vnodes[j].hash
+	})
+}
+
+// Nodes returns ring members sorted by name
+func (r *Ring) Nodes() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	nodes := make([]string, 0, len(r.nodes))
+	for node := range r.nodes {
+		nodes = append(nodes, node)
+	}
+	sort.Strings(nodes)
+	return nodes
+}
+
+// Owner returns node owning key: the first virtual node clockwise from hash of key
+func (r *Ring) Owner(key string) (string, bool) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	if len(r.vnodes) == 0 {
+		return "", false
+	}
+	hash := hashKey(key)
+	i := sort.Search(len(r.vnodes), func(i int) bool {
+		return r.vnodes[i].hash >= hash
+	})
+	if i == len(r.vnodes) {
+		i = 0
+	}
+	return r.vnodes[i].node, true
+}
+
+// hashKey is FNV-1a with final mixing of bits, plain FNV places similar names of virtual nodes too close
+func hashKey(key string) uint64 {
+	h := fnv.New64a()
+	h.Write([]byte(key))
+	x := h.Sum64()
+	x = (x ^ (x >> 30)) * 0xbf58476d1ce4e5b9
+	x = (x ^ (x >> 27)) * 0x94d049bb133111eb
+	return x ^ (x >> 31)
+}
@@ -0,0 +1,58 @@
+package proxy
+
+import (
+	"fmt"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func owners(ring *Ring, keys int) map[string]string {
+	result := make(map[string]string, keys)
+	for i := 0; i < keys; i++ {
+		key := fmt.Sprintf("metric%d", i)
+		result[key], _ = ring.Owner(key)
+	}
+	return result
+}
+
+func TestRing_Distribution(t *testing.T) {
+	ring := NewRing(128, "a:8080", "b:8080", "c:8080", "d:8080")
+
+	shares := make(map[string]int)
+	for _, owner := range owners(ring, 10000) {
+		shares[owner]++
+	}
+	assert.Len(t, shares, 4)
+	for node, share := range shares {
+		assert.InDelta(t, 2500, share, 750, node)
+	}
+}
+
+func TestRing_MinimalRemapping(t *testing.T) {
+	ring := NewRing(128, "a:8080", "b:8080", "c:8080", "d:8080")
+	before := owners(ring, 10000)
+
+	// added node takes keys only from other nodes, about a fifth of all keys
+	ring.Set([]string{"a:8080", "b:8080", "c:8080", "d:8080", "e:8080"})
+	after := owners(ring, 10000)
+	moved := 0
+	for key, owner := range after {
+		if owner != before[key] {
+			assert.Equal(t, "e:8080", owner)
+			moved++
+		}
+	}
+	assert.InDelta(t, 2000, moved, 600)
+
+	// keys of removed node are the only moved keys
+	ring.Set([]string{"a:8080", "c:8080", "d:8080", "e:8080"})
+	for key, owner := range owners(ring, 10000) {
+		if after[key] != "b:8080" {
+			assert.Equal(t, after[key], owner)
+		}
+	}
+	assert.Equal(t, []string{"a:8080", "c:8080", "d:8080", "e:8080"}, ring.Nodes())
+
+	_, ok := NewRing(128).Owner("metric")
+	assert.False(t, ok)
+}
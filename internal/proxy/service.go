@@ -0,0 +1,200 @@
+package proxy
+
+import (
+	"context"
+	"errors"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	metricsMappers "github.com/desepticon55/metrics-collector/internal/server/mapper/metrics"
+	"github.com/go-playground/validator/v10"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+var ErrNoBackends = errors.New("no backend servers are configured")
+
+// Service spreads metrics between backend servers by consistent hashing of metric name.
+// Reads are served by the shard owning metric, so values left on previous owner after
+// membership change are not visible
+type Service struct {
+	mu       sync.RWMutex
+	ring     *Ring
+	backends map[string]*Backend
+	hashKey  string
+	mapper   metricsMappers.Mapper
+	logger   *zap.Logger
+}
+
+func NewService(virtualNodes int, addresses []string, hashKey string, logger *zap.Logger) *Service {
+	s := &Service{
+		ring:    NewRing(virtualNodes),
+		hashKey: hashKey,
+		mapper:  metricsMappers.NewMapper(validator.New()),
+		logger:  logger,
+	}
+	s.SetBackends(addresses)
+	return s
+}
+
+// SetBackends changes shard membership. Only metrics owned by added or removed backends are remapped
+func (s *Service) SetBackends(addresses []string) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	backends := make(map[string]*Backend, len(addresses))
+	for _, address := range addresses {
+		if backend, ok := s.backends[address]; ok {
+			backends[address] = backend
+		} else {
+			backends[address] = NewBackend(address, s.hashKey)
+		}
+	}
+	s.backends = backends
+	s.ring.Set(addresses)
+}
+
+// Backends returns addresses of backend servers
+func (s *Service) Backends() []string {
+	return s.ring.Nodes()
+}
+
+func (s *Service) owner(name string) (string, *Backend, error) {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	address, ok := s.ring.Owner(name)
+	if !ok {
+		return "", nil, ErrNoBackends
+	}
+	return address, s.backends[address], nil
+}
+
+// SaveMetrics splits batch between owning shards and saves parts concurrently. When some shard fails
+// the other parts stay saved and server.PartialSaveError lists metrics of failed shards, so client
+// resends only them instead of counting saved ones twice. Batch is validated as a whole before splitting,
+// so invalid metric rejects the batch with server.ValidationError like a single server does
+func (s *Service) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	for _, metric := range request {
+		if _, err := s.mapper.MapRequestToDomainModel(metric); err != nil {
+			return nil, err
+		}
+	}
+
+	type part struct {
+		backend  *Backend
+		metrics  []common.MetricRequestDto
+		response []common.MetricResponseDto
+		err      error
+	}
+
+	var parts []*part
+	byAddress := make(map[string]*part)
+	for _, metric := range request {
+		address, backend, err := s.owner(metric.ID)
+		if err != nil {
+			return nil, err
+		}
+		p, ok := byAddress[address]
+		if !ok {
+			p = &part{backend: backend}
+			byAddress[address] = p
+			parts = append(parts, p)
+		}
+		p.metrics = append(p.metrics, metric)
+	}
+
+	var wg sync.WaitGroup
+	for _, p := range parts {
+		wg.Add(1)
+		go func(p *part) {
+			defer wg.Done()
+			p.response, p.err = p.backend.SaveMetrics(ctx, p.metrics)
+		}(p)
+	}
+	wg.Wait()
+
+	var response []common.MetricResponseDto
+	var failed []server.FailedMetric
+	var errs []error
+	for _, p := range parts {
+		if p.err != nil {
+			s.logger.Error("Error during save metrics to shard", zap.Int("metrics", len(p.metrics)), zap.Error(p.err))
+			errs = append(errs, p.err)
+			for _, metric := range p.metrics {
+				failed = append(failed, server.FailedMetric{ID: metric.ID, MType: metric.MType, Error: p.err.Error()})
+			}
+			continue
+		}
+		response = append(response, p.response...)
+	}
+	switch {
+	case len(errs) == 0:
+		return response, nil
+	case len(errs) == len(parts):
+		// nothing is saved, so the whole batch can be sent again
+		return nil, errors.Join(errs...)
+	default:
+		return response, &server.PartialSaveError{Saved: response, Failed: failed}
+	}
+}
+
+func (s *Service) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error) {
+	_, backend, err := s.owner(metricName)
+	if err != nil {
+		return common.MetricResponseDto{}, err
+	}
+	return backend.FindOneMetric(ctx, metricName, metricType)
+}
+
+// FindAllMetrics merges metrics of all shards. Every metric is taken from its owning shard only,
+// unavailable shard is skipped
+func (s *Service) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	s.mu.RLock()
+	backends := make(map[string]*Backend, len(s.backends))
+	for address, backend := range s.backends {
+		backends[address] = backend
+	}
+	s.mu.RUnlock()
+
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	result := make([]common.MetricResponseDto, 0)
+	for address, backend := range backends {
+		wg.Add(1)
+		go func(address string, backend *Backend) {
+			defer wg.Done()
+			metrics, err := backend.FindAllMetrics(ctx)
+			if err != nil {
+				s.logger.Error("Error during find metrics of shard", zap.String("backend", address), zap.Error(err))
+				return
+			}
+
+			mu.Lock()
+			defer mu.Unlock()
+			for _, metric := range metrics {
+				if owner, _, err := s.owner(metric.ID); err == nil && owner == address {
+					result = append(result, metric)
+				}
+			}
+		}(address, backend)
+	}
+	wg.Wait()
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].ID == result[j].ID {
+			return result[i].MType < result[j].MType
+		}
+		return result[i].ID < result[j].ID
+	})
+	return result
+}
+
+func (s *Service) CounterRate(ctx context.Context, name string, window time.Duration) (float64, int, error) {
+	_, backend, err := s.owner(name)
+	if err != nil {
+		return 0, 0, err
+	}
+	return backend.CounterRate(ctx, name, window)
+}
@@ -0,0 +1,155 @@
+package proxy
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	metricsApi "github.com/desepticon55/metrics-collector/internal/server/api/metrics/http"
+	metricsMappers "github.com/desepticon55/metrics-collector/internal/server/mapper/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/history"
+	metricsServices "github.com/desepticon55/metrics-collector/internal/server/service/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/storage/memory"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+// startBackend runs metrics server with memory storage and returns its address and service
+func startBackend(t *testing.T) (string, metricsServices.Service) {
+	storage := memory.New(t.TempDir()+"/metrics.json", false, 0)
+	rates := history.New(time.Hour)
+	storage.AddListener(rates.Observe)
+	service := metricsServices.New(storage, metricsMappers.NewMapper(validator.New()), server.NewRetrier(1, 0, 0))
+	logger := zap.NewNop()
+
+	router := chi.NewRouter()
+	router.Method(http.MethodGet, "/", metricsApi.NewFindAllMetricsHandler(service, logger))
+	router.Method(http.MethodPost, "/value/", metricsApi.NewFindOneMetricHandler(service, rates, logger))
+	router.Method(http.MethodPost, "/updates/", metricsApi.NewCreateListMetricsHandlerFromJSON(server.Config{}, nil, service, logger))
+	router.Method(http.MethodGet, "/rate/counter/{name}", metricsApi.NewFindCounterRateHandler(rates, logger))
+
+	backend := httptest.NewServer(router)
+	t.Cleanup(backend.Close)
+	return strings.TrimPrefix(backend.URL, "http://"), service
+}
+
+func gauge(id string, value float64) common.MetricRequestDto {
+	return common.MetricRequestDto{ID: id, MType: common.Gauge, Value: &value}
+}
+
+func TestService_ShardsMetrics(t *testing.T) {
+	ctx := context.Background()
+	addresses := make([]string, 0, 3)
+	backends := make(map[string]metricsServices.Service)
+	for i := 0; i < 3; i++ {
+		address, service := startBackend(t)
+		addresses = append(addresses, address)
+		backends[address] = service
+	}
+	proxy := NewService(128, addresses, "", zap.NewNop())
+
+	var batch []common.MetricRequestDto
+	for _, id := range []string{"Alloc", "Frees", "HeapIdle", "HeapInuse", "NumGC", "Sys", "disk;mount=/"} {
+		batch = append(batch, gauge(id, 1))
+	}
+	delta := int64(5)
+	batch = append(batch, common.MetricRequestDto{ID: "PollCount", MType: common.Counter, Delta: &delta})
+
+	response, err := proxy.SaveMetrics(ctx, batch)
+	assert.NoError(t, err)
+	assert.Len(t, response, len(batch))
+
+	// every metric is stored only by its owner
+	for _, metric := range batch {
+		owner, _, _ := proxy.owner(metric.ID)
+		for address, service := range backends {
+			_, err := service.FindOneMetric(ctx, metric.ID, metric.MType)
+			assert.Equal(t, address == owner, err == nil, metric.ID)
+		}
+	}
+
+	all := proxy.FindAllMetrics(ctx)
+	assert.Len(t, all, len(batch))
+	found, err := proxy.FindOneMetric(ctx, "PollCount", common.Counter)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(5), *found.Delta)
+	_, err = proxy.FindOneMetric(ctx, "Unknown", common.Gauge)
+	var notFoundError *server.MetricNotFoundError
+	assert.ErrorAs(t, err, &notFoundError)
+
+	// batch with invalid metric is rejected as a whole, so no shard saves its part
+	_, err = proxy.SaveMetrics(ctx, []common.MetricRequestDto{gauge("Frees", 100), {ID: "Alloc", MType: common.Gauge}})
+	var validationError *server.ValidationError
+	assert.ErrorAs(t, err, &validationError)
+	found, err = proxy.FindOneMetric(ctx, "Frees", common.Gauge)
+	assert.NoError(t, err)
+	assert.NotEqual(t, 100.0, *found.Value)
+
+	// metric rejected by backend is validation error too
+	_, err = NewBackend(addresses[0], "").SaveMetrics(ctx, []common.MetricRequestDto{{ID: "Alloc", MType: common.Gauge}})
+	assert.ErrorAs(t, err, &validationError)
+
+	// metrics of removed backend are not visible, the rest are served by the same shards
+	removed := addresses[0]
+	proxy.SetBackends(addresses[1:])
+	assert.Equal(t, len(addresses)-1, len(proxy.Backends()))
+	for _, metric := range proxy.FindAllMetrics(ctx) {
+		owner, _, _ := proxy.owner(metric.ID)
+		assert.NotEqual(t, removed, owner)
+	}
+}
+
+func TestService_SavePartially(t *testing.T) {
+	ctx := context.Background()
+	address, backend := startBackend(t)
+	// nothing listens on the second shard
+	proxy := NewService(128, []string{address, "127.0.0.1:1"}, "", zap.NewNop())
+
+	var batch []common.MetricRequestDto
+	var failedIDs []string
+	for _, id := range []string{"Alloc", "Frees", "HeapIdle", "HeapInuse", "NumGC", "Sys"} {
+		batch = append(batch, gauge(id, 1))
+		if owner, _, _ := proxy.owner(id); owner != address {
+			failedIDs = append(failedIDs, id)
+		}
+	}
+	if !assert.NotEmpty(t, failedIDs) || !assert.Less(t, len(failedIDs), len(batch)) {
+		return
+	}
+
+	response, err := proxy.SaveMetrics(ctx, batch)
+	var partialError *server.PartialSaveError
+	if assert.ErrorAs(t, err, &partialError) {
+		assert.Equal(t, response, partialError.Saved)
+		assert.Len(t, partialError.Saved, len(batch)-len(failedIDs))
+		var ids []string
+		for _, metric := range partialError.Failed {
+			ids = append(ids, metric.ID)
+		}
+		assert.ElementsMatch(t, failedIDs, ids)
+	}
+	assert.Len(t, backend.FindAllMetrics(ctx), len(batch)-len(failedIDs))
+
+	// saved part is not failed, client gets failed metrics to resend
+	handler := metricsApi.NewCreateListMetricsHandlerFromJSON(server.Config{}, nil, proxy, zap.NewNop())
+	body, err := json.Marshal(batch)
+	assert.NoError(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+
+	assert.Equal(t, http.StatusMultiStatus, recorder.Code)
+	var partial server.PartialSaveError
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &partial))
+	assert.Len(t, partial.Saved, len(batch)-len(failedIDs))
+	assert.Len(t, partial.Failed, len(failedIDs))
+}
@@ -0,0 +1,76 @@
+package server
+
+import "time"
+
+type AlertStatus string
+
+const (
+	AlertPending  AlertStatus = "pending"
+	AlertFiring   AlertStatus = "firing"
+	AlertResolved AlertStatus = "resolved"
+)
+
+// AlertState is a state of alert rule for single series
+type AlertState struct {
+	Rule       string      `json:"rule"`
+	Series     string      `json:"series"`
+	Status     AlertStatus `json:"status"`
+	Value      float64     `json:"value"`
+	ActiveAt   time.Time   `json:"active_at"`
+	FiredAt    *time.Time  `json:"fired_at,omitempty"`
+	ResolvedAt *time.Time  `json:"resolved_at,omitempty"`
+}
+
+// AlertRuleStatus is alert rule with states of all its series
+type AlertRuleStatus struct {
+	Name       string        `json:"name"`
+	Expr       string        `json:"expr"`
+	Hysteresis float64       `json:"hysteresis,omitempty"`
+	Alerts     []*AlertState `json:"alerts"`
+}
+
+// NotificationRecord is a result of delivery of grouped alert transitions to notification channel
+type NotificationRecord struct {
+	Channel   string    `json:"channel"`
+	Rule      string    `json:"rule"`
+	Alerts    []string  `json:"alerts"`
+	Attempts  int       `json:"attempts"`
+	Delivered bool      `json:"delivered"`
+	Error     string    `json:"error,omitempty"`
+	Time      time.Time `json:"time"`
+}
+
+// Silence mutes notifications of alerts matched by rule name, metric name pattern (path.Match syntax)
+// and labels. Empty matchers match everything
+type Silence struct {
+	ID         string            `json:"id"`
+	Rule       string            `json:"rule,omitempty"`
+	Metric     string            `json:"metric,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	StartsAt   time.Time         `json:"starts_at"`
+	EndsAt     time.Time         `json:"ends_at"`
+	Recurrence *Recurrence       `json:"recurrence,omitempty"`
+	CreatedBy  string            `json:"created_by"`
+	Comment    string            `json:"comment"`
+	CreatedAt  time.Time         `json:"created_at"`
+}
+
+// Recurrence narrows silence to maintenance windows, which start at Start ("15:04", UTC)
+// on Weekdays ("mon", "tue", ...; every day when empty) and last Duration (e.g. "2h")
+type Recurrence struct {
+	Weekdays []string `json:"weekdays,omitempty"`
+	Start    string   `json:"start"`
+	Duration string   `json:"duration"`
+}
+
+// AnomalyBaseline is learned normal behaviour of gauge and deviation of its last value
+type AnomalyBaseline struct {
+	Metric    string     `json:"metric"`
+	Mean      float64    `json:"mean"`
+	StdDev    float64    `json:"std_dev"`
+	Value     float64    `json:"value"`
+	ZScore    float64    `json:"z_score"`
+	Samples   int        `json:"samples"`
+	Anomalous bool       `json:"anomalous"`
+	Since     *time.Time `json:"since,omitempty"`
+}
@@ -0,0 +1,207 @@
+package interceptors
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/proto"
+	"net"
+	"strings"
+	"time"
+)
+
+// Metadata key of request ID, it's taken from client when present and returned in response header
+const RequestIDMetadataKey = "x-request-id"
+
+type requestIDKey struct{}
+
+// Health checks come from load balancers which don't sign calls and may be outside of trusted subnet.
+// Reflection only describes API, so tools like grpcurl can use it without signing
+const (
+	healthPrefix     = "/grpc.health.v1.Health/"
+	reflectionPrefix = "/grpc.reflection."
+)
+
+// check is called before handler, request is nil for streaming calls
+type check func(ctx context.Context, method string, request any) error
+
+// ServerOptions chains interceptors of every call: request ID, access log, panic recovery,
+// then trusted subnet and signature checks when they are configured. Signatures are not checked when verifier is nil
+func ServerOptions(config server.Config, verifier *signing.Verifier, logger *zap.Logger) []grpc.ServerOption {
+	checks := []check{trustedSubnet(config.TrustedSubnet, logger), signature(verifier, logger)}
+
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(
+			func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+				ctx = withRequestID(ctx)
+				start := time.Now()
+				defer func() {
+					logCall(ctx, logger, info.FullMethod, start, err)
+				}()
+				defer recoverPanic(ctx, logger, info.FullMethod, &err)
+
+				for _, c := range checks {
+					if err := c(ctx, info.FullMethod, req); err != nil {
+						return nil, err
+					}
+				}
+				return handler(ctx, req)
+			},
+		),
+		grpc.ChainStreamInterceptor(
+			func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+				ctx := withRequestID(ss.Context())
+				start := time.Now()
+				defer func() {
+					logCall(ctx, logger, info.FullMethod, start, err)
+				}()
+				defer recoverPanic(ctx, logger, info.FullMethod, &err)
+
+				for _, c := range checks {
+					if err := c(ctx, info.FullMethod, nil); err != nil {
+						return err
+					}
+				}
+				return handler(srv, &contextStream{ServerStream: ss, ctx: ctx, verifier: verifier, logger: logger})
+			},
+		),
+	}
+}
+
+// RequestID returns ID of call assigned by interceptor
+func RequestID(ctx context.Context) string {
+	requestID, _ := ctx.Value(requestIDKey{}).(string)
+	return requestID
+}
+
+func withRequestID(ctx context.Context) context.Context {
+	var requestID string
+	if md, ok := metadata.FromIncomingContext(ctx); ok && len(md.Get(RequestIDMetadataKey)) > 0 {
+		requestID = md.Get(RequestIDMetadataKey)[0]
+	} else {
+		id := make([]byte, 8)
+		rand.Read(id)
+		requestID = hex.EncodeToString(id)
+	}
+	grpc.SetHeader(ctx, metadata.Pairs(RequestIDMetadataKey, requestID))
+	return context.WithValue(ctx, requestIDKey{}, requestID)
+}
+
+func logCall(ctx context.Context, logger *zap.Logger, method string, start time.Time, err error) {
+	var remoteAddr string
+	if p, ok := peer.FromContext(ctx); ok {
+		remoteAddr = p.Addr.String()
+	}
+	logger.Info("gRPC request",
+		zap.String("method", method),
+		zap.String("code", status.Code(err).String()),
+		zap.Duration("duration", time.Since(start)),
+		zap.String("remote_addr", remoteAddr),
+		zap.String("peer_identity", common.PeerIdentity(ctx)),
+		zap.String("request_id", RequestID(ctx)),
+	)
+}
+
+// recoverPanic turns panic of handler to Internal error, so server keeps serving other calls
+func recoverPanic(ctx context.Context, logger *zap.Logger, method string, err *error) {
+	if r := recover(); r != nil {
+		logger.Error("Panic during handle gRPC request", zap.String("method", method), zap.String("request_id", RequestID(ctx)), zap.Any("panic", r), zap.Stack("stack"))
+		*err = status.Error(codes.Internal, "Internal server error")
+	}
+}
+
+// trustedSubnet checks address of connection, so address reported by client is not trusted
+func trustedSubnet(subnet string, logger *zap.Logger) check {
+	return func(ctx context.Context, method string, request any) error {
+		if subnet == "" || strings.HasPrefix(method, healthPrefix) {
+			return nil
+		}
+
+		p, ok := peer.FromContext(ctx)
+		if !ok {
+			return status.Error(codes.PermissionDenied, "Forbidden: peer address is unknown")
+		}
+		host, _, err := net.SplitHostPort(p.Addr.String())
+		if err != nil {
+			host = p.Addr.String()
+		}
+		if !isIPInTrustedSubnet(host, subnet) {
+			logger.Error("Forbidden: IP not in trusted subnet", zap.String("agent ip", host))
+			return status.Error(codes.PermissionDenied, "Forbidden: IP not in trusted subnet")
+		}
+		return nil
+	}
+}
+
+// signature checks signature of call made by signing.DialOptions
+func signature(verifier *signing.Verifier, logger *zap.Logger) check {
+	return func(ctx context.Context, method string, request any) error {
+		if verifier == nil || strings.HasPrefix(method, healthPrefix) || strings.HasPrefix(method, reflectionPrefix) {
+			return nil
+		}
+
+		payload := []byte(method)
+		if message, ok := request.(proto.Message); ok {
+			var err error
+			payload, err = signing.RequestPayload(message)
+			if err != nil {
+				return status.Error(codes.Internal, fmt.Sprintf("Error during serialize request: %v", err))
+			}
+		}
+		if err := verifier.Verify(signing.FromIncomingContext(ctx), payload); err != nil {
+			logger.Error("Invalid HashSHA256", zap.String("method", method), zap.Error(err))
+			return status.Errorf(codes.Unauthenticated, "Invalid HashSHA256: %v", err)
+		}
+		return nil
+	}
+}
+
+// contextStream replaces context of stream with context containing request ID
+// and checks signatures of received messages which carry them
+type contextStream struct {
+	grpc.ServerStream
+	ctx      context.Context
+	verifier *signing.Verifier
+	logger   *zap.Logger
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *contextStream) RecvMsg(m any) error {
+	if err := s.ServerStream.RecvMsg(m); err != nil {
+		return err
+	}
+	if message, ok := m.(signing.SignedMessage); ok && s.verifier != nil {
+		if err := s.verifier.VerifyMessage(message); err != nil {
+			s.logger.Error("Invalid HashSHA256 of streamed message", zap.String("request_id", RequestID(s.ctx)), zap.Error(err))
+			return status.Errorf(codes.Unauthenticated, "Invalid HashSHA256: %v", err)
+		}
+	}
+	return nil
+}
+
+func isIPInTrustedSubnet(ipStr, subnetStr string) bool {
+	ip := net.ParseIP(ipStr)
+	if ip == nil {
+		return false
+	}
+
+	_, trustedNet, err := net.ParseCIDR(subnetStr)
+	if err != nil {
+		return false
+	}
+
+	return trustedNet.Contains(ip)
+}
@@ -0,0 +1,137 @@
+package interceptors
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	handler "github.com/desepticon55/metrics-collector/internal/server/api/metrics/grpc"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	"github.com/desepticon55/metrics-collector/proto/metrics"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"net"
+	"strconv"
+	"testing"
+)
+
+type stubMetricsService struct{}
+
+func (s stubMetricsService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	return nil, nil
+}
+
+func (s stubMetricsService) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error) {
+	panic("unexpected call")
+}
+
+func (s stubMetricsService) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	return nil
+}
+
+// newClient starts server on loopback interface, so peer address of calls is 127.0.0.1
+func newClient(t *testing.T, config server.Config, options ...grpc.DialOption) metrics.MetricsServiceClient {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := grpc.NewServer(ServerOptions(config, signing.NewDefaultVerifier(config.HashKey), zap.NewNop())...)
+	metrics.RegisterMetricsServiceServer(s, &handler.MetricsServer{Service: stubMetricsService{}, Logger: zap.NewNop()})
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient(listener.Addr().String(), append(options, grpc.WithTransportCredentials(insecure.NewCredentials()))...)
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return metrics.NewMetricsServiceClient(conn)
+}
+
+func TestTrustedSubnet(t *testing.T) {
+	request := &metrics.MetricsRequest{Metrics: []*metrics.Metric{{Id: "Alloc", Type: "gauge", Value: 1}}, Ip: "192.168.1.2"}
+
+	_, err := newClient(t, server.Config{TrustedSubnet: "127.0.0.0/8"}).SendMetrics(context.Background(), request)
+	assert.NoError(t, err)
+
+	// address reported by client is ignored
+	client := newClient(t, server.Config{TrustedSubnet: "192.168.1.0/24"})
+	_, err = client.SendMetrics(context.Background(), request)
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+
+	watch, err := client.Watch(context.Background(), &metrics.WatchRequest{})
+	assert.NoError(t, err)
+	_, err = watch.Recv()
+	assert.Equal(t, codes.PermissionDenied, status.Code(err))
+}
+
+func TestSignature(t *testing.T) {
+	request := &metrics.MetricsRequest{Metrics: []*metrics.Metric{{Id: "Alloc", Type: "gauge", Value: 1}}}
+	config := server.Config{HashKey: "key"}
+
+	_, err := newClient(t, config, signing.DialOptions("key")...).SendMetrics(context.Background(), request)
+	assert.NoError(t, err)
+
+	for _, client := range []metrics.MetricsServiceClient{newClient(t, config), newClient(t, config, signing.DialOptions("other")...)} {
+		_, err = client.SendMetrics(context.Background(), request)
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+
+	// replayed call is rejected
+	payload, err := signing.MetricsPayload(request.Metrics)
+	assert.NoError(t, err)
+	signature := signing.Sign("key", payload)
+	ctx := metadata.AppendToOutgoingContext(context.Background(),
+		signing.SignatureHeader, signature.Value,
+		signing.TimestampHeader, strconv.FormatInt(signature.Timestamp, 10),
+		signing.NonceHeader, signature.Nonce)
+	client := newClient(t, config)
+	_, err = client.SendMetrics(ctx, request)
+	assert.NoError(t, err)
+	_, err = client.SendMetrics(ctx, request)
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	// stream is signed with method name
+	watch, err := newClient(t, config, signing.DialOptions("key")...).Watch(context.Background(), &metrics.WatchRequest{})
+	assert.NoError(t, err)
+	_, err = watch.Recv()
+	assert.Equal(t, codes.Unimplemented, status.Code(err))
+}
+
+func TestHealthIsNotChecked(t *testing.T) {
+	config := server.Config{HashKey: "key", TrustedSubnet: "192.168.1.0/24"}
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	s := grpc.NewServer(ServerOptions(config, signing.NewDefaultVerifier(config.HashKey), zap.NewNop())...)
+	healthpb.RegisterHealthServer(s, health.NewServer())
+	go s.Serve(listener)
+	defer s.Stop()
+
+	conn, err := grpc.NewClient(listener.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	response, err := healthpb.NewHealthClient(conn).Check(context.Background(), &healthpb.HealthCheckRequest{})
+	assert.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_SERVING, response.Status)
+}
+
+func TestRequestIDAndRecovery(t *testing.T) {
+	client := newClient(t, server.Config{})
+
+	var header metadata.MD
+	ctx := metadata.AppendToOutgoingContext(context.Background(), RequestIDMetadataKey, "request-1")
+	_, err := client.Ping(ctx, &metrics.PingRequest{}, grpc.Header(&header))
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+	assert.Equal(t, []string{"request-1"}, header.Get(RequestIDMetadataKey))
+
+	// FindOneMetric of service panics
+	_, err = client.GetMetric(context.Background(), &metrics.GetMetricRequest{Id: "Alloc", Type: "gauge"}, grpc.Header(&header))
+	assert.Equal(t, codes.Internal, status.Code(err))
+	assert.Len(t, header.Get(RequestIDMetadataKey)[0], 16)
+
+	_, err = client.SendMetrics(context.Background(), &metrics.MetricsRequest{})
+	assert.NoError(t, err)
+}
@@ -3,6 +3,10 @@ package metrics
 import (
 	"context"
 	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/service/forecast"
+	"github.com/desepticon55/metrics-collector/internal/server/service/stream"
+	"time"
 )
 
 type MetricsService interface {
@@ -12,3 +16,68 @@ type MetricsService interface {
 
 	FindAllMetrics(ctx context.Context) []common.MetricResponseDto
 }
+
+type Pinger interface {
+	Ping(ctx context.Context) error
+}
+
+type MetricsStream interface {
+	Subscribe(filter stream.Filter, resumeFrom uint64) *stream.Subscription
+
+	Unsubscribe(subscription *stream.Subscription)
+}
+
+type ReplicationStream interface {
+	Resume(filter stream.Filter, resumeFrom uint64) (*stream.Subscription, bool)
+
+	Unsubscribe(subscription *stream.Subscription)
+
+	LastSeq() uint64
+}
+
+type ReplicationService interface {
+	ReplicationStatus(ctx context.Context) server.ReplicationStatus
+
+	Promote(ctx context.Context) server.ReplicationStatus
+}
+
+type AlertsService interface {
+	FindAllAlerts(ctx context.Context) []server.AlertRuleStatus
+}
+
+type NotificationsService interface {
+	FindAllNotifications(ctx context.Context) []server.NotificationRecord
+}
+
+type SilencesService interface {
+	CreateSilence(ctx context.Context, silence server.Silence) (server.Silence, error)
+
+	FindAllSilences(ctx context.Context, includeExpired bool) []server.Silence
+
+	ExpireSilence(ctx context.Context, id string) (server.Silence, error)
+}
+
+type AnomaliesService interface {
+	FindAllAnomalies(ctx context.Context) []server.AnomalyBaseline
+}
+
+type RatesService interface {
+	CounterRate(ctx context.Context, name string, window time.Duration) (float64, int, error)
+}
+
+type ForecastService interface {
+	Forecast(ctx context.Context, name string, method forecast.Method, window time.Duration, ahead time.Duration, threshold *float64) (common.ForecastResponseDto, error)
+}
+
+type SLOService interface {
+	FindAllSLOs(ctx context.Context) []server.SLOStatus
+}
+
+// StreamPositionStorage keeps positions of agent streams, so batches applied before restart of server are skipped
+type StreamPositionStorage interface {
+	SaveStreamPosition(ctx context.Context, position server.StreamPosition) error
+
+	FindAllStreamPositions(ctx context.Context) ([]server.StreamPosition, error)
+
+	DeleteStreamPosition(ctx context.Context, agentID string) error
+}
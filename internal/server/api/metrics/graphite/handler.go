@@ -0,0 +1,119 @@
+package graphite
+
+import (
+	"bufio"
+	"context"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const maxBatchSize = 500
+
+// Connection is closed when client sends nothing during this time and server has no idle timeout
+const defaultIdleTimeout = 5 * time.Minute
+
+// Graphite plaintext protocol server. Every line has format "path value timestamp"
+// and is saved as gauge metric with ID = path. Timestamp is validated but not stored,
+// metric gets the time it's saved
+type MetricsServer struct {
+	Service     metrics.MetricsService
+	Logger      *zap.Logger
+	IdleTimeout time.Duration
+}
+
+func (s *MetricsServer) Serve(listener net.Listener) error {
+	for {
+		conn, err := listener.Accept()
+		if err != nil {
+			if errors.Is(err, net.ErrClosed) {
+				return nil
+			}
+			return err
+		}
+		go s.handleConnection(conn)
+	}
+}
+
+func (s *MetricsServer) handleConnection(conn net.Conn) {
+	defer conn.Close()
+
+	idleTimeout := s.IdleTimeout
+	if idleTimeout <= 0 {
+		idleTimeout = defaultIdleTimeout
+	}
+
+	reader := bufio.NewReader(conn)
+	var batch []common.MetricRequestDto
+	for {
+		if err := conn.SetReadDeadline(time.Now().Add(idleTimeout)); err != nil {
+			s.Logger.Error("Error during set read deadline of graphite connection", zap.Error(err))
+			return
+		}
+		line, err := reader.ReadString('\n')
+		if len(line) > 0 {
+			metric, parseErr := parseLine(line)
+			if parseErr != nil {
+				s.Logger.Error("Error during parse graphite line", zap.String("line", line), zap.Error(parseErr))
+			} else if metric != nil {
+				batch = append(batch, *metric)
+			}
+		}
+
+		if len(batch) > 0 && (err != nil || len(batch) >= maxBatchSize || reader.Buffered() == 0) {
+			if _, saveErr := s.Service.SaveMetrics(context.Background(), batch); saveErr != nil {
+				s.Logger.Error("Error during save graphite metrics", zap.Error(saveErr))
+			}
+			batch = nil
+		}
+
+		if err != nil {
+			switch {
+			case errors.Is(err, io.EOF):
+			case errors.Is(err, os.ErrDeadlineExceeded):
+				s.Logger.Debug("Idle graphite connection is closed", zap.String("remote", conn.RemoteAddr().String()))
+			default:
+				s.Logger.Error("Error during read graphite connection", zap.Error(err))
+			}
+			return
+		}
+	}
+}
+
+func parseLine(line string) (*common.MetricRequestDto, error) {
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return nil, nil
+	}
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("expected 'path value timestamp', got %d fields", len(fields))
+	}
+
+	name, labels := common.SplitLabels(fields[0])
+	if name == "" {
+		return nil, fmt.Errorf("metric path should be filled")
+	}
+
+	value, err := strconv.ParseFloat(fields[1], 64)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect value = %s: %w", fields[1], err)
+	}
+
+	if _, err := strconv.ParseFloat(fields[2], 64); err != nil {
+		return nil, fmt.Errorf("incorrect timestamp = %s: %w", fields[2], err)
+	}
+
+	return &common.MetricRequestDto{
+		ID:    common.JoinLabels(name, labels),
+		MType: common.Gauge,
+		Value: &value,
+	}, nil
+}
@@ -0,0 +1,100 @@
+package graphite
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/mock"
+	"go.uber.org/zap"
+	"io"
+	"net"
+	"testing"
+	"time"
+)
+
+type MockMetricsService struct {
+	mock.Mock
+}
+
+func (m *MockMetricsService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	args := m.Called(ctx, request)
+	return args.Get(0).([]common.MetricResponseDto), args.Error(1)
+}
+
+func (m *MockMetricsService) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error) {
+	panic("implement me")
+}
+
+func (m *MockMetricsService) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	panic("implement me")
+}
+
+func TestParseLine(t *testing.T) {
+	metric, err := parseLine("servers.host1.cpu 42.5 1718000000\n")
+	assert.NoError(t, err)
+	assert.Equal(t, "servers.host1.cpu", metric.ID)
+	assert.Equal(t, common.Gauge, metric.MType)
+	assert.Equal(t, 42.5, *metric.Value)
+
+	metric, err = parseLine("disk.used;host=a;dc=eu 10 -1")
+	assert.NoError(t, err)
+	assert.Equal(t, "disk.used;dc=eu;host=a", metric.ID)
+
+	metric, err = parseLine("   ")
+	assert.NoError(t, err)
+	assert.Nil(t, metric)
+
+	_, err = parseLine("servers.host1.cpu abc 1718000000")
+	assert.Error(t, err)
+
+	_, err = parseLine("servers.host1.cpu 1")
+	assert.Error(t, err)
+}
+
+func TestMetricsServer_Serve(t *testing.T) {
+	saved := make(chan []common.MetricRequestDto, 1)
+	mockService := new(MockMetricsService)
+	mockService.On("SaveMetrics", mock.Anything, mock.Anything).
+		Run(func(args mock.Arguments) { saved <- args.Get(1).([]common.MetricRequestDto) }).
+		Return([]common.MetricResponseDto{}, nil)
+
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	server := &MetricsServer{Service: mockService, Logger: zap.NewNop()}
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+	_, err = conn.Write([]byte("a.b 1 1718000000\na.c 2 1718000000\n"))
+	assert.NoError(t, err)
+	conn.Close()
+
+	select {
+	case metrics := <-saved:
+		assert.Len(t, metrics, 2)
+		assert.Equal(t, "a.b", metrics[0].ID)
+		assert.Equal(t, "a.c", metrics[1].ID)
+	case <-time.After(time.Second):
+		t.Fatal("metrics were not saved")
+	}
+}
+
+func TestMetricsServer_ClosesIdleConnection(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	defer listener.Close()
+
+	server := &MetricsServer{Service: new(MockMetricsService), Logger: zap.NewNop(), IdleTimeout: 50 * time.Millisecond}
+	go server.Serve(listener)
+
+	conn, err := net.Dial("tcp", listener.Addr().String())
+	assert.NoError(t, err)
+	defer conn.Close()
+
+	// server closes connection, so read returns instead of waiting for test deadline
+	assert.NoError(t, conn.SetReadDeadline(time.Now().Add(5*time.Second)))
+	_, err = conn.Read(make([]byte, 1))
+	assert.ErrorIs(t, err, io.EOF)
+}
@@ -2,77 +2,181 @@ package grpc
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
+	"encoding/base64"
+	"errors"
 	"github.com/desepticon55/metrics-collector/internal/common"
 	"github.com/desepticon55/metrics-collector/internal/server"
 	metrics2 "github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/stream"
 	grpc "github.com/desepticon55/metrics-collector/proto/metrics"
 	"go.uber.org/zap"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
-	"net"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Page size of ListMetrics when it's not set and its upper limit
+const (
+	defaultPageSize = 100
+	maxPageSize     = 1000
 )
 
 type MetricsServer struct {
 	grpc.UnimplementedMetricsServiceServer
 	Service metrics2.MetricsService
-	Config  server.Config
-	Logger  *zap.Logger
+	Pool    metrics2.Pinger
+	Stream  metrics2.MetricsStream
+	// Positions keeps positions of agent streams, they are kept only in memory when it's not set
+	Positions metrics2.StreamPositionStorage
+	Logger    *zap.Logger
+
+	agentsMu sync.Mutex
+	agents   map[string]*agentStream
 }
 
 func (s *MetricsServer) SendMetrics(ctx context.Context, req *grpc.MetricsRequest) (*grpc.MetricsResponse, error) {
-	if s.Config.HashKey != "" {
-		hash := sha256.Sum256(append([]byte(req.Ip), []byte(s.Config.HashKey)...))
-		hashStr := hex.EncodeToString(hash[:])
-		if req.Hash != hashStr {
-			s.Logger.Error("Invalid HashSHA256", zap.String("header hash", req.Hash), zap.String("calculated hash", hashStr))
-			return nil, status.Error(codes.InvalidArgument, "Invalid HashSHA256")
-		}
+	var metrics []common.MetricRequestDto
+	for _, metric := range req.Metrics {
+		metrics = append(metrics, common.MetricRequestFromProto(metric))
 	}
 
-	if len(s.Config.TrustedSubnet) != 0 {
-		agentIP := req.Ip
-		if agentIP == "" {
-			s.Logger.Error("X-Real-IP header missing", zap.String("agent ip", req.Ip))
-			return nil, status.Error(codes.InvalidArgument, "X-Real-IP header missing")
-		}
+	_, err := s.Service.SaveMetrics(ctx, metrics)
+	if err != nil {
+		return nil, toStatus(err, s.Logger)
+	}
 
-		if !isIPInTrustedSubnet(agentIP, s.Config.TrustedSubnet) {
-			s.Logger.Error("Forbidden: IP not in trusted subnet", zap.String("agent ip", req.Ip))
-			return nil, status.Error(codes.InvalidArgument, "Forbidden: IP not in trusted subnet")
-		}
+	return &grpc.MetricsResponse{Status: "ok"}, nil
+}
+
+// GetMetric returns current value of metric like POST /value/
+func (s *MetricsServer) GetMetric(ctx context.Context, req *grpc.GetMetricRequest) (*grpc.Metric, error) {
+	metricType := common.MetricType(req.Type)
+	if metricType != common.Gauge && metricType != common.Counter {
+		return nil, status.Errorf(codes.InvalidArgument, "Unsupported metric type = '%s'", req.Type)
 	}
 
-	var metrics []common.MetricRequestDto
-	for _, metric := range req.Metrics {
-		metrics = append(metrics, common.MetricRequestDto{
-			ID:    metric.Id,
-			MType: common.MetricType(metric.Type),
-			Delta: &metric.Delta,
-			Value: &metric.Value,
-		})
+	metric, err := s.Service.FindOneMetric(ctx, req.Id, metricType)
+	if err != nil {
+		return nil, toStatus(err, s.Logger)
 	}
+	return common.MetricResponseToProto(metric), nil
+}
 
-	_, err := s.Service.SaveMetrics(ctx, metrics)
+// ListMetrics returns page of metrics matching filter like GET /. Metrics are ordered by name and type,
+// page token is the last returned metric, so pages stay consistent when new metrics are added
+func (s *MetricsServer) ListMetrics(ctx context.Context, req *grpc.ListMetricsRequest) (*grpc.ListMetricsResponse, error) {
+	matched, nextPageToken, err := listPage(ctx, s.Service, stream.Filter{Name: req.Name, Type: common.MetricType(req.Type)}, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &grpc.ListMetricsResponse{NextPageToken: nextPageToken}
+	for _, metric := range matched {
+		response.Metrics = append(response.Metrics, common.MetricResponseToProto(metric))
+	}
+	return response, nil
+}
+
+// UpdateMetric saves single metric like POST /update/ and returns its new value
+func (s *MetricsServer) UpdateMetric(ctx context.Context, req *grpc.UpdateMetricRequest) (*grpc.Metric, error) {
+	if req.Metric == nil {
+		return nil, status.Error(codes.InvalidArgument, "Metric is required")
+	}
+
+	saved, err := s.Service.SaveMetrics(ctx, []common.MetricRequestDto{common.MetricRequestFromProto(req.Metric)})
 	if err != nil {
-		s.Logger.Error("Internal server error", zap.Error(err))
+		return nil, toStatus(err, s.Logger)
+	}
+	if len(saved) == 0 {
 		return nil, status.Error(codes.Internal, "Internal server error")
 	}
+	return common.MetricResponseToProto(saved[0]), nil
+}
+
+// Ping checks database connection like GET /ping
+func (s *MetricsServer) Ping(ctx context.Context, req *grpc.PingRequest) (*grpc.PingResponse, error) {
+	if s.Pool == nil {
+		s.Logger.Error("Connect with DB was not created")
+		return nil, status.Error(codes.Unavailable, "Connect with DB was not created")
+	}
 
-	return &grpc.MetricsResponse{Status: "ok"}, nil
+	ctx, cancelFunc := context.WithTimeout(ctx, 1*time.Second)
+	defer cancelFunc()
+
+	if err := s.Pool.Ping(ctx); err != nil {
+		s.Logger.Error("Database is not available", zap.Error(err))
+		return nil, status.Error(codes.Unavailable, "Database is not available")
+	}
+	return &grpc.PingResponse{Status: "ok"}, nil
 }
 
-func isIPInTrustedSubnet(ipStr, subnetStr string) bool {
-	ip := net.ParseIP(ipStr)
-	if ip == nil {
-		return false
+// toStatus maps service errors to gRPC status codes
+func toStatus(err error, logger *zap.Logger) error {
+	var notFoundError *server.MetricNotFoundError
+	var validationError *server.ValidationError
+	var partialError *server.PartialSaveError
+	switch {
+	case errors.As(err, &notFoundError):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.As(err, &validationError):
+		return status.Error(codes.InvalidArgument, err.Error())
+	case errors.Is(err, server.ErrReadOnly):
+		return status.Error(codes.FailedPrecondition, err.Error())
+	case errors.As(err, &partialError):
+		// status can't hold saved metrics, message lists failed ones so they can be resent
+		ids := make([]string, 0, len(partialError.Failed))
+		for _, metric := range partialError.Failed {
+			ids = append(ids, metric.ID)
+		}
+		return status.Errorf(codes.Aborted, "%s, not saved metrics: %s", err.Error(), strings.Join(ids, ", "))
+	default:
+		logger.Error("Internal server error", zap.Error(err))
+		return status.Error(codes.Internal, "Internal server error")
+	}
+}
+
+// listPage returns page of metrics matching filter ordered by name and type and token of the next page
+func listPage(ctx context.Context, service metrics2.MetricsService, filter stream.Filter, size int32, token string) ([]common.MetricResponseDto, string, error) {
+	pageSize := int(size)
+	switch {
+	case pageSize < 0:
+		return nil, "", status.Error(codes.InvalidArgument, "Page size should not be negative")
+	case pageSize == 0:
+		pageSize = defaultPageSize
+	case pageSize > maxPageSize:
+		pageSize = maxPageSize
 	}
 
-	_, trustedNet, err := net.ParseCIDR(subnetStr)
+	after, err := decodePageToken(token)
 	if err != nil {
-		return false
+		return nil, "", status.Error(codes.InvalidArgument, "Invalid page token")
+	}
+
+	var matched []common.MetricResponseDto
+	for _, metric := range service.FindAllMetrics(ctx) {
+		if filter.Match(metric) && pageKey(metric) > after {
+			matched = append(matched, metric)
+		}
 	}
+	sort.Slice(matched, func(i, j int) bool {
+		return pageKey(matched[i]) < pageKey(matched[j])
+	})
+
+	if len(matched) <= pageSize {
+		return matched, "", nil
+	}
+	matched = matched[:pageSize]
+	return matched, base64.RawURLEncoding.EncodeToString([]byte(pageKey(matched[pageSize-1]))), nil
+}
+
+func pageKey(metric common.MetricResponseDto) string {
+	return metric.ID + "\x00" + string(metric.MType)
+}
 
-	return trustedNet.Contains(ip)
+func decodePageToken(token string) (string, error) {
+	key, err := base64.RawURLEncoding.DecodeString(token)
+	return string(key), err
 }
@@ -2,6 +2,7 @@ package grpc
 
 import (
 	"context"
+	"errors"
 	"github.com/desepticon55/metrics-collector/internal/common"
 	server2 "github.com/desepticon55/metrics-collector/internal/server"
 	grpc "github.com/desepticon55/metrics-collector/proto/metrics"
@@ -35,24 +36,15 @@ func TestSendMetrics(t *testing.T) {
 	logger, _ := zap.NewDevelopment()
 	defer logger.Sync()
 
-	mockService := new(MockMetricsService)
-	mockService.On("SaveMetrics", mock.Anything, mock.Anything).Return([]common.MetricResponseDto{}, nil)
-
 	tests := []struct {
 		name        string
-		config      server2.Config
+		serviceErr  error
 		req         *grpc.MetricsRequest
 		expectedErr error
 	}{
 		{
 			name: "valid request",
-			config: server2.Config{
-				HashKey:       "somehashkey",
-				TrustedSubnet: "192.168.1.0/24",
-			},
 			req: &grpc.MetricsRequest{
-				Ip:   "192.168.1.2",
-				Hash: "d55a6e05957298d3c93e6bf8b48e903e55c3c7a9a4b4d20e2d39259b0de4ce3e",
 				Metrics: []*grpc.Metric{
 					{Id: "metric1", Type: "gauge", Delta: 10, Value: 5.5},
 				},
@@ -60,54 +52,32 @@ func TestSendMetrics(t *testing.T) {
 			expectedErr: nil,
 		},
 		{
-			name: "invalid hash",
-			config: server2.Config{
-				HashKey:       "somehashkey",
-				TrustedSubnet: "192.168.1.0/24",
-			},
-			req: &grpc.MetricsRequest{
-				Ip:   "192.168.1.2",
-				Hash: "invalidhash",
-				Metrics: []*grpc.Metric{
-					{Id: "metric1", Type: "gauge", Delta: 10, Value: 5.5},
-				},
-			},
-			expectedErr: status.Error(codes.InvalidArgument, "Invalid HashSHA256"),
-		},
-		{
-			name: "missing IP",
-			config: server2.Config{
-				TrustedSubnet: "192.168.1.0/24",
-			},
+			name:       "invalid metric",
+			serviceErr: server2.NewValidationError(errors.New("unsupported metric type")),
 			req: &grpc.MetricsRequest{
-				Ip:   "",
-				Hash: "d55a6e05957298d3c93e6bf8b48e903e55c3c7a9a4b4d20e2d39259b0de4ce3e",
 				Metrics: []*grpc.Metric{
-					{Id: "metric1", Type: "gauge", Delta: 10, Value: 5.5},
+					{Id: "metric1", Type: "histogram"},
 				},
 			},
-			expectedErr: status.Error(codes.InvalidArgument, "X-Real-IP header missing"),
+			expectedErr: status.Error(codes.InvalidArgument, "request validation failed: unsupported metric type"),
 		},
 		{
-			name: "IP not in trusted subnet",
-			config: server2.Config{
-				TrustedSubnet: "192.168.1.0/24",
-			},
+			name:       "storage error",
+			serviceErr: errors.New("connection refused"),
 			req: &grpc.MetricsRequest{
-				Ip:   "10.0.0.1",
-				Hash: "d55a6e05957298d3c93e6bf8b48e903e55c3c7a9a4b4d20e2d39259b0de4ce3e",
 				Metrics: []*grpc.Metric{
-					{Id: "metric1", Type: "gauge", Delta: 10, Value: 5.5},
+					{Id: "metric1", Type: "gauge", Value: 5.5},
 				},
 			},
-			expectedErr: status.Error(codes.InvalidArgument, "Forbidden: IP not in trusted subnet"),
+			expectedErr: status.Error(codes.Internal, "Internal server error"),
 		},
 	}
 
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
+			mockService := new(MockMetricsService)
+			mockService.On("SaveMetrics", mock.Anything, mock.Anything).Return([]common.MetricResponseDto{}, tt.serviceErr)
 			server := &MetricsServer{
-				Config:  tt.config,
 				Logger:  logger,
 				Service: mockService,
 			}
@@ -126,3 +96,102 @@ func TestSendMetrics(t *testing.T) {
 		})
 	}
 }
+
+type stubMetricsService struct {
+	metrics []common.MetricResponseDto
+}
+
+func (s *stubMetricsService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	var saved []common.MetricResponseDto
+	for _, metric := range request {
+		saved = append(saved, common.MetricResponseDto{ID: metric.ID, MType: metric.MType, Delta: metric.Delta, Value: metric.Value})
+	}
+	return saved, nil
+}
+
+func (s *stubMetricsService) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error) {
+	for _, metric := range s.metrics {
+		if metric.ID == metricName && metric.MType == metricType {
+			return metric, nil
+		}
+	}
+	return common.MetricResponseDto{}, server2.NewMetricNotFoundError(metricName, metricType)
+}
+
+func (s *stubMetricsService) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	return s.metrics
+}
+
+func newStubServer() *MetricsServer {
+	value, delta := 1.5, int64(3)
+	return &MetricsServer{
+		Service: &stubMetricsService{metrics: []common.MetricResponseDto{
+			{ID: "HeapIdle", MType: common.Gauge, Value: &value},
+			{ID: "Alloc", MType: common.Gauge, Value: &value},
+			{ID: "PollCount", MType: common.Counter, Delta: &delta},
+			{ID: "HeapInuse", MType: common.Gauge, Value: &value},
+		}},
+		Logger: zap.NewNop(),
+	}
+}
+
+func TestGetMetric(t *testing.T) {
+	s := newStubServer()
+
+	metric, err := s.GetMetric(context.Background(), &grpc.GetMetricRequest{Id: "PollCount", Type: "counter"})
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), metric.Delta)
+
+	_, err = s.GetMetric(context.Background(), &grpc.GetMetricRequest{Id: "Unknown", Type: "gauge"})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+
+	_, err = s.GetMetric(context.Background(), &grpc.GetMetricRequest{Id: "Alloc", Type: "histogram"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestListMetrics(t *testing.T) {
+	s := newStubServer()
+
+	var ids []string
+	request := &grpc.ListMetricsRequest{Type: "gauge", PageSize: 2}
+	for page := 0; ; page++ {
+		response, err := s.ListMetrics(context.Background(), request)
+		assert.NoError(t, err)
+		for _, metric := range response.Metrics {
+			ids = append(ids, metric.Id)
+		}
+		if response.NextPageToken == "" {
+			assert.Equal(t, 1, page)
+			break
+		}
+		request.PageToken = response.NextPageToken
+	}
+	assert.Equal(t, []string{"Alloc", "HeapIdle", "HeapInuse"}, ids)
+
+	response, err := s.ListMetrics(context.Background(), &grpc.ListMetricsRequest{Name: "Heap*"})
+	assert.NoError(t, err)
+	assert.Len(t, response.Metrics, 2)
+	assert.Empty(t, response.NextPageToken)
+
+	_, err = s.ListMetrics(context.Background(), &grpc.ListMetricsRequest{PageToken: "%%%"})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
+
+func TestUpdateMetric(t *testing.T) {
+	s := newStubServer()
+
+	metric, err := s.UpdateMetric(context.Background(), &grpc.UpdateMetricRequest{Metric: &grpc.Metric{Id: "Alloc", Type: "gauge", Value: 2.5}})
+	assert.NoError(t, err)
+	assert.Equal(t, 2.5, metric.Value)
+
+	s.Service = &MockMetricsService{}
+	s.Service.(*MockMetricsService).On("SaveMetrics", mock.Anything, mock.Anything).Return([]common.MetricResponseDto(nil), server2.NewValidationError(errors.New("value is required for gauge type")))
+	_, err = s.UpdateMetric(context.Background(), &grpc.UpdateMetricRequest{Metric: &grpc.Metric{Id: "Alloc", Type: "gauge"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.UpdateMetric(context.Background(), &grpc.UpdateMetricRequest{})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = s.Ping(context.Background(), &grpc.PingRequest{})
+	assert.Equal(t, codes.Unavailable, status.Code(err))
+}
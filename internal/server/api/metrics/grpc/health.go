@@ -0,0 +1,40 @@
+package grpc
+
+import (
+	"context"
+	metrics2 "github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	grpc "github.com/desepticon55/metrics-collector/proto/metrics"
+	metricsv2 "github.com/desepticon55/metrics-collector/proto/metrics/v2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"time"
+)
+
+// ReportHealth checks storage with interval and reports status of server and both versions of metrics service until context is done.
+// Storage without pool (memory storage) is always healthy
+func ReportHealth(ctx context.Context, healthServer *health.Server, pool metrics2.Pinger, interval time.Duration, logger *zap.Logger) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		status := healthpb.HealthCheckResponse_SERVING
+		if pool != nil {
+			pingCtx, cancel := context.WithTimeout(ctx, interval)
+			if err := pool.Ping(pingCtx); err != nil {
+				logger.Error("Storage is not healthy", zap.Error(err))
+				status = healthpb.HealthCheckResponse_NOT_SERVING
+			}
+			cancel()
+		}
+		healthServer.SetServingStatus("", status)
+		healthServer.SetServingStatus(grpc.MetricsService_ServiceDesc.ServiceName, status)
+		healthServer.SetServingStatus(metricsv2.MetricsService_ServiceDesc.ServiceName, status)
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
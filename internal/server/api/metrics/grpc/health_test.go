@@ -0,0 +1,52 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+type stubPinger struct {
+	down atomic.Bool
+}
+
+func (p *stubPinger) Ping(ctx context.Context) error {
+	if p.down.Load() {
+		return errors.New("connection refused")
+	}
+	return nil
+}
+
+func TestReportHealth(t *testing.T) {
+	healthServer := health.NewServer()
+	pool := &stubPinger{}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go ReportHealth(ctx, healthServer, pool, 10*time.Millisecond, zap.NewNop())
+
+	status := func() healthpb.HealthCheckResponse_ServingStatus {
+		response, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "metrics.MetricsService"})
+		if err != nil {
+			return healthpb.HealthCheckResponse_UNKNOWN
+		}
+		// version 2 of service has the same status
+		responseV2, err := healthServer.Check(context.Background(), &healthpb.HealthCheckRequest{Service: "metrics.v2.MetricsService"})
+		if err != nil || responseV2.Status != response.Status {
+			return healthpb.HealthCheckResponse_UNKNOWN
+		}
+		return response.Status
+	}
+	assert.Eventually(t, func() bool { return status() == healthpb.HealthCheckResponse_SERVING }, time.Second, 5*time.Millisecond)
+
+	pool.down.Store(true)
+	assert.Eventually(t, func() bool { return status() == healthpb.HealthCheckResponse_NOT_SERVING }, time.Second, 5*time.Millisecond)
+
+	pool.down.Store(false)
+	assert.Eventually(t, func() bool { return status() == healthpb.HealthCheckResponse_SERVING }, time.Second, 5*time.Millisecond)
+}
@@ -0,0 +1,77 @@
+package grpc
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/common"
+	metrics2 "github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/stream"
+	grpc "github.com/desepticon55/metrics-collector/proto/metrics"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// Max number of metric changes sent to follower in one batch
+const maxReplicationBatchSize = 500
+
+// ReplicationServer streams every write applied by primary server to followers. Epoch identifies
+// process of primary, sequence numbers of changes are meaningful only within the same epoch
+type ReplicationServer struct {
+	grpc.UnimplementedReplicationServiceServer
+	Stream  metrics2.ReplicationStream
+	Service metrics2.MetricsService
+	Epoch   string
+	Logger  *zap.Logger
+}
+
+// Replicate resumes follower from its last applied change. When changes are not retained anymore
+// or follower was replicating another epoch, full snapshot is sent first
+func (s *ReplicationServer) Replicate(req *grpc.ReplicationRequest, out grpc.ReplicationService_ReplicateServer) error {
+	ctx := out.Context()
+	resumeFrom := req.LastSeq
+	if req.Epoch != s.Epoch {
+		resumeFrom = 0
+	}
+
+	subscription, complete := s.Stream.Resume(stream.Filter{}, resumeFrom)
+	defer s.Stream.Unsubscribe(subscription)
+
+	if req.Epoch != s.Epoch || !complete {
+		// changes published after subscription are streamed anyway, so snapshot may only be newer than its seq
+		resumeFrom = s.Stream.LastSeq()
+		snapshot := &grpc.ReplicationBatch{Epoch: s.Epoch, Seq: resumeFrom, Snapshot: true}
+		for _, metric := range s.Service.FindAllMetrics(ctx) {
+			snapshot.Metrics = append(snapshot.Metrics, common.MetricResponseToProto(metric))
+		}
+		if err := out.Send(snapshot); err != nil {
+			return err
+		}
+		s.Logger.Info("Replication snapshot was sent", zap.Uint64("seq", resumeFrom), zap.Int("metrics", len(snapshot.Metrics)))
+	}
+
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return status.Error(codes.Unavailable, "Follower is too slow")
+			}
+			if event.Seq <= resumeFrom {
+				continue
+			}
+
+			batch := &grpc.ReplicationBatch{Epoch: s.Epoch, Seq: event.Seq, Metrics: []*grpc.Metric{common.MetricResponseToProto(event.Metric)}}
+			for len(batch.Metrics) < maxReplicationBatchSize && len(subscription.Events()) > 0 {
+				event, ok = <-subscription.Events()
+				if !ok {
+					break
+				}
+				batch.Seq = event.Seq
+				batch.Metrics = append(batch.Metrics, common.MetricResponseToProto(event.Metric))
+			}
+			if err := out.Send(batch); err != nil {
+				return err
+			}
+		}
+	}
+}
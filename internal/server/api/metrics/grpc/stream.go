@@ -0,0 +1,202 @@
+package grpc
+
+import (
+	"context"
+	"errors"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	grpc "github.com/desepticon55/metrics-collector/proto/metrics"
+	"go.uber.org/zap"
+	"io"
+	"sync"
+	"time"
+)
+
+// Stream is acknowledged after this number of applied batches or after interval since previous ack
+const (
+	ackBatches  = 10
+	ackInterval = 1 * time.Second
+)
+
+// Position of agent is forgotten when agent sends nothing during this time
+const agentRetention = 24 * time.Hour
+
+// agentStream is the last batch applied for agent. It's shared by streams of agent, so batch resent
+// over new stream is skipped even when previous stream is not closed yet
+type agentStream struct {
+	mu         sync.Mutex
+	lastSeq    uint64
+	partialSeq uint64
+	unsaved    []server.FailedMetric
+	lastSeen   time.Time
+}
+
+// StreamMetrics applies batches pushed by agent in order. Batches which were applied before are
+// skipped, so agent may resend everything not acknowledged after reconnect. Positions are kept by
+// Positions storage when it's set, so batches applied before restart of server are skipped too.
+// Partially saved batch is not acknowledged: stream is aborted with failed metrics and only they are
+// saved when agent resends the batch
+func (s *MetricsServer) StreamMetrics(stream grpc.MetricsService_StreamMetricsServer) error {
+	ctx := stream.Context()
+	// agent authenticated by certificate can't take over positions of other agents
+	identity := common.PeerIdentity(ctx)
+	var acked, applied uint64
+	lastAck := time.Now()
+
+	for {
+		batch, err := stream.Recv()
+		if errors.Is(err, io.EOF) {
+			if applied > acked {
+				return stream.Send(&grpc.MetricsAck{LastSeq: applied})
+			}
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		agentID := batch.AgentId
+		if identity != "" && agentID != "" {
+			agentID = identity + "/" + agentID
+		}
+		agent := s.agentStream(ctx, agentID)
+		agent.mu.Lock()
+		if batch.Seq > agent.lastSeq || batch.AgentId == "" {
+			metrics := make([]common.MetricRequestDto, 0, len(batch.Metrics))
+			for _, metric := range batch.Metrics {
+				metrics = append(metrics, common.MetricRequestFromProto(metric))
+			}
+			if batch.AgentId != "" && batch.Seq == agent.partialSeq {
+				// saved part of resent batch would be counted twice
+				metrics = onlyUnsaved(metrics, agent.unsaved)
+			}
+
+			_, err := s.Service.SaveMetrics(ctx, metrics)
+			var partialError *server.PartialSaveError
+			if errors.As(err, &partialError) && batch.AgentId != "" {
+				agent.partialSeq, agent.unsaved = batch.Seq, partialError.Failed
+				agent.lastSeen = time.Now()
+				s.savePosition(ctx, agentID, agent)
+			}
+			if err != nil {
+				agent.mu.Unlock()
+				if applied > acked {
+					if err := stream.Send(&grpc.MetricsAck{LastSeq: applied}); err != nil {
+						s.Logger.Error("Error during acknowledge applied batches", zap.String("agent", agentID), zap.Error(err))
+					}
+				}
+				return toStatus(err, s.Logger)
+			}
+			agent.lastSeq, agent.partialSeq, agent.unsaved = batch.Seq, 0, nil
+		}
+		agent.lastSeen = time.Now()
+		s.savePosition(ctx, agentID, agent)
+		agent.mu.Unlock()
+
+		applied = max(applied, batch.Seq)
+		if applied-acked >= ackBatches || time.Since(lastAck) >= ackInterval {
+			if err := stream.Send(&grpc.MetricsAck{LastSeq: applied}); err != nil {
+				return err
+			}
+			acked, lastAck = applied, time.Now()
+		}
+	}
+}
+
+// onlyUnsaved keeps metrics which were not saved with previous attempt of batch
+func onlyUnsaved(metrics []common.MetricRequestDto, unsaved []server.FailedMetric) []common.MetricRequestDto {
+	type key struct {
+		id    string
+		mType common.MetricType
+	}
+	keys := make(map[key]bool, len(unsaved))
+	for _, metric := range unsaved {
+		keys[key{id: metric.ID, mType: metric.MType}] = true
+	}
+
+	result := make([]common.MetricRequestDto, 0, len(unsaved))
+	for _, metric := range metrics {
+		if keys[key{id: metric.ID, mType: metric.MType}] {
+			result = append(result, metric)
+		}
+	}
+	return result
+}
+
+// savePosition persists position of agent. Failure is only logged: batch is already applied,
+// so position is still kept in memory and is persisted with the next batch
+func (s *MetricsServer) savePosition(ctx context.Context, agentID string, agent *agentStream) {
+	if s.Positions == nil || agentID == "" {
+		return
+	}
+
+	position := server.StreamPosition{
+		AgentID:    agentID,
+		Seq:        agent.lastSeq,
+		PartialSeq: agent.partialSeq,
+		Unsaved:    agent.unsaved,
+		LastSeen:   agent.lastSeen,
+	}
+	if err := s.Positions.SaveStreamPosition(ctx, position); err != nil {
+		s.Logger.Error("Error during save stream position", zap.String("agent", agentID), zap.Error(err))
+	}
+}
+
+func (s *MetricsServer) agentStream(ctx context.Context, agentID string) *agentStream {
+	if agentID == "" {
+		return &agentStream{}
+	}
+
+	s.agentsMu.Lock()
+	defer s.agentsMu.Unlock()
+
+	if s.agents == nil {
+		s.agents = s.loadAgents(ctx)
+	}
+	agent, ok := s.agents[agentID]
+	if !ok {
+		s.forgetIdleAgents(ctx)
+		agent = &agentStream{}
+		s.agents[agentID] = agent
+	}
+	return agent
+}
+
+func (s *MetricsServer) loadAgents(ctx context.Context) map[string]*agentStream {
+	agents := make(map[string]*agentStream)
+	if s.Positions == nil {
+		return agents
+	}
+
+	positions, err := s.Positions.FindAllStreamPositions(ctx)
+	if err != nil {
+		s.Logger.Error("Error during load stream positions", zap.Error(err))
+		return agents
+	}
+	for _, position := range positions {
+		agents[position.AgentID] = &agentStream{
+			lastSeq:    position.Seq,
+			partialSeq: position.PartialSeq,
+			unsaved:    position.Unsaved,
+			lastSeen:   position.LastSeen,
+		}
+	}
+	return agents
+}
+
+func (s *MetricsServer) forgetIdleAgents(ctx context.Context) {
+	for agentID, agent := range s.agents {
+		agent.mu.Lock()
+		idle := !agent.lastSeen.IsZero() && time.Since(agent.lastSeen) > agentRetention
+		agent.mu.Unlock()
+		if idle {
+			delete(s.agents, agentID)
+			if s.Positions != nil {
+				if err := s.Positions.DeleteStreamPosition(ctx, agentID); err != nil {
+					s.Logger.Error("Error during delete stream position", zap.String("agent", agentID), zap.Error(err))
+				}
+			}
+			s.Logger.Debug("Stream position of idle agent was forgotten", zap.String("agent", agentID))
+		}
+	}
+}
@@ -0,0 +1,95 @@
+package grpc
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	metrics2 "github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/stream"
+	metricsv2 "github.com/desepticon55/metrics-collector/proto/metrics/v2"
+	"go.uber.org/zap"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// MetricsServerV2 serves version 2 of MetricsService over the same service as version 1
+type MetricsServerV2 struct {
+	metricsv2.UnimplementedMetricsServiceServer
+	Service metrics2.MetricsService
+	Logger  *zap.Logger
+}
+
+// SendMetrics saves all metrics and returns their new values. Nothing is saved when any metric is invalid
+func (s *MetricsServerV2) SendMetrics(ctx context.Context, req *metricsv2.SendMetricsRequest) (*metricsv2.SendMetricsResponse, error) {
+	metrics := make([]common.MetricRequestDto, 0, len(req.Metrics))
+	for _, metric := range req.Metrics {
+		dto, err := common.MetricRequestFromProtoV2(metric)
+		if err != nil {
+			return nil, status.Error(codes.InvalidArgument, err.Error())
+		}
+		metrics = append(metrics, dto)
+	}
+
+	saved, err := s.Service.SaveMetrics(ctx, metrics)
+	if err != nil {
+		return nil, toStatus(err, s.Logger)
+	}
+
+	response := &metricsv2.SendMetricsResponse{}
+	for _, metric := range saved {
+		response.Metrics = append(response.Metrics, common.MetricResponseToProtoV2(metric))
+	}
+	return response, nil
+}
+
+func (s *MetricsServerV2) GetMetric(ctx context.Context, req *metricsv2.GetMetricRequest) (*metricsv2.Metric, error) {
+	metricType, err := common.MetricTypeFromKind(req.Kind)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if metricType == "" {
+		return nil, status.Error(codes.InvalidArgument, "Kind of metric is required")
+	}
+	if err := common.ValidateLabels(req.Id, req.Labels); err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	metric, err := s.Service.FindOneMetric(ctx, common.JoinLabels(req.Id, req.Labels), metricType)
+	if err != nil {
+		return nil, toStatus(err, s.Logger)
+	}
+	return common.MetricResponseToProtoV2(metric), nil
+}
+
+// ListMetrics pages metrics like version 1, name pattern is matched against name with labels
+func (s *MetricsServerV2) ListMetrics(ctx context.Context, req *metricsv2.ListMetricsRequest) (*metricsv2.ListMetricsResponse, error) {
+	metricType, err := common.MetricTypeFromKind(req.Kind)
+	if err != nil {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+
+	matched, nextPageToken, err := listPage(ctx, s.Service, stream.Filter{Name: req.Name, Type: metricType}, req.PageSize, req.PageToken)
+	if err != nil {
+		return nil, err
+	}
+
+	response := &metricsv2.ListMetricsResponse{NextPageToken: nextPageToken}
+	for _, metric := range matched {
+		response.Metrics = append(response.Metrics, common.MetricResponseToProtoV2(metric))
+	}
+	return response, nil
+}
+
+func (s *MetricsServerV2) UpdateMetric(ctx context.Context, req *metricsv2.UpdateMetricRequest) (*metricsv2.Metric, error) {
+	if req.Metric == nil {
+		return nil, status.Error(codes.InvalidArgument, "Metric is required")
+	}
+
+	response, err := s.SendMetrics(ctx, &metricsv2.SendMetricsRequest{Metrics: []*metricsv2.Metric{req.Metric}})
+	if err != nil {
+		return nil, err
+	}
+	if len(response.Metrics) == 0 {
+		return nil, status.Error(codes.Internal, "Internal server error")
+	}
+	return response.Metrics[0], nil
+}
@@ -0,0 +1,131 @@
+package grpc
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	server2 "github.com/desepticon55/metrics-collector/internal/server"
+	metrics "github.com/desepticon55/metrics-collector/proto/metrics"
+	metricsv2 "github.com/desepticon55/metrics-collector/proto/metrics/v2"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+// mapMetricsService keeps the last saved metrics by ID and type
+type mapMetricsService map[string]common.MetricResponseDto
+
+func (s mapMetricsService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	var response []common.MetricResponseDto
+	for _, metric := range request {
+		saved := common.MetricResponseDto{ID: metric.ID, MType: metric.MType, Delta: metric.Delta, Value: metric.Value}
+		s[metric.ID+string(metric.MType)] = saved
+		response = append(response, saved)
+	}
+	return response, nil
+}
+
+func (s mapMetricsService) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error) {
+	metric, ok := s[metricName+string(metricType)]
+	if !ok {
+		return metric, server2.NewMetricNotFoundError(metricName, metricType)
+	}
+	return metric, nil
+}
+
+func (s mapMetricsService) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	var result []common.MetricResponseDto
+	for _, metric := range s {
+		result = append(result, metric)
+	}
+	return result
+}
+
+// newVersionedConn serves both versions of MetricsService on one server
+func newVersionedConn(t *testing.T, service mapMetricsService) *grpc.ClientConn {
+	listener := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	metrics.RegisterMetricsServiceServer(s, &MetricsServer{Service: service, Logger: zap.NewNop()})
+	metricsv2.RegisterMetricsServiceServer(s, &MetricsServerV2{Service: service, Logger: zap.NewNop()})
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestMetricsServerV2_SendMetrics(t *testing.T) {
+	service := mapMetricsService{}
+	conn := newVersionedConn(t, service)
+	client := metricsv2.NewMetricsServiceClient(conn)
+	ctx := context.Background()
+
+	response, err := client.SendMetrics(ctx, &metricsv2.SendMetricsRequest{Metrics: []*metricsv2.Metric{
+		{Id: "Alloc", Value: &metricsv2.Metric_Gauge{Gauge: 0}, Labels: map[string]string{"host": "a"}},
+		{Id: "PollCount", Value: &metricsv2.Metric_Counter{Counter: 0}},
+	}})
+	assert.NoError(t, err)
+	if assert.Len(t, response.Metrics, 2) {
+		assert.Equal(t, "Alloc", response.Metrics[0].Id)
+		assert.Equal(t, map[string]string{"host": "a"}, response.Metrics[0].Labels)
+		assert.IsType(t, &metricsv2.Metric_Gauge{}, response.Metrics[0].Value)
+		assert.IsType(t, &metricsv2.Metric_Counter{}, response.Metrics[1].Value)
+	}
+
+	// zero is saved as value of its type only
+	saved := service["Alloc;host=a"+string(common.Gauge)]
+	if assert.NotNil(t, saved.Value) {
+		assert.Equal(t, 0.0, *saved.Value)
+	}
+	assert.Nil(t, saved.Delta)
+
+	metric, err := client.GetMetric(ctx, &metricsv2.GetMetricRequest{Id: "Alloc", Kind: metricsv2.MetricKind_METRIC_KIND_GAUGE, Labels: map[string]string{"host": "a"}})
+	assert.NoError(t, err)
+	assert.Equal(t, 0.0, metric.GetGauge())
+
+	for _, invalid := range []*metricsv2.Metric{
+		{Id: "Empty"},
+		{Id: "Latency", Value: &metricsv2.Metric_Histogram{Histogram: &metricsv2.Histogram{Bounds: []float64{1}, Counts: []uint64{1, 1}}}},
+		// separators would collide with ID of {"a":"x","b":"y"}
+		{Id: "Alloc", Value: &metricsv2.Metric_Gauge{Gauge: 1}, Labels: map[string]string{"a": "x;b=y"}},
+		{Id: "Alloc", Value: &metricsv2.Metric_Gauge{Gauge: 1}, Labels: map[string]string{"a=b": "x"}},
+		{Id: "Alloc;a=x", Value: &metricsv2.Metric_Gauge{Gauge: 1}},
+		{Id: "Alloc", Value: &metricsv2.Metric_Gauge{Gauge: 1}, Labels: map[string]string{"": "x"}},
+	} {
+		_, err = client.SendMetrics(ctx, &metricsv2.SendMetricsRequest{Metrics: []*metricsv2.Metric{invalid}})
+		assert.Equal(t, codes.InvalidArgument, status.Code(err), invalid.Id)
+	}
+
+	_, err = client.GetMetric(ctx, &metricsv2.GetMetricRequest{Id: "Alloc", Kind: metricsv2.MetricKind_METRIC_KIND_GAUGE, Labels: map[string]string{"host": "a;b=c"}})
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+
+	_, err = client.GetMetric(ctx, &metricsv2.GetMetricRequest{Id: "Alloc", Kind: metricsv2.MetricKind_METRIC_KIND_COUNTER})
+	assert.Equal(t, codes.NotFound, status.Code(err))
+}
+
+func TestMetricsServerV2_ServedWithV1(t *testing.T) {
+	service := mapMetricsService{}
+	conn := newVersionedConn(t, service)
+	ctx := context.Background()
+
+	_, err := metrics.NewMetricsServiceClient(conn).SendMetrics(ctx, &metrics.MetricsRequest{Metrics: []*metrics.Metric{{Id: "PollCount", Type: "counter", Delta: 3}}})
+	assert.NoError(t, err)
+	assert.Nil(t, service["PollCount"+string(common.Counter)].Value)
+
+	response, err := metricsv2.NewMetricsServiceClient(conn).ListMetrics(ctx, &metricsv2.ListMetricsRequest{Kind: metricsv2.MetricKind_METRIC_KIND_COUNTER})
+	assert.NoError(t, err)
+	if assert.Len(t, response.Metrics, 1) {
+		assert.Equal(t, int64(3), response.Metrics[0].GetCounter())
+	}
+}
@@ -0,0 +1,75 @@
+package grpc
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server/service/stream"
+	grpc "github.com/desepticon55/metrics-collector/proto/metrics"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"path"
+)
+
+// Watch pushes changes of metrics matching filter like GET /stream. Watcher which can't keep up
+// is disconnected with ResourceExhausted, so it should resume from the last received seq,
+// coalescing watcher gets only the latest pending change of every metric instead
+func (s *MetricsServer) Watch(req *grpc.WatchRequest, srv grpc.MetricsService_WatchServer) error {
+	if s.Stream == nil {
+		return status.Error(codes.Unimplemented, "Watch is not enabled")
+	}
+
+	filter := stream.Filter{Name: req.Name, Type: common.MetricType(req.Type)}
+	if filter.Type != "" && filter.Type != common.Gauge && filter.Type != common.Counter {
+		return status.Errorf(codes.InvalidArgument, "Unsupported metric type = '%s'", req.Type)
+	}
+	if _, err := path.Match(filter.Name, ""); err != nil {
+		return status.Errorf(codes.InvalidArgument, "Incorrect name pattern = '%s'", req.Name)
+	}
+
+	subscription := s.Stream.Subscribe(filter, req.ResumeFrom)
+	defer s.Stream.Unsubscribe(subscription)
+
+	if req.Coalesce {
+		return s.watchCoalesced(srv, stream.NewCoalescer(subscription))
+	}
+
+	for {
+		select {
+		case <-srv.Context().Done():
+			return nil
+		case event, ok := <-subscription.Events():
+			if !ok {
+				return status.Error(codes.ResourceExhausted, "Watcher is too slow, resume from the last received seq")
+			}
+			if err := srv.Send(toMetricUpdate(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func (s *MetricsServer) watchCoalesced(srv grpc.MetricsService_WatchServer, coalescer *stream.Coalescer) error {
+	for {
+		select {
+		case <-srv.Context().Done():
+			return nil
+		case <-coalescer.Ready():
+		case <-coalescer.Done():
+			for _, event := range coalescer.Take() {
+				if err := srv.Send(toMetricUpdate(event)); err != nil {
+					return err
+				}
+			}
+			return status.Error(codes.ResourceExhausted, "Watcher is too slow, resume from the last received seq")
+		}
+
+		for _, event := range coalescer.Take() {
+			if err := srv.Send(toMetricUpdate(event)); err != nil {
+				return err
+			}
+		}
+	}
+}
+
+func toMetricUpdate(event stream.Event) *grpc.MetricUpdate {
+	return &grpc.MetricUpdate{Seq: event.Seq, Metric: common.MetricResponseToProto(event.Metric)}
+}
@@ -0,0 +1,68 @@
+package grpc
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	server2 "github.com/desepticon55/metrics-collector/internal/server"
+	metricsMappers "github.com/desepticon55/metrics-collector/internal/server/mapper/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/stream"
+	metrics "github.com/desepticon55/metrics-collector/proto/metrics"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"testing"
+)
+
+func newWatchClient(t *testing.T, broker *stream.Broker) metrics.MetricsServiceClient {
+	listener := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	metrics.RegisterMetricsServiceServer(s, &MetricsServer{Stream: broker, Logger: zap.NewNop()})
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return metrics.NewMetricsServiceClient(conn)
+}
+
+func gauge(name string, value float64) server2.Metric {
+	return &server2.Gauge{BaseMetric: server2.BaseMetric{Name: name, Type: common.Gauge}, Value: value}
+}
+
+func TestWatch(t *testing.T) {
+	broker := stream.New(metricsMappers.NewMapper(validator.New()), 10)
+	broker.Publish([]server2.Metric{gauge("Alloc", 1), gauge("FreeMemory", 2), gauge("Alloc", 3)})
+	client := newWatchClient(t, broker)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	watch, err := client.Watch(ctx, &metrics.WatchRequest{Name: "Alloc", ResumeFrom: 1})
+	assert.NoError(t, err)
+	// retained change is replayed, so subscription is registered by server
+	update, err := watch.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(3), update.Seq)
+
+	broker.Publish([]server2.Metric{gauge("FreeMemory", 3), gauge("Alloc", 4)})
+	update, err = watch.Recv()
+	assert.NoError(t, err)
+	assert.Equal(t, uint64(5), update.Seq)
+	assert.Equal(t, "Alloc", update.Metric.Id)
+	assert.Equal(t, 4.0, update.Metric.Value)
+
+	watch, err = client.Watch(ctx, &metrics.WatchRequest{Type: "histogram"})
+	assert.NoError(t, err)
+	_, err = watch.Recv()
+	assert.Equal(t, codes.InvalidArgument, status.Code(err))
+}
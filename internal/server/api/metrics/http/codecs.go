@@ -0,0 +1,303 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/proto/metrics"
+	"google.golang.org/protobuf/proto"
+	"io"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+const (
+	contentTypeJSON     = "application/json"
+	contentTypeProtobuf = "application/x-protobuf"
+	contentTypeNDJSON   = "application/x-ndjson"
+
+	ndjsonBatchSize = 100
+	// signed body is read into memory to be verified before saving
+	maxSignedBodySize = 32 << 20
+)
+
+// metricsCodec encodes and decodes metrics in specific body format
+type metricsCodec interface {
+	contentType() string
+
+	decodeOne(reader io.Reader) (common.MetricRequestDto, error)
+
+	// decodeList calls handle for every decoded batch of metrics
+	decodeList(reader io.Reader, handle func([]common.MetricRequestDto) error) error
+
+	encodeOne(writer io.Writer, metric common.MetricResponseDto) error
+
+	newListEncoder(writer io.Writer) listEncoder
+}
+
+type listEncoder interface {
+	encode(metrics []common.MetricResponseDto) error
+
+	close() error
+}
+
+// streamingEncoder writes metrics as soon as they are encoded, so response status is sent before the whole list
+// is saved. Error after that is reported by trailing error record
+type streamingEncoder interface {
+	listEncoder
+
+	fail(err error) error
+
+	// skip reports metrics which were not saved while the rest of list is saved
+	skip(failed []server.FailedMetric) error
+}
+
+// errorRecord is the last record of streamed response when saving of list was interrupted.
+// Record of metric which was not saved has its id and type
+type errorRecord struct {
+	ID    string            `json:"id,omitempty"`
+	MType common.MetricType `json:"type,omitempty"`
+	Error string            `json:"error"`
+}
+
+var codecs = map[string]metricsCodec{
+	contentTypeJSON:     jsonCodec{},
+	contentTypeProtobuf: protobufCodec{},
+	contentTypeNDJSON:   ndjsonCodec{},
+}
+
+// requestCodec selects codec by Content-Type header. JSON is used when header is empty
+func requestCodec(request *http.Request) (metricsCodec, bool) {
+	contentType := request.Header.Get("Content-Type")
+	if contentType == "" {
+		return jsonCodec{}, true
+	}
+
+	mediaType, _, err := mime.ParseMediaType(contentType)
+	if err != nil {
+		return nil, false
+	}
+	codec, ok := codecs[mediaType]
+	return codec, ok
+}
+
+// responseCodec selects codec by Accept header. Returns false when client doesn't ask for specific format
+func responseCodec(request *http.Request) (metricsCodec, bool) {
+	for _, accept := range strings.Split(request.Header.Get("Accept"), ",") {
+		mediaType, _, err := mime.ParseMediaType(strings.TrimSpace(accept))
+		if err != nil {
+			continue
+		}
+		if codec, ok := codecs[mediaType]; ok {
+			return codec, true
+		}
+	}
+	return nil, false
+}
+
+type jsonCodec struct{}
+
+func (jsonCodec) contentType() string {
+	return contentTypeJSON
+}
+
+func (jsonCodec) decodeOne(reader io.Reader) (common.MetricRequestDto, error) {
+	var requestDto common.MetricRequestDto
+	err := json.NewDecoder(reader).Decode(&requestDto)
+	return requestDto, err
+}
+
+func (jsonCodec) decodeList(reader io.Reader, handle func([]common.MetricRequestDto) error) error {
+	var requestDtoList []common.MetricRequestDto
+	if err := json.NewDecoder(reader).Decode(&requestDtoList); err != nil {
+		return err
+	}
+	return handle(requestDtoList)
+}
+
+func (jsonCodec) encodeOne(writer io.Writer, metric common.MetricResponseDto) error {
+	bytes, err := json.Marshal(metric)
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(bytes)
+	return err
+}
+
+func (jsonCodec) newListEncoder(writer io.Writer) listEncoder {
+	return &jsonListEncoder{writer: writer}
+}
+
+type jsonListEncoder struct {
+	writer  io.Writer
+	metrics []common.MetricResponseDto
+}
+
+func (e *jsonListEncoder) encode(metrics []common.MetricResponseDto) error {
+	e.metrics = append(e.metrics, metrics...)
+	return nil
+}
+
+func (e *jsonListEncoder) close() error {
+	bytes, err := json.Marshal(e.metrics)
+	if err != nil {
+		return err
+	}
+	_, err = e.writer.Write(bytes)
+	return err
+}
+
+type protobufCodec struct{}
+
+func (protobufCodec) contentType() string {
+	return contentTypeProtobuf
+}
+
+func (protobufCodec) decodeOne(reader io.Reader) (common.MetricRequestDto, error) {
+	bytes, err := io.ReadAll(reader)
+	if err != nil {
+		return common.MetricRequestDto{}, err
+	}
+
+	var protoMetric metrics.Metric
+	if err := proto.Unmarshal(bytes, &protoMetric); err != nil {
+		return common.MetricRequestDto{}, err
+	}
+	return common.MetricRequestFromProto(&protoMetric), nil
+}
+
+func (protobufCodec) decodeList(reader io.Reader, handle func([]common.MetricRequestDto) error) error {
+	bytes, err := io.ReadAll(reader)
+	if err != nil {
+		return err
+	}
+
+	var protoRequest metrics.MetricsRequest
+	if err := proto.Unmarshal(bytes, &protoRequest); err != nil {
+		return err
+	}
+
+	requestDtoList := make([]common.MetricRequestDto, 0, len(protoRequest.Metrics))
+	for _, protoMetric := range protoRequest.Metrics {
+		requestDtoList = append(requestDtoList, common.MetricRequestFromProto(protoMetric))
+	}
+	return handle(requestDtoList)
+}
+
+func (protobufCodec) encodeOne(writer io.Writer, metric common.MetricResponseDto) error {
+	bytes, err := proto.Marshal(common.MetricResponseToProto(metric))
+	if err != nil {
+		return err
+	}
+	_, err = writer.Write(bytes)
+	return err
+}
+
+func (protobufCodec) newListEncoder(writer io.Writer) listEncoder {
+	return &protobufListEncoder{writer: writer}
+}
+
+type protobufListEncoder struct {
+	writer  io.Writer
+	metrics []*metrics.Metric
+}
+
+func (e *protobufListEncoder) encode(metricList []common.MetricResponseDto) error {
+	for _, metric := range metricList {
+		e.metrics = append(e.metrics, common.MetricResponseToProto(metric))
+	}
+	return nil
+}
+
+func (e *protobufListEncoder) close() error {
+	bytes, err := proto.Marshal(&metrics.MetricsRequest{Metrics: e.metrics})
+	if err != nil {
+		return err
+	}
+	_, err = e.writer.Write(bytes)
+	return err
+}
+
+// ndjsonCodec reads and writes newline delimited JSON objects one by one,
+// so list of metrics is never held in memory entirely
+type ndjsonCodec struct{}
+
+func (ndjsonCodec) contentType() string {
+	return contentTypeNDJSON
+}
+
+func (ndjsonCodec) decodeOne(reader io.Reader) (common.MetricRequestDto, error) {
+	return jsonCodec{}.decodeOne(reader)
+}
+
+func (ndjsonCodec) decodeList(reader io.Reader, handle func([]common.MetricRequestDto) error) error {
+	decoder := json.NewDecoder(reader)
+	batch := make([]common.MetricRequestDto, 0, ndjsonBatchSize)
+	for {
+		var requestDto common.MetricRequestDto
+		err := decoder.Decode(&requestDto)
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		if err != nil {
+			return err
+		}
+
+		batch = append(batch, requestDto)
+		if len(batch) == ndjsonBatchSize {
+			if err := handle(batch); err != nil {
+				return err
+			}
+			batch = make([]common.MetricRequestDto, 0, ndjsonBatchSize)
+		}
+	}
+
+	if len(batch) > 0 {
+		return handle(batch)
+	}
+	return nil
+}
+
+func (ndjsonCodec) encodeOne(writer io.Writer, metric common.MetricResponseDto) error {
+	return json.NewEncoder(writer).Encode(metric)
+}
+
+func (ndjsonCodec) newListEncoder(writer io.Writer) listEncoder {
+	return &ndjsonListEncoder{writer: writer, encoder: json.NewEncoder(writer)}
+}
+
+type ndjsonListEncoder struct {
+	writer  io.Writer
+	encoder *json.Encoder
+}
+
+func (e *ndjsonListEncoder) encode(metrics []common.MetricResponseDto) error {
+	for _, metric := range metrics {
+		if err := e.encoder.Encode(metric); err != nil {
+			return err
+		}
+	}
+	if flusher, ok := e.writer.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+func (e *ndjsonListEncoder) close() error {
+	return nil
+}
+
+func (e *ndjsonListEncoder) fail(err error) error {
+	return e.encoder.Encode(errorRecord{Error: err.Error()})
+}
+
+func (e *ndjsonListEncoder) skip(failed []server.FailedMetric) error {
+	for _, metric := range failed {
+		if err := e.encoder.Encode(errorRecord{ID: metric.ID, MType: metric.MType, Error: metric.Error}); err != nil {
+			return err
+		}
+	}
+	return nil
+}
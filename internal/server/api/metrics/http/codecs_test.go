@@ -0,0 +1,263 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	"github.com/desepticon55/metrics-collector/proto/metrics"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type stubMetricsService struct {
+	batches [][]common.MetricRequestDto
+	metrics []common.MetricResponseDto
+}
+
+func (s *stubMetricsService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	s.batches = append(s.batches, request)
+	var response []common.MetricResponseDto
+	for _, metric := range request {
+		response = append(response, common.MetricResponseDto{ID: metric.ID, MType: metric.MType, Delta: metric.Delta, Value: metric.Value})
+	}
+	return response, nil
+}
+
+func (s *stubMetricsService) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error) {
+	for _, metric := range s.metrics {
+		if metric.ID == metricName && metric.MType == metricType {
+			return metric, nil
+		}
+	}
+	return common.MetricResponseDto{}, server.NewMetricNotFoundError(metricName, metricType)
+}
+
+func (s *stubMetricsService) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	return s.metrics
+}
+
+func TestCreateListMetricsHandler_NDJSON(t *testing.T) {
+	service := &stubMetricsService{}
+	handler := NewCreateListMetricsHandlerFromJSON(server.Config{}, nil, service, zap.NewNop())
+
+	var body strings.Builder
+	for i := 0; i < ndjsonBatchSize+1; i++ {
+		fmt.Fprintf(&body, "{\"id\":\"m%d\",\"type\":\"counter\",\"delta\":%d}\n", i, i)
+	}
+
+	request := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body.String()))
+	request.Header.Set("Content-Type", "application/x-ndjson")
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, contentTypeNDJSON, recorder.Header().Get("Content-Type"))
+	assert.Len(t, service.batches, 2)
+	assert.Len(t, strings.Split(strings.TrimSpace(recorder.Body.String()), "\n"), ndjsonBatchSize+1)
+}
+
+func TestCreateListMetricsHandler_NDJSONInterrupted(t *testing.T) {
+	var body strings.Builder
+	for i := 0; i < ndjsonBatchSize+10; i++ {
+		if i == ndjsonBatchSize+5 {
+			body.WriteString("{\"id\":\n")
+			break
+		}
+		fmt.Fprintf(&body, "{\"id\":\"m%d\",\"type\":\"counter\",\"delta\":%d}\n", i, i)
+	}
+
+	t.Run("streamed response ends with error record", func(t *testing.T) {
+		service := &stubMetricsService{}
+		handler := NewCreateListMetricsHandlerFromJSON(server.Config{}, nil, service, zap.NewNop())
+
+		request := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body.String()))
+		request.Header.Set("Content-Type", "application/x-ndjson")
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		assert.Len(t, service.batches, 1)
+		lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+		if assert.Len(t, lines, ndjsonBatchSize+1) {
+			var record errorRecord
+			assert.NoError(t, json.Unmarshal([]byte(lines[ndjsonBatchSize]), &record))
+			assert.NotEmpty(t, record.Error)
+		}
+	})
+
+	t.Run("buffered response reports saved metrics", func(t *testing.T) {
+		service := &stubMetricsService{}
+		handler := NewCreateListMetricsHandlerFromJSON(server.Config{}, nil, service, zap.NewNop())
+
+		request := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body.String()))
+		request.Header.Set("Content-Type", "application/x-ndjson")
+		request.Header.Set("Accept", "application/json")
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+
+		assert.Equal(t, http.StatusBadRequest, recorder.Code)
+		assert.Contains(t, recorder.Body.String(), fmt.Sprintf("%d metrics were saved before error", ndjsonBatchSize))
+	})
+}
+
+func TestCreateListMetricsHandler_Protobuf(t *testing.T) {
+	service := &stubMetricsService{}
+	handler := NewCreateListMetricsHandlerFromJSON(server.Config{}, nil, service, zap.NewNop())
+
+	body, err := proto.Marshal(&metrics.MetricsRequest{Metrics: []*metrics.Metric{
+		{Id: "gauge1", Type: "gauge", Value: 0},
+		{Id: "counter1", Type: "counter", Delta: 5},
+	}})
+	assert.NoError(t, err)
+
+	request := httptest.NewRequest(http.MethodPost, "/updates/", bytes.NewReader(body))
+	request.Header.Set("Content-Type", "application/x-protobuf")
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, contentTypeProtobuf, recorder.Header().Get("Content-Type"))
+
+	saved := service.batches[0]
+	assert.Equal(t, float64(0), *saved[0].Value)
+	assert.Nil(t, saved[0].Delta)
+	assert.Equal(t, int64(5), *saved[1].Delta)
+	assert.Nil(t, saved[1].Value)
+
+	var response metrics.MetricsRequest
+	assert.NoError(t, proto.Unmarshal(recorder.Body.Bytes(), &response))
+	assert.Len(t, response.Metrics, 2)
+}
+
+func TestCreateListMetricsHandler_UnsupportedContentType(t *testing.T) {
+	handler := NewCreateListMetricsHandlerFromJSON(server.Config{}, nil, &stubMetricsService{}, zap.NewNop())
+
+	request := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader("a=b"))
+	request.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+
+	assert.Equal(t, http.StatusUnsupportedMediaType, recorder.Code)
+}
+
+func TestFindAllMetricsHandler_Negotiation(t *testing.T) {
+	value := 1.5
+	service := &stubMetricsService{metrics: []common.MetricResponseDto{{ID: "gauge1", MType: common.Gauge, Value: &value}}}
+	handler := NewFindAllMetricsHandler(service, zap.NewNop())
+
+	request := httptest.NewRequest(http.MethodGet, "/", nil)
+	recorder := httptest.NewRecorder()
+	handler(recorder, request)
+	assert.Equal(t, "text/html", recorder.Header().Get("Content-Type"))
+
+	request = httptest.NewRequest(http.MethodGet, "/", nil)
+	request.Header.Set("Accept", "application/x-ndjson")
+	recorder = httptest.NewRecorder()
+	handler(recorder, request)
+
+	assert.Equal(t, contentTypeNDJSON, recorder.Header().Get("Content-Type"))
+	var metric common.MetricResponseDto
+	assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &metric))
+	assert.Equal(t, "gauge1", metric.ID)
+}
+
+func TestCreateListMetricsHandler_SharedVerifier(t *testing.T) {
+	config := server.Config{HashKey: "key"}
+	verifier := signing.NewDefaultVerifier(config.HashKey)
+	first := NewCreateListMetricsHandlerFromJSON(config, verifier, &stubMetricsService{}, zap.NewNop())
+	second := NewCreateListMetricsHandlerFromJSON(config, verifier, &stubMetricsService{}, zap.NewNop())
+
+	body := `[{"id":"PollCount","type":"counter","delta":5}]`
+	delta := int64(5)
+	payload, err := signing.MetricsPayload([]*metrics.Metric{common.MetricRequestToProto(common.MetricRequestDto{ID: "PollCount", MType: common.Counter, Delta: &delta})})
+	assert.NoError(t, err)
+	signature := signing.Sign(config.HashKey, payload)
+
+	send := func(handler http.HandlerFunc) *httptest.ResponseRecorder {
+		request := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/json")
+		signature.SetHeaders(request.Header)
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+		return recorder
+	}
+
+	recorder := send(first)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	// response is signed with the same scheme
+	responseVerifier := signing.NewDefaultVerifier(config.HashKey)
+	assert.NoError(t, responseVerifier.Verify(signing.FromHeaders(recorder.Header()), recorder.Body.Bytes()))
+
+	// the same batch can't be replayed to another endpoint sharing verifier
+	recorder = send(second)
+	assert.Equal(t, http.StatusBadRequest, recorder.Code)
+	assert.Contains(t, recorder.Body.String(), signing.ErrReplayed.Error())
+}
+
+// partialService fails to save metrics with "failed" prefix while the rest are saved
+type partialService struct {
+	stubMetricsService
+}
+
+func (s *partialService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	var saved []common.MetricRequestDto
+	partial := &server.PartialSaveError{}
+	for _, metric := range request {
+		if strings.HasPrefix(metric.ID, "failed") {
+			partial.Failed = append(partial.Failed, server.FailedMetric{ID: metric.ID, MType: metric.MType, Error: "shard is unavailable"})
+			continue
+		}
+		saved = append(saved, metric)
+	}
+	response, _ := s.stubMetricsService.SaveMetrics(ctx, saved)
+	if len(partial.Failed) == 0 {
+		return response, nil
+	}
+	partial.Saved = response
+	return response, partial
+}
+
+func TestCreateListMetricsHandler_Partial(t *testing.T) {
+	body := "{\"id\":\"m1\",\"type\":\"counter\",\"delta\":1}\n{\"id\":\"failed1\",\"type\":\"counter\",\"delta\":1}\n"
+
+	t.Run("streamed response lists failed metrics", func(t *testing.T) {
+		handler := NewCreateListMetricsHandlerFromJSON(server.Config{}, nil, &partialService{}, zap.NewNop())
+		request := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/x-ndjson")
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+
+		assert.Equal(t, http.StatusOK, recorder.Code)
+		lines := strings.Split(strings.TrimSpace(recorder.Body.String()), "\n")
+		if assert.Len(t, lines, 2) {
+			var record errorRecord
+			assert.NoError(t, json.Unmarshal([]byte(lines[0]), &record))
+			assert.Equal(t, errorRecord{ID: "failed1", MType: common.Counter, Error: "shard is unavailable"}, record)
+			assert.Contains(t, lines[1], "\"m1\"")
+		}
+	})
+
+	t.Run("buffered response has multi-status", func(t *testing.T) {
+		handler := NewCreateListMetricsHandlerFromJSON(server.Config{HashKey: "key"}, nil, &partialService{}, zap.NewNop())
+		request := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(body))
+		request.Header.Set("Content-Type", "application/x-ndjson")
+		recorder := httptest.NewRecorder()
+		handler(recorder, request)
+
+		assert.Equal(t, http.StatusMultiStatus, recorder.Code)
+		assert.NotEmpty(t, recorder.Header().Get("HashSHA256"))
+		var partial server.PartialSaveError
+		assert.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &partial))
+		assert.Len(t, partial.Saved, 1)
+		assert.Equal(t, []server.FailedMetric{{ID: "failed1", MType: common.Counter, Error: "shard is unavailable"}}, partial.Failed)
+	})
+}
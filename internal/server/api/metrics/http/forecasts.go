@@ -0,0 +1,88 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/forecast"
+	"github.com/desepticon55/metrics-collector/internal/server/service/history"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// Forecast gauge handler. Query parameters: method (linear or holt, linear by default),
+// window of history (1h by default), ahead (1h by default) and optional threshold
+func NewForecastHandler(service metrics.ForecastService, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodGet {
+			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
+			return
+		}
+
+		query := request.URL.Query()
+		method := forecast.Method(query.Get("method"))
+		if method == "" {
+			method = forecast.Linear
+		}
+		if method != forecast.Linear && method != forecast.Holt {
+			http.Error(writer, fmt.Sprintf("Unsupported method = '%s'", method), http.StatusBadRequest)
+			return
+		}
+
+		window, err := durationParam(query.Get("window"), time.Hour)
+		if err != nil || window <= 0 {
+			http.Error(writer, fmt.Sprintf("Incorrect window = '%s'", query.Get("window")), http.StatusBadRequest)
+			return
+		}
+		ahead, err := durationParam(query.Get("ahead"), time.Hour)
+		if err != nil || ahead < 0 {
+			http.Error(writer, fmt.Sprintf("Incorrect ahead = '%s'", query.Get("ahead")), http.StatusBadRequest)
+			return
+		}
+
+		var threshold *float64
+		if value := query.Get("threshold"); value != "" {
+			parsed, err := strconv.ParseFloat(value, 64)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("Incorrect threshold = '%s'", value), http.StatusBadRequest)
+				return
+			}
+			threshold = &parsed
+		}
+
+		response, err := service.Forecast(request.Context(), chi.URLParam(request, "name"), method, window, ahead, threshold)
+		if err != nil {
+			if errors.Is(err, history.ErrNotEnoughHistory) {
+				http.Error(writer, err.Error(), http.StatusNotFound)
+				return
+			}
+			logger.Error("Error during forecast.", zap.Error(err))
+			http.Error(writer, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		bytes, err := json.Marshal(response)
+		if err != nil {
+			logger.Error("Error during marshal forecast.", zap.Error(err))
+			http.Error(writer, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		if _, err = writer.Write(bytes); err != nil {
+			http.Error(writer, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
+
+func durationParam(value string, defaultValue time.Duration) (time.Duration, error) {
+	if value == "" {
+		return defaultValue, nil
+	}
+	return time.ParseDuration(value)
+}
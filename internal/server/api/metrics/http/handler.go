@@ -3,14 +3,14 @@ package http
 import (
 	"bytes"
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/desepticon55/metrics-collector/internal/common"
 	"github.com/desepticon55/metrics-collector/internal/server"
 	"github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	metrics2 "github.com/desepticon55/metrics-collector/proto/metrics"
 	"github.com/go-chi/chi/v5"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/zap"
@@ -67,9 +67,12 @@ func NewCreateMetricHandler(service metrics.MetricsService, logger *zap.Logger)
 
 		if _, err := service.SaveMetrics(request.Context(), []common.MetricRequestDto{requestDto}); err != nil {
 			var validationError *server.ValidationError
-			if errors.As(err, &validationError) {
+			switch {
+			case errors.As(err, &validationError):
 				http.Error(writer, err.Error(), http.StatusBadRequest)
-			} else {
+			case errors.Is(err, server.ErrReadOnly):
+				http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+			default:
 				http.Error(writer, err.Error(), http.StatusInternalServerError)
 			}
 			return
@@ -101,10 +104,13 @@ func NewCreateMetricHandlerFromJSON(service metrics.MetricsService, logger *zap.
 		metric, err := service.SaveMetrics(request.Context(), []common.MetricRequestDto{requestDto})
 		if err != nil {
 			var validationError *server.ValidationError
-			if errors.As(err, &validationError) {
+			switch {
+			case errors.As(err, &validationError):
 				logger.Error("Validation was failed", zap.Error(err))
 				http.Error(writer, err.Error(), http.StatusBadRequest)
-			} else {
+			case errors.Is(err, server.ErrReadOnly):
+				http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+			default:
 				logger.Error("Internal server error", zap.Error(err))
 				http.Error(writer, err.Error(), http.StatusInternalServerError)
 			}
@@ -120,78 +126,194 @@ func NewCreateMetricHandlerFromJSON(service metrics.MetricsService, logger *zap.
 	}
 }
 
-// Create list of mertics from JSON handler
-func NewCreateListMetricsHandlerFromJSON(config server.Config, service metrics.MetricsService, logger *zap.Logger) http.HandlerFunc {
+// Create list of mertics handler. Body can be JSON array, protobuf MetricsRequest or NDJSON stream.
+// NDJSON is saved by batches and NDJSON response is streamed, so every line of response is saved metric.
+// When saving is interrupted after status is sent, response ends with {"error":"..."} record and only metrics
+// listed before it are saved. Buffered response reports the same with error status and number of saved metrics.
+// Metrics which were not saved while the rest are saved (for example by failed shard of proxy) are listed by
+// {"id":"...","type":"...","error":"..."} records of streamed response or by 207 status with saved and failed lists.
+// Signature covers all metrics of request, so with hash key the whole body is read and verified before saving
+// and response is buffered to be signed. Signed body is limited by maxSignedBodySize and NDJSON is not streamed then
+func NewCreateListMetricsHandlerFromJSON(config server.Config, verifier *signing.Verifier, service metrics.MetricsService, logger *zap.Logger) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
 		if request.Method != http.MethodPost {
 			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
 			return
 		}
 
-		if config.HashKey != "" {
+		decoder, ok := requestCodec(request)
+		if !ok {
+			http.Error(writer, fmt.Sprintf("Content type '%s' is not supported", request.Header.Get("Content-Type")), http.StatusUnsupportedMediaType)
+			return
+		}
+		encoder, ok := responseCodec(request)
+		if !ok {
+			encoder = decoder
+		}
+
+		if verifier != nil {
 			var requestBodyBytes bytes.Buffer
-			_, err := io.Copy(&requestBodyBytes, request.Body)
+			_, err := io.Copy(&requestBodyBytes, http.MaxBytesReader(writer, request.Body, maxSignedBodySize))
+			var maxBytesError *http.MaxBytesError
+			if errors.As(err, &maxBytesError) {
+				http.Error(writer, fmt.Sprintf("Signed body is larger than %d bytes", maxSignedBodySize), http.StatusRequestEntityTooLarge)
+				return
+			}
 			if err != nil {
 				logger.Error("Error reading request body", zap.Error(err))
 				http.Error(writer, err.Error(), http.StatusInternalServerError)
 				return
 			}
-
 			requestBody := requestBodyBytes.Bytes()
-			hash := sha256.Sum256(append(requestBody, []byte(config.HashKey)...))
-			hashStr := hex.EncodeToString(hash[:])
-			hashSHA256 := request.Header.Get("HashSHA256")
-			if hashSHA256 == "" {
+
+			signature := signing.FromHeaders(request.Header)
+			if signature.Value == "" {
 				http.Error(writer, "HashSHA256 header is missing", http.StatusBadRequest)
 				return
 			}
 
-			if hashSHA256 != hashStr {
-				logger.Error("Invalid HashSHA256", zap.String("header hash", hashSHA256), zap.String("calculated hash", hashStr))
-				http.Error(writer, "Invalid HashSHA256", http.StatusBadRequest)
+			// signature covers canonical serialization of metrics, so it doesn't depend on encoding of body
+			var protoMetrics []*metrics2.Metric
+			err = decoder.decodeList(bytes.NewReader(requestBody), func(requestDtoList []common.MetricRequestDto) error {
+				for _, metric := range requestDtoList {
+					protoMetrics = append(protoMetrics, common.MetricRequestToProto(metric))
+				}
+				return nil
+			})
+			if err != nil {
+				logger.Error("Error decoding request", zap.Error(err))
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			payload, err := signing.MetricsPayload(protoMetrics)
+			if err != nil {
+				logger.Error("Error during serialize signed payload", zap.Error(err))
+				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				return
+			}
+			if err := verifier.Verify(signature, payload); err != nil {
+				logger.Error("Invalid HashSHA256", zap.String("header hash", signature.Value), zap.Error(err))
+				http.Error(writer, fmt.Sprintf("Invalid HashSHA256: %v", err), http.StatusBadRequest)
 				return
 			}
 			request.Body = io.NopCloser(bytes.NewReader(requestBody))
 		}
 
-		var requestDtoList []common.MetricRequestDto
-		if err := json.NewDecoder(request.Body).Decode(&requestDtoList); err != nil {
-			logger.Error("Error decoding request", zap.Error(err))
-			http.Error(writer, err.Error(), http.StatusBadRequest)
-			return
-		}
+		// response is streamed directly to client unless it should be signed with hash
+		var response bytes.Buffer
+		var output io.Writer = writer
+		if config.HashKey != "" {
+			output = &response
+		}
+		listEncoder := encoder.newListEncoder(output)
+
+		streaming, isStreaming := listEncoder.(streamingEncoder)
+		isStreaming = isStreaming && config.HashKey == ""
+
+		headerWritten := false
+		saved := 0
+		var saveErr error
+		partial := &server.PartialSaveError{}
+		err := decoder.decodeList(request.Body, func(requestDtoList []common.MetricRequestDto) error {
+			savedMetrics, err := service.SaveMetrics(request.Context(), requestDtoList)
+			var partialError *server.PartialSaveError
+			if errors.As(err, &partialError) {
+				savedMetrics = partialError.Saved
+				partial.Failed = append(partial.Failed, partialError.Failed...)
+			} else if err != nil {
+				saveErr = err
+				return err
+			}
+			saved += len(savedMetrics)
+
+			if !headerWritten && isStreaming {
+				writer.Header().Set("Content-Type", encoder.contentType())
+				writer.WriteHeader(http.StatusOK)
+				headerWritten = true
+			}
+			if !isStreaming {
+				partial.Saved = append(partial.Saved, savedMetrics...)
+			} else if partialError != nil {
+				if err := streaming.skip(partialError.Failed); err != nil {
+					return err
+				}
+			}
+			return listEncoder.encode(savedMetrics)
+		})
 
-		savedMetrics, err := service.SaveMetrics(request.Context(), requestDtoList)
 		if err != nil {
+			if headerWritten {
+				logger.Error("Error during streaming metrics", zap.Error(err), zap.Int("saved", saved))
+				if err := streaming.fail(err); err != nil {
+					logger.Error("Error during write error record", zap.Error(err))
+				}
+				return
+			}
+
+			message := err.Error()
+			if saved > 0 {
+				message = fmt.Sprintf("%s (%d metrics were saved before error)", message, saved)
+			}
 			var validationError *server.ValidationError
-			if errors.As(err, &validationError) {
+			switch {
+			case saveErr == nil:
+				logger.Error("Error decoding request", zap.Error(err))
+				http.Error(writer, message, http.StatusBadRequest)
+			case errors.As(saveErr, &validationError):
 				logger.Error("Validation was failed", zap.Error(err))
-				http.Error(writer, err.Error(), http.StatusBadRequest)
-			} else {
+				http.Error(writer, message, http.StatusBadRequest)
+			case errors.Is(saveErr, server.ErrReadOnly):
+				http.Error(writer, message, http.StatusServiceUnavailable)
+			default:
 				logger.Error("Internal server error", zap.Error(err))
-				http.Error(writer, err.Error(), http.StatusInternalServerError)
+				http.Error(writer, message, http.StatusInternalServerError)
 			}
 			return
 		}
 
-		response, err := json.Marshal(savedMetrics)
-		if err != nil {
+		if len(partial.Failed) > 0 && !isStreaming {
+			logger.Error("Metrics were saved partially", zap.Error(partial))
+			writePartial(writer, config, partial, logger)
+			return
+		}
+
+		if !headerWritten && config.HashKey == "" {
+			writer.Header().Set("Content-Type", encoder.contentType())
+			writer.WriteHeader(http.StatusOK)
+		}
+		if err := listEncoder.close(); err != nil {
 			logger.Error("Error during marshal response", zap.Error(err))
-			http.Error(writer, err.Error(), http.StatusInternalServerError)
+			return
 		}
 
 		if config.HashKey != "" {
-			hash := sha256.Sum256(append(response, []byte(config.HashKey)...))
-			hashStr := hex.EncodeToString(hash[:])
-			writer.Header().Set("HashSHA256", hashStr)
+			signing.Sign(config.HashKey, response.Bytes()).SetHeaders(writer.Header())
+			writer.Header().Set("Content-Type", encoder.contentType())
+			writer.WriteHeader(http.StatusOK)
+			if _, err := writer.Write(response.Bytes()); err != nil {
+				logger.Error("Error during write response", zap.Error(err))
+			}
 		}
+	}
+}
 
-		writer.Header().Set("Content-Type", "application/json")
-		writer.WriteHeader(http.StatusOK)
-		if _, err := writer.Write(response); err != nil {
-			logger.Error("Error during write response", zap.Error(err))
-			http.Error(writer, err.Error(), http.StatusInternalServerError)
-		}
+// writePartial responds with saved and failed metrics as JSON whatever encoding was requested.
+// Response is signed when server has key
+func writePartial(writer http.ResponseWriter, config server.Config, partial *server.PartialSaveError, logger *zap.Logger) {
+	body, err := json.Marshal(partial)
+	if err != nil {
+		logger.Error("Error during marshal response", zap.Error(err))
+		http.Error(writer, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	if config.HashKey != "" {
+		signing.Sign(config.HashKey, body).SetHeaders(writer.Header())
+	}
+	writer.Header().Set("Content-Type", contentTypeJSON)
+	writer.WriteHeader(http.StatusMultiStatus)
+	if _, err := writer.Write(body); err != nil {
+		logger.Error("Error during write response", zap.Error(err))
 	}
 }
 
@@ -222,6 +344,14 @@ func NewFindMetricValueHandler(service metrics.MetricsService, logger *zap.Logge
 			return
 		}
 
+		if encoder, ok := responseCodec(request); ok {
+			writer.Header().Set("Content-Type", encoder.contentType())
+			if err := encoder.encodeOne(writer, metric); err != nil {
+				logger.Error("Error during encode metric", zap.Error(err))
+			}
+			return
+		}
+
 		var value interface{}
 		if metric.MType == common.Gauge {
 			value = metric.Value
@@ -245,17 +375,26 @@ func NewFindMetricValueHandler(service metrics.MetricsService, logger *zap.Logge
 	}
 }
 
-// Find one mertic handler
-func NewFindOneMetricHandler(service metrics.MetricsService, logger *zap.Logger) http.HandlerFunc {
+// Find one mertic handler. Body can be JSON or protobuf Metric. Counters are returned with rate
+func NewFindOneMetricHandler(service metrics.MetricsService, rates metrics.RatesService, logger *zap.Logger) http.HandlerFunc {
 	return func(writer http.ResponseWriter, request *http.Request) {
 		if request.Method != http.MethodPost {
 			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
 			return
 		}
 
-		var requestDto common.MetricRequestDto
+		decoder, ok := requestCodec(request)
+		if !ok {
+			http.Error(writer, fmt.Sprintf("Content type '%s' is not supported", request.Header.Get("Content-Type")), http.StatusUnsupportedMediaType)
+			return
+		}
+		encoder, ok := responseCodec(request)
+		if !ok {
+			encoder = decoder
+		}
 
-		if err := json.NewDecoder(request.Body).Decode(&requestDto); err != nil {
+		requestDto, err := decoder.decodeOne(request.Body)
+		if err != nil {
 			logger.Error("Error decode request", zap.Error(err))
 			http.Error(writer, err.Error(), http.StatusBadRequest)
 			return
@@ -280,16 +419,21 @@ func NewFindOneMetricHandler(service metrics.MetricsService, logger *zap.Logger)
 			}
 			return
 		}
+		if metric.MType == common.Counter {
+			if rate, _, err := rates.CounterRate(request.Context(), metric.ID, defaultRateWindow); err == nil {
+				metric.Rate = &rate
+			}
+		}
 
-		bytes, err := json.Marshal(metric)
-		if err != nil {
+		var response bytes.Buffer
+		if err := encoder.encodeOne(&response, metric); err != nil {
 			logger.Error("Error during marshal metric. {}", zap.Error(err))
 			http.Error(writer, "Internal server error", http.StatusInternalServerError)
 			return
 		}
 
-		writer.Header().Set("Content-Type", "application/json")
-		if _, err = writer.Write(bytes); err != nil {
+		writer.Header().Set("Content-Type", encoder.contentType())
+		if _, err = writer.Write(response.Bytes()); err != nil {
 			http.Error(writer, "Internal server error", http.StatusInternalServerError)
 			return
 		}
@@ -305,6 +449,19 @@ func NewFindAllMetricsHandler(service metrics.MetricsService, logger *zap.Logger
 			return
 		}
 
+		if encoder, ok := responseCodec(request); ok {
+			writer.Header().Set("Content-Type", encoder.contentType())
+			listEncoder := encoder.newListEncoder(writer)
+			err := listEncoder.encode(service.FindAllMetrics(request.Context()))
+			if err == nil {
+				err = listEncoder.close()
+			}
+			if err != nil {
+				logger.Error("Error during encode metrics", zap.Error(err))
+			}
+			return
+		}
+
 		bytes, err := json.Marshal(service.FindAllMetrics(request.Context()))
 		if err != nil {
 			logger.Error("Error during marshal metric.", zap.Error(err))
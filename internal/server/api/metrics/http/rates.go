@@ -0,0 +1,62 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/history"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+	"time"
+)
+
+// Window of rate which is returned together with counter value
+const defaultRateWindow = time.Minute
+
+// Find per-second rate of counter handler. Window is set by query parameter, e.g. window=5m
+func NewFindCounterRateHandler(service metrics.RatesService, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodGet {
+			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
+			return
+		}
+
+		window := defaultRateWindow
+		if value := request.URL.Query().Get("window"); value != "" {
+			var err error
+			window, err = time.ParseDuration(value)
+			if err != nil || window <= 0 {
+				http.Error(writer, fmt.Sprintf("Incorrect window = '%s'", value), http.StatusBadRequest)
+				return
+			}
+		}
+
+		name := chi.URLParam(request, "name")
+		rate, samples, err := service.CounterRate(request.Context(), name, window)
+		if err != nil {
+			if errors.Is(err, history.ErrNotEnoughHistory) {
+				http.Error(writer, err.Error(), http.StatusNotFound)
+				return
+			}
+			logger.Error("Error during compute rate.", zap.Error(err))
+			http.Error(writer, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		bytes, err := json.Marshal(common.RateResponseDto{ID: name, MType: common.Counter, Window: window.String(), Rate: rate, Samples: samples})
+		if err != nil {
+			logger.Error("Error during marshal rate.", zap.Error(err))
+			http.Error(writer, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writer.Header().Set("Content-Type", "application/json")
+		if _, err = writer.Write(bytes); err != nil {
+			http.Error(writer, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+	}
+}
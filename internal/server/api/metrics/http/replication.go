@@ -0,0 +1,49 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// Find replication status of follower handler
+func NewReplicationStatusHandler(service metrics.ReplicationService, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodGet {
+			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
+			return
+		}
+
+		writeReplicationStatus(writer, service.ReplicationStatus(request.Context()), logger)
+	}
+}
+
+// Promote follower to primary handler. Promotion of already promoted follower changes nothing
+func NewPromoteHandler(service metrics.ReplicationService, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
+			return
+		}
+
+		writeReplicationStatus(writer, service.Promote(request.Context()), logger)
+	}
+}
+
+func writeReplicationStatus(writer http.ResponseWriter, status server.ReplicationStatus, logger *zap.Logger) {
+	bytes, err := json.Marshal(status)
+	if err != nil {
+		logger.Error("Error during marshal replication status.", zap.Error(err))
+		http.Error(writer, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	if _, err = writer.Write(bytes); err != nil {
+		http.Error(writer, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+}
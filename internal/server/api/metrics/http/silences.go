@@ -0,0 +1,93 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/silences"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// Create silence handler
+func NewCreateSilenceHandler(service metrics.SilencesService, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
+			return
+		}
+
+		var silence server.Silence
+		if err := json.NewDecoder(request.Body).Decode(&silence); err != nil {
+			http.Error(writer, "Invalid JSON", http.StatusBadRequest)
+			return
+		}
+
+		silence, err := service.CreateSilence(request.Context(), silence)
+		if err != nil {
+			if errors.Is(err, silences.ErrInvalidSilence) {
+				http.Error(writer, err.Error(), http.StatusBadRequest)
+				return
+			}
+			logger.Error("Error during create silence.", zap.Error(err))
+			http.Error(writer, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeSilenceJSON(writer, http.StatusCreated, silence, logger)
+	}
+}
+
+// Find all not expired silences handler. Expired silences are included with query parameter all=true
+func NewFindAllSilencesHandler(service metrics.SilencesService, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodGet {
+			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
+			return
+		}
+
+		includeExpired := request.URL.Query().Get("all") == "true"
+		writeSilenceJSON(writer, http.StatusOK, service.FindAllSilences(request.Context(), includeExpired), logger)
+	}
+}
+
+// Expire silence handler
+func NewExpireSilenceHandler(service metrics.SilencesService, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodDelete {
+			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
+			return
+		}
+
+		silence, err := service.ExpireSilence(request.Context(), chi.URLParam(request, "id"))
+		if err != nil {
+			if errors.Is(err, silences.ErrSilenceNotFound) {
+				http.Error(writer, "Silence not found", http.StatusNotFound)
+				return
+			}
+			logger.Error("Error during expire silence.", zap.Error(err))
+			http.Error(writer, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+
+		writeSilenceJSON(writer, http.StatusOK, silence, logger)
+	}
+}
+
+func writeSilenceJSON(writer http.ResponseWriter, status int, value any, logger *zap.Logger) {
+	bytes, err := json.Marshal(value)
+	if err != nil {
+		logger.Error("Error during marshal silences.", zap.Error(err))
+		http.Error(writer, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if _, err = writer.Write(bytes); err != nil {
+		logger.Error("Error during write response.", zap.Error(err))
+	}
+}
@@ -0,0 +1,163 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/stream"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+)
+
+const streamHeartbeatInterval = 15 * time.Second
+
+// Stream of metric changes handler. Server-Sent Events are used by default,
+// WebSocket is used when client requests connection upgrade.
+// Query parameters: name - metric name pattern, type - metric type, resume - last received event ID
+// (Last-Event-ID header is used by SSE clients on reconnect)
+func NewStreamHandler(broker metrics.MetricsStream, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodGet {
+			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
+			return
+		}
+
+		filter := stream.Filter{
+			Name: request.URL.Query().Get("name"),
+			Type: common.MetricType(request.URL.Query().Get("type")),
+		}
+		if filter.Type != "" && filter.Type != common.Gauge && filter.Type != common.Counter {
+			http.Error(writer, fmt.Sprintf("Unsupported metric type = '%s'", filter.Type), http.StatusBadRequest)
+			return
+		}
+
+		resumeToken := request.URL.Query().Get("resume")
+		if resumeToken == "" {
+			resumeToken = request.Header.Get("Last-Event-ID")
+		}
+		var resumeFrom uint64
+		if resumeToken != "" {
+			var err error
+			resumeFrom, err = strconv.ParseUint(resumeToken, 10, 64)
+			if err != nil {
+				http.Error(writer, fmt.Sprintf("Incorrect resume token = '%s'", resumeToken), http.StatusBadRequest)
+				return
+			}
+		}
+
+		if strings.EqualFold(request.Header.Get("Upgrade"), "websocket") {
+			serveWebSocket(writer, request, broker, filter, resumeFrom, logger)
+			return
+		}
+		serveSSE(writer, request, broker, filter, resumeFrom, logger)
+	}
+}
+
+func serveSSE(writer http.ResponseWriter, request *http.Request, broker metrics.MetricsStream, filter stream.Filter, resumeFrom uint64, logger *zap.Logger) {
+	flusher, ok := writer.(http.Flusher)
+	if !ok {
+		http.Error(writer, "Streaming is not supported", http.StatusInternalServerError)
+		return
+	}
+
+	subscription := broker.Subscribe(filter, resumeFrom)
+	defer broker.Unsubscribe(subscription)
+
+	writer.Header().Set("Content-Type", "text/event-stream")
+	writer.Header().Set("Cache-Control", "no-cache")
+	writer.Header().Set("Connection", "keep-alive")
+	writer.WriteHeader(http.StatusOK)
+	flusher.Flush()
+
+	heartbeat := time.NewTicker(streamHeartbeatInterval)
+	defer heartbeat.Stop()
+
+	for {
+		select {
+		case <-request.Context().Done():
+			return
+		case <-heartbeat.C:
+			if _, err := fmt.Fprint(writer, ": ping\n\n"); err != nil {
+				return
+			}
+			flusher.Flush()
+		case event, ok := <-subscription.Events():
+			if !ok {
+				logger.Info("Stream subscriber is too slow, disconnecting", zap.String("remote_addr", request.RemoteAddr))
+				return
+			}
+
+			data, err := json.Marshal(event.Metric)
+			if err != nil {
+				logger.Error("Error during marshal metric", zap.Error(err))
+				return
+			}
+			if _, err := fmt.Fprintf(writer, "id: %d\nevent: metric\ndata: %s\n\n", event.Seq, data); err != nil {
+				return
+			}
+			flusher.Flush()
+		}
+	}
+}
+
+func serveWebSocket(writer http.ResponseWriter, request *http.Request, broker metrics.MetricsStream, filter stream.Filter, resumeFrom uint64, logger *zap.Logger) {
+	server := websocket.Server{
+		Handshake: func(config *websocket.Config, request *http.Request) error {
+			return checkOrigin(request)
+		},
+		Handler: func(conn *websocket.Conn) {
+			defer conn.Close()
+
+			subscription := broker.Subscribe(filter, resumeFrom)
+			defer broker.Unsubscribe(subscription)
+
+			// client messages are not expected, reading is used to detect closed connection
+			closed := make(chan struct{})
+			go func() {
+				defer close(closed)
+				var message []byte
+				for websocket.Message.Receive(conn, &message) == nil {
+				}
+			}()
+
+			for {
+				select {
+				case <-closed:
+					return
+				case event, ok := <-subscription.Events():
+					if !ok {
+						logger.Info("Stream subscriber is too slow, disconnecting", zap.String("remote_addr", request.RemoteAddr))
+						return
+					}
+					if err := websocket.JSON.Send(conn, event); err != nil {
+						return
+					}
+				}
+			}
+		},
+	}
+	server.ServeHTTP(writer, request)
+}
+
+// checkOrigin allows browser to connect only from page of the same host, so other sites can't read stream
+// with credentials of user. Non-browser clients don't send Origin header, their requests are allowed
+func checkOrigin(request *http.Request) error {
+	origin := request.Header.Get("Origin")
+	if origin == "" {
+		return nil
+	}
+	originURL, err := url.Parse(origin)
+	if err != nil {
+		return fmt.Errorf("invalid origin '%s': %w", origin, err)
+	}
+	if !strings.EqualFold(originURL.Host, request.Host) {
+		return fmt.Errorf("origin '%s' is not allowed", origin)
+	}
+	return nil
+}
@@ -0,0 +1,48 @@
+package http
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/server/service/stream"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"golang.org/x/net/websocket"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestCheckOrigin(t *testing.T) {
+	tests := []struct {
+		name    string
+		origin  string
+		allowed bool
+	}{
+		{name: "non-browser client", origin: "", allowed: true},
+		{name: "same host", origin: "https://metrics.example.com:8080", allowed: true},
+		{name: "other site", origin: "https://evil.example.com", allowed: false},
+		{name: "other port", origin: "https://metrics.example.com:9090", allowed: false},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			request := httptest.NewRequest(http.MethodGet, "http://metrics.example.com:8080/stream", nil)
+			if tt.origin != "" {
+				request.Header.Set("Origin", tt.origin)
+			}
+			assert.Equal(t, tt.allowed, checkOrigin(request) == nil)
+		})
+	}
+}
+
+func TestStreamHandler_WebSocketOrigin(t *testing.T) {
+	testServer := httptest.NewServer(NewStreamHandler(stream.New(nil, 10), zap.NewNop()))
+	defer testServer.Close()
+	location := "ws" + strings.TrimPrefix(testServer.URL, "http") + "/stream"
+
+	conn, err := websocket.Dial(location, "", testServer.URL)
+	if assert.NoError(t, err) {
+		conn.Close()
+	}
+
+	_, err = websocket.Dial(location, "", "https://evil.example.com")
+	assert.Error(t, err)
+}
@@ -0,0 +1,119 @@
+package v2
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	metrics2 "github.com/desepticon55/metrics-collector/proto/metrics"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// Find all metrics handler
+func NewFindAllMetricsHandler(service metrics.MetricsService, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		result := service.FindAllMetrics(request.Context())
+		if result == nil {
+			result = []common.MetricResponseDto{}
+		}
+		writeJSON(writer, http.StatusOK, result, logger)
+	}
+}
+
+// Find metric by type and name handler
+func NewFindMetricHandler(service metrics.MetricsService, logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		metricType := common.MetricType(chi.URLParam(request, "type"))
+		if metricType != common.Gauge && metricType != common.Counter {
+			WriteProblem(writer, request, http.StatusBadRequest, CodeValidationFailed, fmt.Sprintf("Metric type '%s' is not supported", metricType),
+				FieldError{Field: "type", Rule: "oneof", Message: "type should be one of: gauge, counter"})
+			return
+		}
+
+		metric, err := service.FindOneMetric(request.Context(), chi.URLParam(request, "name"), metricType)
+		if err != nil {
+			var notFoundError *server.MetricNotFoundError
+			if errors.As(err, &notFoundError) {
+				WriteProblem(writer, request, http.StatusNotFound, CodeMetricNotFound, err.Error())
+				return
+			}
+			logger.Error("Error during find metric", zap.Error(err))
+			WriteProblem(writer, request, http.StatusInternalServerError, CodeInternal, "Internal server error")
+			return
+		}
+		writeJSON(writer, http.StatusOK, metric, logger)
+	}
+}
+
+// Save list of metrics handler. Body is JSON array, all metrics are validated before anything is saved.
+// When verifier is set, request is signed like /updates/ and signature is verified before validation
+func NewSaveMetricsHandler(verifier *signing.Verifier, service metrics.MetricsService, logger *zap.Logger) http.HandlerFunc {
+	v := newValidator()
+
+	return func(writer http.ResponseWriter, request *http.Request) {
+		var metricRequests []metricRequest
+		decoder := json.NewDecoder(request.Body)
+		decoder.DisallowUnknownFields()
+		if err := decoder.Decode(&metricRequests); err != nil {
+			WriteProblem(writer, request, http.StatusBadRequest, CodeMalformedRequest, fmt.Sprintf("Invalid JSON: %v", err))
+			return
+		}
+
+		dtoList := make([]common.MetricRequestDto, 0, len(metricRequests))
+		for _, metric := range metricRequests {
+			dtoList = append(dtoList, metric.toDto())
+		}
+
+		// unauthenticated client learns nothing about validation of metrics
+		if verifier != nil {
+			if err := verify(verifier, request, dtoList); err != nil {
+				logger.Error("Invalid HashSHA256", zap.Error(err))
+				WriteProblem(writer, request, http.StatusUnauthorized, CodeInvalidSignature, err.Error())
+				return
+			}
+		}
+
+		if len(metricRequests) == 0 {
+			WriteProblem(writer, request, http.StatusUnprocessableEntity, CodeValidationFailed, "At least one metric is required")
+			return
+		}
+		if fieldErrors := validateMetrics(v, metricRequests); len(fieldErrors) > 0 {
+			WriteProblem(writer, request, http.StatusUnprocessableEntity, CodeValidationFailed, "Metrics are invalid", fieldErrors...)
+			return
+		}
+
+		saved, err := service.SaveMetrics(request.Context(), dtoList)
+		if err != nil {
+			var validationError *server.ValidationError
+			if errors.As(err, &validationError) {
+				WriteProblem(writer, request, http.StatusUnprocessableEntity, CodeValidationFailed, err.Error())
+				return
+			}
+			if errors.Is(err, server.ErrReadOnly) {
+				WriteProblem(writer, request, http.StatusServiceUnavailable, CodeReadOnly, err.Error())
+				return
+			}
+			logger.Error("Error during save metrics", zap.Error(err))
+			WriteProblem(writer, request, http.StatusInternalServerError, CodeInternal, "Internal server error")
+			return
+		}
+		writeJSON(writer, http.StatusOK, saved, logger)
+	}
+}
+
+func verify(verifier *signing.Verifier, request *http.Request, dtoList []common.MetricRequestDto) error {
+	protoMetrics := make([]*metrics2.Metric, 0, len(dtoList))
+	for _, dto := range dtoList {
+		protoMetrics = append(protoMetrics, common.MetricRequestToProto(dto))
+	}
+	payload, err := signing.MetricsPayload(protoMetrics)
+	if err != nil {
+		return err
+	}
+	return verifier.Verify(signing.FromHeaders(request.Header), payload)
+}
@@ -0,0 +1,70 @@
+package v2
+
+import (
+	"encoding/json"
+	"github.com/go-chi/chi/v5/middleware"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+const problemContentType = "application/problem+json"
+
+// Codes of problems, they are stable and can be used by clients instead of detail
+const (
+	CodeNotFound             = "not_found"
+	CodeMethodNotAllowed     = "method_not_allowed"
+	CodeUnsupportedMediaType = "unsupported_media_type"
+	CodeMalformedRequest     = "malformed_request"
+	CodeValidationFailed     = "validation_failed"
+	CodeMetricNotFound       = "metric_not_found"
+	CodeInvalidSignature     = "invalid_signature"
+	CodeReadOnly             = "read_only"
+	CodeInternal             = "internal_error"
+)
+
+// Problem is RFC 7807 problem details extended with code, request ID and validation errors
+type Problem struct {
+	Type      string       `json:"type"`
+	Title     string       `json:"title"`
+	Status    int          `json:"status"`
+	Detail    string       `json:"detail,omitempty"`
+	Instance  string       `json:"instance,omitempty"`
+	Code      string       `json:"code"`
+	RequestID string       `json:"request_id,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+}
+
+// FieldError is validation error of field of request. Index is position of metric in batch request
+type FieldError struct {
+	Index   *int   `json:"index,omitempty"`
+	Field   string `json:"field"`
+	Rule    string `json:"rule"`
+	Message string `json:"message"`
+}
+
+// WriteProblem responds with problem details, errors are listed when request is invalid
+func WriteProblem(writer http.ResponseWriter, request *http.Request, status int, code string, detail string, errors ...FieldError) {
+	problem := Problem{
+		Type:      "about:blank",
+		Title:     http.StatusText(status),
+		Status:    status,
+		Detail:    detail,
+		Instance:  request.URL.Path,
+		Code:      code,
+		RequestID: middleware.GetReqID(request.Context()),
+		Errors:    errors,
+	}
+
+	writer.Header().Set("Content-Type", problemContentType)
+	writer.Header().Set("X-Content-Type-Options", "nosniff")
+	writer.WriteHeader(status)
+	_ = json.NewEncoder(writer).Encode(problem)
+}
+
+func writeJSON(writer http.ResponseWriter, status int, body any, logger *zap.Logger) {
+	writer.Header().Set("Content-Type", "application/json")
+	writer.WriteHeader(status)
+	if err := json.NewEncoder(writer).Encode(body); err != nil {
+		logger.Error("Error during encode response", zap.Error(err))
+	}
+}
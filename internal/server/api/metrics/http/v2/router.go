@@ -0,0 +1,64 @@
+package v2
+
+import (
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	"github.com/go-chi/chi/v5"
+	"go.uber.org/zap"
+	"mime"
+	"net/http"
+	"strings"
+)
+
+// Prefix is path where router is mounted
+const Prefix = "/api/v2"
+
+var routerMethods = []string{http.MethodGet, http.MethodHead, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete, http.MethodOptions}
+
+// NewRouter makes route tree of v2 API. Every error is responded with problem details
+func NewRouter(verifier *signing.Verifier, service metrics.MetricsService, logger *zap.Logger) http.Handler {
+	router := chi.NewRouter()
+	router.NotFound(func(writer http.ResponseWriter, request *http.Request) {
+		WriteProblem(writer, request, http.StatusNotFound, CodeNotFound, fmt.Sprintf("Path '%s' is not found", request.URL.Path))
+	})
+	router.MethodNotAllowed(func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Allow", strings.Join(allowedMethods(router, request), ", "))
+		WriteProblem(writer, request, http.StatusMethodNotAllowed, CodeMethodNotAllowed, fmt.Sprintf("Method '%s' is not allowed", request.Method))
+	})
+
+	router.Get("/metrics", NewFindAllMetricsHandler(service, logger))
+	router.With(RequireJSON).Post("/metrics", NewSaveMetricsHandler(verifier, service, logger))
+	router.Get("/metrics/{type}/{name}", NewFindMetricHandler(service, logger))
+	return router
+}
+
+// allowedMethods finds methods of route matching request path
+func allowedMethods(router chi.Routes, request *http.Request) []string {
+	path := request.URL.Path
+	if routeContext := chi.RouteContext(request.Context()); routeContext != nil && routeContext.RoutePath != "" {
+		path = routeContext.RoutePath
+	}
+
+	var allowed []string
+	for _, method := range routerMethods {
+		if router.Match(chi.NewRouteContext(), method, path) {
+			allowed = append(allowed, method)
+		}
+	}
+	return allowed
+}
+
+// RequireJSON responds with 415 when request body is not JSON
+func RequireJSON(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		contentType := request.Header.Get("Content-Type")
+		mediaType, _, err := mime.ParseMediaType(contentType)
+		if err != nil || mediaType != "application/json" {
+			WriteProblem(writer, request, http.StatusUnsupportedMediaType, CodeUnsupportedMediaType,
+				fmt.Sprintf("Content type '%s' is not supported, use 'application/json'", contentType))
+			return
+		}
+		next.ServeHTTP(writer, request)
+	})
+}
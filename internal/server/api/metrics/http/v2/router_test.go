@@ -0,0 +1,161 @@
+package v2
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/signing"
+	metrics2 "github.com/desepticon55/metrics-collector/proto/metrics"
+	"github.com/go-chi/chi/v5"
+	"github.com/go-chi/chi/v5/middleware"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type stubService struct {
+	saved []common.MetricRequestDto
+}
+
+func (s *stubService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	s.saved = append(s.saved, request...)
+	var response []common.MetricResponseDto
+	for _, metric := range request {
+		response = append(response, common.MetricResponseDto{ID: metric.ID, MType: metric.MType, Delta: metric.Delta, Value: metric.Value})
+	}
+	return response, nil
+}
+
+func (s *stubService) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error) {
+	return common.MetricResponseDto{}, server.NewMetricNotFoundError(metricName, metricType)
+}
+
+func (s *stubService) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	return nil
+}
+
+func newTestRouter(config server.Config, service *stubService) http.Handler {
+	router := chi.NewRouter()
+	router.Use(middleware.RequestID)
+	router.Mount(Prefix, NewRouter(signing.NewDefaultVerifier(config.HashKey), service, zap.NewNop()))
+	return router
+}
+
+func serve(router http.Handler, method string, path string, contentType string, body string) (*httptest.ResponseRecorder, Problem) {
+	request := httptest.NewRequest(method, path, strings.NewReader(body))
+	if contentType != "" {
+		request.Header.Set("Content-Type", contentType)
+	}
+	recorder := httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+
+	var problem Problem
+	if recorder.Header().Get("Content-Type") == problemContentType {
+		_ = json.Unmarshal(recorder.Body.Bytes(), &problem)
+	}
+	return recorder, problem
+}
+
+func TestRouter_Problems(t *testing.T) {
+	router := newTestRouter(server.Config{}, &stubService{})
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		status      int
+		code        string
+	}{
+		{name: "unknown path", method: http.MethodGet, path: "/api/v2/unknown", status: http.StatusNotFound, code: CodeNotFound},
+		{name: "wrong method", method: http.MethodDelete, path: "/api/v2/metrics", status: http.StatusMethodNotAllowed, code: CodeMethodNotAllowed},
+		{name: "not JSON", method: http.MethodPost, path: "/api/v2/metrics", contentType: "text/plain", body: "[]", status: http.StatusUnsupportedMediaType, code: CodeUnsupportedMediaType},
+		{name: "malformed JSON", method: http.MethodPost, path: "/api/v2/metrics", contentType: "application/json", body: "[{", status: http.StatusBadRequest, code: CodeMalformedRequest},
+		{name: "empty batch", method: http.MethodPost, path: "/api/v2/metrics", contentType: "application/json", body: "[]", status: http.StatusUnprocessableEntity, code: CodeValidationFailed},
+		{name: "unsupported type", method: http.MethodGet, path: "/api/v2/metrics/histogram/Alloc", status: http.StatusBadRequest, code: CodeValidationFailed},
+		{name: "metric not found", method: http.MethodGet, path: "/api/v2/metrics/gauge/Alloc", status: http.StatusNotFound, code: CodeMetricNotFound},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder, problem := serve(router, tt.method, tt.path, tt.contentType, tt.body)
+			assert.Equal(t, tt.status, recorder.Code)
+			assert.Equal(t, tt.status, problem.Status)
+			assert.Equal(t, tt.code, problem.Code)
+			assert.Equal(t, tt.path, problem.Instance)
+			assert.NotEmpty(t, problem.RequestID)
+		})
+	}
+
+	recorder, _ := serve(router, http.MethodPut, "/api/v2/metrics", "", "")
+	assert.Equal(t, "GET, POST", recorder.Header().Get("Allow"))
+}
+
+func TestRouter_SaveMetricsValidation(t *testing.T) {
+	service := &stubService{}
+	router := newTestRouter(server.Config{}, service)
+
+	body := `[{"id":"Alloc","type":"gauge","value":0},{"id":"","type":"counter","value":1},{"id":"Latency","type":"histogram"}]`
+	recorder, problem := serve(router, http.MethodPost, "/api/v2/metrics", "application/json; charset=utf-8", body)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Empty(t, service.saved)
+
+	type fieldError struct {
+		index int
+		field string
+		rule  string
+	}
+	var actual []fieldError
+	for _, e := range problem.Errors {
+		actual = append(actual, fieldError{index: *e.Index, field: e.Field, rule: e.Rule})
+	}
+	assert.ElementsMatch(t, []fieldError{
+		{index: 1, field: "id", rule: "required"},
+		{index: 1, field: "delta", rule: "required_if"},
+		{index: 1, field: "value", rule: "excluded_unless"},
+		{index: 2, field: "type", rule: "oneof"},
+	}, actual)
+
+	recorder, _ = serve(router, http.MethodPost, "/api/v2/metrics", "application/json", `[{"id":"Alloc","type":"gauge","value":0}]`)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	if assert.Len(t, service.saved, 1) {
+		assert.Equal(t, 0.0, *service.saved[0].Value)
+	}
+}
+
+func TestRouter_SaveMetricsSignature(t *testing.T) {
+	service := &stubService{}
+	router := newTestRouter(server.Config{HashKey: "key"}, service)
+	body := `[{"id":"PollCount","type":"counter","delta":5}]`
+
+	recorder, problem := serve(router, http.MethodPost, "/api/v2/metrics", "application/json", body)
+	assert.Equal(t, http.StatusUnauthorized, recorder.Code)
+	assert.Equal(t, CodeInvalidSignature, problem.Code)
+
+	delta := int64(5)
+	payload, err := signing.MetricsPayload([]*metrics2.Metric{common.MetricRequestToProto(common.MetricRequestDto{ID: "PollCount", MType: common.Counter, Delta: &delta})})
+	assert.NoError(t, err)
+	request := httptest.NewRequest(http.MethodPost, "/api/v2/metrics", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	signing.Sign("key", payload).SetHeaders(request.Header)
+	recorder = httptest.NewRecorder()
+	router.ServeHTTP(recorder, request)
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Len(t, service.saved, 1)
+}
+
+func TestRouter_SaveMetricsSignatureIsVerifiedFirst(t *testing.T) {
+	service := &stubService{}
+	router := newTestRouter(server.Config{HashKey: "key"}, service)
+
+	for _, body := range []string{`[]`, `[{"id":"PollCount","type":"histogram"}]`} {
+		recorder, problem := serve(router, http.MethodPost, "/api/v2/metrics", "application/json", body)
+		assert.Equal(t, http.StatusUnauthorized, recorder.Code, body)
+		assert.Equal(t, CodeInvalidSignature, problem.Code, body)
+	}
+	assert.Empty(t, service.saved)
+}
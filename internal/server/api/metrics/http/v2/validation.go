@@ -0,0 +1,72 @@
+package v2
+
+import (
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/go-playground/validator/v10"
+	"reflect"
+	"strings"
+)
+
+// metricRequest is metric of v2 API. Value of other metric type is not allowed
+type metricRequest struct {
+	ID    string            `json:"id" validate:"required,max=255"`
+	MType common.MetricType `json:"type" validate:"required,oneof=gauge counter"`
+	Delta *int64            `json:"delta,omitempty" validate:"required_if=MType counter,excluded_unless=MType counter"`
+	Value *float64          `json:"value,omitempty" validate:"required_if=MType gauge,excluded_unless=MType gauge"`
+}
+
+func (r metricRequest) toDto() common.MetricRequestDto {
+	return common.MetricRequestDto{ID: r.ID, MType: r.MType, Delta: r.Delta, Value: r.Value}
+}
+
+// newValidator reports fields by their JSON names
+func newValidator() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	v.RegisterTagNameFunc(func(field reflect.StructField) string {
+		name, _, _ := strings.Cut(field.Tag.Get("json"), ",")
+		if name == "-" {
+			return ""
+		}
+		return name
+	})
+	return v
+}
+
+// validateMetrics validates every metric of batch and returns errors of all of them
+func validateMetrics(v *validator.Validate, metrics []metricRequest) []FieldError {
+	var result []FieldError
+	for i, metric := range metrics {
+		err := v.Struct(metric)
+		var validationErrors validator.ValidationErrors
+		if !errors.As(err, &validationErrors) {
+			continue
+		}
+		for _, fieldError := range validationErrors {
+			index := i
+			result = append(result, FieldError{
+				Index:   &index,
+				Field:   fieldError.Field(),
+				Rule:    fieldError.Tag(),
+				Message: fieldMessage(fieldError),
+			})
+		}
+	}
+	return result
+}
+
+func fieldMessage(fieldError validator.FieldError) string {
+	switch fieldError.Tag() {
+	case "required", "required_if":
+		return fmt.Sprintf("%s is required", fieldError.Field())
+	case "excluded_unless":
+		return fmt.Sprintf("%s is not allowed for this type", fieldError.Field())
+	case "oneof":
+		return fmt.Sprintf("%s should be one of: %s", fieldError.Field(), strings.ReplaceAll(fieldError.Param(), " ", ", "))
+	case "max":
+		return fmt.Sprintf("%s should be at most %s characters long", fieldError.Field(), fieldError.Param())
+	default:
+		return fmt.Sprintf("%s is invalid", fieldError.Field())
+	}
+}
@@ -0,0 +1,79 @@
+package influx
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/api/metrics"
+	"go.uber.org/zap"
+	"net/http"
+)
+
+// Write metrics in InfluxDB line protocol handler. Integer fields are cumulative totals,
+// handler keeps last total of every series to save increments to counters
+func NewWriteHandler(service metrics.MetricsService, logger *zap.Logger) http.HandlerFunc {
+	totals := NewTotals()
+	return func(writer http.ResponseWriter, request *http.Request) {
+		if request.Method != http.MethodPost {
+			http.Error(writer, fmt.Sprintf("Method '%s' is not allowed", request.Method), http.StatusBadRequest)
+			return
+		}
+
+		requestDtoList, err := Parse(request.Body)
+		if err != nil {
+			logger.Error("Error during parse line protocol", zap.Error(err))
+			http.Error(writer, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		if len(requestDtoList) > 0 {
+			revert := totals.Derive(requestDtoList)
+			if _, err := service.SaveMetrics(request.Context(), requestDtoList); err != nil {
+				revert(unsavedIDs(requestDtoList, err))
+
+				var validationError *server.ValidationError
+				var partialError *server.PartialSaveError
+				switch {
+				case errors.As(err, &partialError):
+					// saved metrics are not rolled back, so client should resend only failed ones
+					logger.Error("Metrics were saved partially", zap.Error(err))
+					writer.Header().Set("Content-Type", "application/json")
+					writer.WriteHeader(http.StatusMultiStatus)
+					if err := json.NewEncoder(writer).Encode(partialError); err != nil {
+						logger.Error("Error during write response", zap.Error(err))
+					}
+				case errors.As(err, &validationError):
+					logger.Error("Validation was failed", zap.Error(err))
+					http.Error(writer, err.Error(), http.StatusBadRequest)
+				case errors.Is(err, server.ErrReadOnly):
+					http.Error(writer, err.Error(), http.StatusServiceUnavailable)
+				default:
+					logger.Error("Internal server error", zap.Error(err))
+					http.Error(writer, err.Error(), http.StatusInternalServerError)
+				}
+				return
+			}
+		}
+
+		writer.WriteHeader(http.StatusNoContent)
+	}
+}
+
+func unsavedIDs(requestDtoList []common.MetricRequestDto, err error) []string {
+	var partialError *server.PartialSaveError
+	if errors.As(err, &partialError) {
+		ids := make([]string, 0, len(partialError.Failed))
+		for _, failed := range partialError.Failed {
+			ids = append(ids, failed.ID)
+		}
+		return ids
+	}
+
+	ids := make([]string, 0, len(requestDtoList))
+	for _, metric := range requestDtoList {
+		ids = append(ids, metric.ID)
+	}
+	return ids
+}
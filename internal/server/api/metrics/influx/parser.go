@@ -0,0 +1,194 @@
+package influx
+
+import (
+	"bufio"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// Parse InfluxDB line protocol "measurement,tag=value field=value timestamp".
+// Every field becomes separate metric with name = measurement_field (or measurement for "value" field)
+// and tags as labels. Integer fields are mapped to counters with the cumulative total as delta
+// (see Totals), float and boolean fields to gauges, string fields are skipped.
+// Timestamp is validated but not stored, metric gets the time it's saved.
+// Separators of metric ID (";" and "=") are not allowed in measurement, fields and tags even escaped
+func Parse(reader io.Reader) ([]common.MetricRequestDto, error) {
+	var metrics []common.MetricRequestDto
+
+	scanner := bufio.NewScanner(reader)
+	scanner.Buffer(make([]byte, 0, 64*1024), 1024*1024)
+	lineNumber := 0
+	for scanner.Scan() {
+		lineNumber++
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		lineMetrics, err := parseLine(line)
+		if err != nil {
+			return nil, fmt.Errorf("line %d: %w", lineNumber, err)
+		}
+		metrics = append(metrics, lineMetrics...)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return metrics, nil
+}
+
+func parseLine(line string) ([]common.MetricRequestDto, error) {
+	sections := splitUnescaped(line, ' ', true)
+	if len(sections) != 2 && len(sections) != 3 {
+		return nil, fmt.Errorf("expected 'measurement[,tags] fields [timestamp]'")
+	}
+
+	if len(sections) == 3 {
+		if _, err := strconv.ParseInt(sections[2], 10, 64); err != nil {
+			return nil, fmt.Errorf("incorrect timestamp = %s", sections[2])
+		}
+	}
+
+	series := splitUnescaped(sections[0], ',', false)
+	measurement := unescape(series[0])
+	if measurement == "" {
+		return nil, fmt.Errorf("measurement should be filled")
+	}
+
+	labels := make(map[string]string, len(series)-1)
+	for _, tag := range series[1:] {
+		parts := splitUnescaped(tag, '=', false)
+		if len(parts) != 2 || parts[0] == "" {
+			return nil, fmt.Errorf("incorrect tag = %s", tag)
+		}
+		labels[unescape(parts[0])] = unescape(parts[1])
+	}
+
+	var metrics []common.MetricRequestDto
+	for _, field := range splitUnescaped(sections[1], ',', true) {
+		key, rawValue, found := cutUnescaped(field, '=')
+		if !found || key == "" || rawValue == "" {
+			return nil, fmt.Errorf("incorrect field = %s", field)
+		}
+
+		name := measurement
+		if fieldKey := unescape(key); fieldKey != "value" {
+			name = measurement + "_" + fieldKey
+		}
+		if err := common.ValidateLabels(name, labels); err != nil {
+			return nil, err
+		}
+		id := common.JoinLabels(name, labels)
+
+		metric, err := parseFieldValue(id, rawValue)
+		if err != nil {
+			return nil, fmt.Errorf("field %s: %w", key, err)
+		}
+		if metric != nil {
+			metrics = append(metrics, *metric)
+		}
+	}
+	return metrics, nil
+}
+
+func parseFieldValue(id string, rawValue string) (*common.MetricRequestDto, error) {
+	if strings.HasPrefix(rawValue, "\"") {
+		return nil, nil
+	}
+
+	switch rawValue {
+	case "t", "T", "true", "True", "TRUE":
+		value := float64(1)
+		return &common.MetricRequestDto{ID: id, MType: common.Gauge, Value: &value}, nil
+	case "f", "F", "false", "False", "FALSE":
+		value := float64(0)
+		return &common.MetricRequestDto{ID: id, MType: common.Gauge, Value: &value}, nil
+	}
+
+	switch rawValue[len(rawValue)-1] {
+	case 'i':
+		delta, err := strconv.ParseInt(rawValue[:len(rawValue)-1], 10, 64)
+		if err != nil {
+			return nil, fmt.Errorf("incorrect integer value = %s", rawValue)
+		}
+		return &common.MetricRequestDto{ID: id, MType: common.Counter, Delta: &delta}, nil
+	case 'u':
+		unsigned, err := strconv.ParseUint(rawValue[:len(rawValue)-1], 10, 64)
+		if err != nil || unsigned > math.MaxInt64 {
+			return nil, fmt.Errorf("incorrect unsigned integer value = %s", rawValue)
+		}
+		delta := int64(unsigned)
+		return &common.MetricRequestDto{ID: id, MType: common.Counter, Delta: &delta}, nil
+	}
+
+	value, err := strconv.ParseFloat(rawValue, 64)
+	if err != nil {
+		return nil, fmt.Errorf("incorrect float value = %s", rawValue)
+	}
+	return &common.MetricRequestDto{ID: id, MType: common.Gauge, Value: &value}, nil
+}
+
+// splitUnescaped splits string by separator which is not escaped with backslash
+// and (optionally) is not placed inside double-quoted string. Empty parts are skipped for space separator
+func splitUnescaped(s string, separator byte, respectQuotes bool) []string {
+	var parts []string
+	start := 0
+	escaped := false
+	quoted := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case respectQuotes && s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == separator:
+			parts = appendPart(parts, s[start:i], separator)
+			start = i + 1
+		}
+	}
+	return appendPart(parts, s[start:], separator)
+}
+
+func appendPart(parts []string, part string, separator byte) []string {
+	if separator == ' ' && part == "" {
+		return parts
+	}
+	return append(parts, part)
+}
+
+func cutUnescaped(s string, separator byte) (string, string, bool) {
+	escaped := false
+	for i := 0; i < len(s); i++ {
+		switch {
+		case escaped:
+			escaped = false
+		case s[i] == '\\':
+			escaped = true
+		case s[i] == separator:
+			return s[:i], s[i+1:], true
+		}
+	}
+	return s, "", false
+}
+
+func unescape(s string) string {
+	if !strings.Contains(s, "\\") {
+		return s
+	}
+
+	var builder strings.Builder
+	for i := 0; i < len(s); i++ {
+		if s[i] == '\\' && i+1 < len(s) && strings.IndexByte(", =\"\\", s[i+1]) >= 0 {
+			i++
+		}
+		builder.WriteByte(s[i])
+	}
+	return builder.String()
+}
@@ -0,0 +1,58 @@
+package influx
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/stretchr/testify/assert"
+	"strings"
+	"testing"
+)
+
+func TestParse(t *testing.T) {
+	body := `# comment
+cpu,host=server01,region=us-west usage_idle=64.5,usage_user=2i 1434055562000000000
+mem value=1024u
+disk\ io,path=/data\,1 ok=true,label="some text, with comma"
+`
+	metrics, err := Parse(strings.NewReader(body))
+	assert.NoError(t, err)
+	assert.Len(t, metrics, 4)
+
+	assert.Equal(t, "cpu_usage_idle;host=server01;region=us-west", metrics[0].ID)
+	assert.Equal(t, common.Gauge, metrics[0].MType)
+	assert.Equal(t, 64.5, *metrics[0].Value)
+
+	assert.Equal(t, "cpu_usage_user;host=server01;region=us-west", metrics[1].ID)
+	assert.Equal(t, common.Counter, metrics[1].MType)
+	assert.Equal(t, int64(2), *metrics[1].Delta)
+
+	assert.Equal(t, "mem", metrics[2].ID)
+	assert.Equal(t, common.Counter, metrics[2].MType)
+	assert.Equal(t, int64(1024), *metrics[2].Delta)
+
+	assert.Equal(t, "disk io_ok;path=/data,1", metrics[3].ID)
+	assert.Equal(t, float64(1), *metrics[3].Value)
+}
+
+func TestParse_InvalidLines(t *testing.T) {
+	tests := []struct {
+		name string
+		body string
+	}{
+		{name: "without fields", body: "cpu"},
+		{name: "incorrect field", body: "cpu usage"},
+		{name: "incorrect integer", body: "cpu usage=1.5i"},
+		{name: "unsigned out of range", body: "cpu usage=18446744073709551615u"},
+		{name: "incorrect timestamp", body: "cpu usage=1 now"},
+		{name: "incorrect tag", body: "cpu,host usage=1"},
+		{name: "escaped separator in tag key", body: `cpu,ho\=st=a usage=1`},
+		{name: "separator in tag value", body: "cpu,host=a;b usage=1"},
+		{name: "separator in measurement", body: "cpu;host=a usage=1"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Parse(strings.NewReader(tt.body))
+			assert.Error(t, err)
+		})
+	}
+}
@@ -0,0 +1,72 @@
+package influx
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"sync"
+)
+
+// Totals converts cumulative totals of Influx integer fields into counter deltas.
+// Telegraf reports integers as totals since the source start, so adding them to counter as is
+// would count every report again
+type Totals struct {
+	mu   sync.Mutex
+	last map[string]int64
+}
+
+type previousTotal struct {
+	total   int64
+	found   bool
+	current int64
+}
+
+func NewTotals() *Totals {
+	return &Totals{last: make(map[string]int64)}
+}
+
+// Derive replaces totals of counters with increment since previous total of the same series.
+// First total of series is taken as is, so counter is equal to the total reported by single source.
+// Total lower than previous one means that source was restarted and is taken as is too.
+// Returned function reverts totals of metrics with given IDs when they were not saved
+func (t *Totals) Derive(metrics []common.MetricRequestDto) func(ids []string) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	previous := make(map[string]*previousTotal)
+	for i, metric := range metrics {
+		if metric.MType != common.Counter || metric.Delta == nil {
+			continue
+		}
+
+		total := *metric.Delta
+		last, found := t.last[metric.ID]
+		if _, ok := previous[metric.ID]; !ok {
+			previous[metric.ID] = &previousTotal{total: last, found: found}
+		}
+		previous[metric.ID].current = total
+		t.last[metric.ID] = total
+
+		delta := total
+		if found && total >= last {
+			delta = total - last
+		}
+		metrics[i].Delta = &delta
+	}
+
+	return func(ids []string) {
+		t.mu.Lock()
+		defer t.mu.Unlock()
+
+		for _, id := range ids {
+			state, ok := previous[id]
+			if !ok || t.last[id] != state.current {
+				// total was changed by another request after this one
+				continue
+			}
+			if state.found {
+				t.last[id] = state.total
+			} else {
+				delete(t.last, id)
+			}
+		}
+	}
+}
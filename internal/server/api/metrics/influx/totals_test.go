@@ -0,0 +1,39 @@
+package influx
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func counter(id string, total int64) common.MetricRequestDto {
+	return common.MetricRequestDto{ID: id, MType: common.Counter, Delta: &total}
+}
+
+func TestTotals_Derive(t *testing.T) {
+	totals := NewTotals()
+
+	metrics := []common.MetricRequestDto{counter("requests", 10), counter("errors", 2)}
+	totals.Derive(metrics)
+	assert.Equal(t, int64(10), *metrics[0].Delta)
+	assert.Equal(t, int64(2), *metrics[1].Delta)
+
+	metrics = []common.MetricRequestDto{counter("requests", 15), counter("requests", 18), counter("errors", 1)}
+	totals.Derive(metrics)
+	assert.Equal(t, int64(5), *metrics[0].Delta)
+	assert.Equal(t, int64(3), *metrics[1].Delta)
+	assert.Equal(t, int64(1), *metrics[2].Delta, "source restart")
+}
+
+func TestTotals_Revert(t *testing.T) {
+	totals := NewTotals()
+	totals.Derive([]common.MetricRequestDto{counter("requests", 10)})
+
+	revert := totals.Derive([]common.MetricRequestDto{counter("requests", 15), counter("errors", 2)})
+	revert([]string{"requests", "errors"})
+
+	metrics := []common.MetricRequestDto{counter("requests", 15), counter("errors", 2)}
+	totals.Derive(metrics)
+	assert.Equal(t, int64(5), *metrics[0].Delta)
+	assert.Equal(t, int64(2), *metrics[1].Delta)
+}
@@ -62,7 +62,7 @@ func CompressingMiddleware() func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
 			acceptEncoding := request.Header.Get("Accept-Encoding")
-			if strings.Contains(acceptEncoding, "gzip") {
+			if strings.Contains(acceptEncoding, "gzip") && request.Header.Get("Upgrade") == "" {
 				writer.Header().Set("Content-Encoding", "gzip")
 				gzipWriter := gzip.NewWriter(writer)
 				defer gzipWriter.Close()
@@ -84,6 +84,15 @@ func (w *gzipResponseWriter) Write(b []byte) (int, error) {
 	return w.Writer.Write(b)
 }
 
+func (w *gzipResponseWriter) Flush() {
+	if flusher, ok := w.Writer.(interface{ Flush() error }); ok {
+		flusher.Flush()
+	}
+	if flusher, ok := w.ResponseWriter.(http.Flusher); ok {
+		flusher.Flush()
+	}
+}
+
 func TrustedSubnetMiddleware(trustedSubnet string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
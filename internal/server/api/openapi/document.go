@@ -0,0 +1,198 @@
+package openapi
+
+import (
+	_ "embed"
+	"encoding/json"
+	"fmt"
+	"go.uber.org/zap"
+	"net/http"
+	"sort"
+	"strings"
+)
+
+// Path of specification served by server
+const SpecPath = "/openapi.json"
+
+//go:embed openapi.json
+var spec []byte
+
+// Order of methods in path item, it's also order of generated client methods
+var methods = []string{http.MethodGet, http.MethodPost, http.MethodPut, http.MethodPatch, http.MethodDelete}
+
+// Document is subset of OpenAPI 3 document used by server: references are supported
+// for parameters, responses and schemas of components only
+type Document struct {
+	OpenAPI    string              `json:"openapi"`
+	Info       Info                `json:"info"`
+	Paths      map[string]PathItem `json:"paths"`
+	Components Components          `json:"components"`
+}
+
+type Info struct {
+	Title       string `json:"title"`
+	Description string `json:"description,omitempty"`
+	Version     string `json:"version"`
+}
+
+// PathItem maps lower case HTTP method to operation
+type PathItem map[string]*Operation
+
+type Operation struct {
+	OperationID string               `json:"operationId"`
+	Summary     string               `json:"summary,omitempty"`
+	Parameters  []*Parameter         `json:"parameters,omitempty"`
+	RequestBody *RequestBody         `json:"requestBody,omitempty"`
+	Responses   map[string]*Response `json:"responses"`
+}
+
+type Parameter struct {
+	Ref         string  `json:"$ref,omitempty"`
+	Name        string  `json:"name,omitempty"`
+	In          string  `json:"in,omitempty"`
+	Description string  `json:"description,omitempty"`
+	Required    bool    `json:"required,omitempty"`
+	Schema      *Schema `json:"schema,omitempty"`
+}
+
+// RequestBody is validated by JSON schema of its media type. ValidationStatus is status of response
+// when body doesn't match schema, 400 is used when it's not set
+// RequestBody of signed request (request with signature header) is validated by handler after signature is verified
+type RequestBody struct {
+	Required         bool                 `json:"required,omitempty"`
+	Content          map[string]MediaType `json:"content"`
+	ValidationStatus int                  `json:"x-validation-status,omitempty"`
+	SignatureHeader  string               `json:"x-signature-header,omitempty"`
+}
+
+// MediaType without schema is not described by specification, for example protobuf body
+type MediaType struct {
+	Schema *Schema `json:"schema,omitempty"`
+}
+
+type Response struct {
+	Ref         string               `json:"$ref,omitempty"`
+	Description string               `json:"description,omitempty"`
+	Content     map[string]MediaType `json:"content,omitempty"`
+}
+
+type Components struct {
+	Parameters map[string]*Parameter `json:"parameters,omitempty"`
+	Responses  map[string]*Response  `json:"responses,omitempty"`
+	Schemas    map[string]*Schema    `json:"schemas,omitempty"`
+}
+
+type Schema struct {
+	Ref                  string                `json:"$ref,omitempty"`
+	Type                 string                `json:"type,omitempty"`
+	Format               string                `json:"format,omitempty"`
+	Description          string                `json:"description,omitempty"`
+	Enum                 []string              `json:"enum,omitempty"`
+	Required             []string              `json:"required,omitempty"`
+	Properties           map[string]*Schema    `json:"properties,omitempty"`
+	AdditionalProperties *AdditionalProperties `json:"additionalProperties,omitempty"`
+	Items                *Schema               `json:"items,omitempty"`
+	MinLength            *int                  `json:"minLength,omitempty"`
+	MaxLength            *int                  `json:"maxLength,omitempty"`
+	MinItems             *int                  `json:"minItems,omitempty"`
+	Minimum              *float64              `json:"minimum,omitempty"`
+}
+
+// AdditionalProperties is either boolean or schema of properties which are not listed in schema
+type AdditionalProperties struct {
+	Allowed bool
+	Schema  *Schema
+}
+
+func (a *AdditionalProperties) UnmarshalJSON(data []byte) error {
+	if err := json.Unmarshal(data, &a.Allowed); err == nil {
+		return nil
+	}
+	a.Allowed = true
+	return json.Unmarshal(data, &a.Schema)
+}
+
+// Route is operation with its method and path template
+type Route struct {
+	Method    string
+	Path      string
+	Operation *Operation
+}
+
+// Spec returns specification served by server
+func Spec() []byte {
+	return spec
+}
+
+// Load parses specification and inlines references to parameters and responses of components
+func Load() (*Document, error) {
+	var document Document
+	if err := json.Unmarshal(spec, &document); err != nil {
+		return nil, fmt.Errorf("error during parse OpenAPI specification: %w", err)
+	}
+
+	for _, route := range document.Routes() {
+		for i, parameter := range route.Operation.Parameters {
+			if parameter.Ref == "" {
+				continue
+			}
+			resolved, ok := document.Components.Parameters[strings.TrimPrefix(parameter.Ref, "#/components/parameters/")]
+			if !ok {
+				return nil, fmt.Errorf("%s %s: unknown parameter %s", route.Method, route.Path, parameter.Ref)
+			}
+			route.Operation.Parameters[i] = resolved
+		}
+		for status, response := range route.Operation.Responses {
+			if response.Ref == "" {
+				continue
+			}
+			resolved, ok := document.Components.Responses[strings.TrimPrefix(response.Ref, "#/components/responses/")]
+			if !ok {
+				return nil, fmt.Errorf("%s %s: unknown response %s", route.Method, route.Path, response.Ref)
+			}
+			route.Operation.Responses[status] = resolved
+		}
+	}
+	return &document, nil
+}
+
+// Routes returns all operations ordered by path and method
+func (d *Document) Routes() []Route {
+	paths := make([]string, 0, len(d.Paths))
+	for path := range d.Paths {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var routes []Route
+	for _, path := range paths {
+		for _, method := range methods {
+			if operation, ok := d.Paths[path][strings.ToLower(method)]; ok {
+				routes = append(routes, Route{Method: method, Path: path, Operation: operation})
+			}
+		}
+	}
+	return routes
+}
+
+// Resolve returns schema of component when schema is reference
+func (d *Document) Resolve(schema *Schema) *Schema {
+	for schema != nil && schema.Ref != "" {
+		schema = d.Components.Schemas[RefName(schema.Ref)]
+	}
+	return schema
+}
+
+// RefName is name of component schema in reference
+func RefName(ref string) string {
+	return strings.TrimPrefix(ref, "#/components/schemas/")
+}
+
+// Specification handler
+func NewSpecHandler(logger *zap.Logger) http.HandlerFunc {
+	return func(writer http.ResponseWriter, request *http.Request) {
+		writer.Header().Set("Content-Type", "application/json")
+		if _, err := writer.Write(spec); err != nil {
+			logger.Error("Error during write OpenAPI specification", zap.Error(err))
+		}
+	}
+}
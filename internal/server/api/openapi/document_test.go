@@ -0,0 +1,88 @@
+package openapi
+
+import (
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"testing"
+)
+
+func TestLoad(t *testing.T) {
+	document, err := Load()
+	require.NoError(t, err)
+
+	operations := map[string]bool{}
+	for _, route := range document.Routes() {
+		assert.NotEmpty(t, route.Operation.OperationID, "%s %s", route.Method, route.Path)
+		assert.False(t, operations[route.Operation.OperationID], "duplicated operation %s", route.Operation.OperationID)
+		operations[route.Operation.OperationID] = true
+
+		for _, parameter := range route.Operation.Parameters {
+			assert.Empty(t, parameter.Ref, "%s %s", route.Method, route.Path)
+			assert.NotNil(t, document.Resolve(parameter.Schema), "%s %s: %s", route.Method, route.Path, parameter.Name)
+		}
+		for status, response := range route.Operation.Responses {
+			assert.Empty(t, response.Ref, "%s %s: %s", route.Method, route.Path, status)
+		}
+	}
+
+	var checkRefs func(schema *Schema)
+	checkRefs = func(schema *Schema) {
+		if schema == nil {
+			return
+		}
+		if schema.Ref != "" {
+			assert.NotNil(t, document.Resolve(schema), schema.Ref)
+			return
+		}
+		for _, property := range schema.Properties {
+			checkRefs(property)
+		}
+		checkRefs(schema.Items)
+		if schema.AdditionalProperties != nil {
+			checkRefs(schema.AdditionalProperties.Schema)
+		}
+	}
+	for _, schema := range document.Components.Schemas {
+		checkRefs(schema)
+	}
+}
+
+func TestNewSpecHandler(t *testing.T) {
+	recorder := httptest.NewRecorder()
+	NewSpecHandler(zap.NewNop())(recorder, httptest.NewRequest(http.MethodGet, SpecPath, nil))
+
+	assert.Equal(t, http.StatusOK, recorder.Code)
+	assert.Equal(t, "application/json", recorder.Header().Get("Content-Type"))
+	assert.JSONEq(t, string(Spec()), recorder.Body.String())
+}
+
+func TestGenerateClient_UpToDate(t *testing.T) {
+	document, err := Load()
+	require.NoError(t, err)
+
+	source, err := GenerateClient(document, "client")
+	require.NoError(t, err)
+	generated, err := os.ReadFile("../../../../pkg/client/client.gen.go")
+	require.NoError(t, err)
+	assert.Equal(t, string(source), string(generated), "client is outdated, run go generate ./pkg/client")
+}
+
+func TestGoName(t *testing.T) {
+	tests := map[string]string{
+		"last_applied_at":       "LastAppliedAt",
+		"request_id":            "RequestID",
+		"findAllSLOs":           "FindAllSLOs",
+		"getOpenAPI":            "GetOpenAPI",
+		"createMetricFromJSON":  "CreateMetricFromJSON",
+		"X-Signature-Timestamp": "XSignatureTimestamp",
+		"Last-Event-ID":         "LastEventID",
+		"HashSHA256":            "HashSHA256",
+	}
+	for name, expected := range tests {
+		assert.Equal(t, expected, goName(name))
+	}
+}
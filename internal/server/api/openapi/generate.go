@@ -0,0 +1,435 @@
+package openapi
+
+import (
+	"fmt"
+	"go/format"
+	"go/token"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+// Words which are written in upper case in Go names
+var initialisms = map[string]bool{"api": true, "http": true, "id": true, "ip": true, "json": true, "slo": true, "slos": true, "url": true}
+
+// GenerateClient generates Go source of typed client: types of component schemas and method of
+// client for every operation. Generated code is based on Client of package with handwritten transport
+func GenerateClient(document *Document, packageName string) ([]byte, error) {
+	g := &generator{document: document, imports: map[string]bool{"context": true, "net/http": true}}
+
+	names := make([]string, 0, len(document.Components.Schemas))
+	for name := range document.Components.Schemas {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		g.schemaType(name, document.Components.Schemas[name])
+	}
+	for _, route := range document.Routes() {
+		g.operation(route)
+	}
+	if g.err != nil {
+		return nil, g.err
+	}
+
+	var source strings.Builder
+	source.WriteString("// Code generated by openapi-client-gen. DO NOT EDIT.\n\n")
+	fmt.Fprintf(&source, "package %s\n\nimport (\n", packageName)
+	imports := make([]string, 0, len(g.imports))
+	for path := range g.imports {
+		imports = append(imports, path)
+	}
+	sort.Strings(imports)
+	for _, path := range imports {
+		fmt.Fprintf(&source, "%q\n", path)
+	}
+	source.WriteString(")\n")
+	source.WriteString(g.body.String())
+
+	formatted, err := format.Source([]byte(source.String()))
+	if err != nil {
+		return nil, fmt.Errorf("error during format generated client: %w", err)
+	}
+	return formatted, nil
+}
+
+type generator struct {
+	document *Document
+	imports  map[string]bool
+	body     strings.Builder
+	err      error
+}
+
+func (g *generator) printf(format string, args ...any) {
+	fmt.Fprintf(&g.body, format, args...)
+}
+
+func (g *generator) fail(format string, args ...any) {
+	if g.err == nil {
+		g.err = fmt.Errorf(format, args...)
+	}
+}
+
+func (g *generator) schemaType(name string, schema *Schema) {
+	typeName := goName(name)
+	g.printf("\n")
+	g.comment(typeName+" schema", schema.Description)
+
+	switch {
+	case schema.Type == "string" && len(schema.Enum) > 0:
+		g.printf("type %s string\n\nconst (\n", typeName)
+		for _, value := range schema.Enum {
+			g.printf("%s%s %s = %q\n", typeName, goName(value), typeName, value)
+		}
+		g.printf(")\n")
+	case schema.Type == "object" && len(schema.Properties) > 0:
+		required := map[string]bool{}
+		for _, property := range schema.Required {
+			required[property] = true
+		}
+		properties := make([]string, 0, len(schema.Properties))
+		for property := range schema.Properties {
+			properties = append(properties, property)
+		}
+		sort.Strings(properties)
+
+		g.printf("type %s struct {\n", typeName)
+		for _, property := range properties {
+			fieldType := g.goType(schema.Properties[property])
+			tag := property
+			if !required[property] {
+				fieldType = optional(fieldType)
+				tag += ",omitempty"
+			}
+			if description := schema.Properties[property].Description; description != "" {
+				g.printf("// %s\n", description)
+			}
+			g.printf("%s %s `json:%q`\n", goName(property), fieldType, tag)
+		}
+		g.printf("}\n")
+	default:
+		g.printf("type %s = %s\n", typeName, g.goType(schema))
+	}
+}
+
+func (g *generator) operation(route Route) {
+	operation := route.Operation
+	if operation.OperationID == "" {
+		g.fail("%s %s: operationId is required", route.Method, route.Path)
+		return
+	}
+	name := goName(operation.OperationID)
+
+	var pathParams, otherParams []*Parameter
+	for _, parameter := range operation.Parameters {
+		if parameter.In == "path" {
+			pathParams = append(pathParams, parameter)
+		} else {
+			otherParams = append(otherParams, parameter)
+		}
+	}
+
+	args := []string{"ctx context.Context"}
+	for _, parameter := range pathParams {
+		args = append(args, fmt.Sprintf("%s %s", argName(parameter), g.goType(parameter.Schema)))
+	}
+	bodyType, bodyMedia := g.requestBody(route)
+	if bodyType != "" {
+		args = append(args, "body "+bodyType)
+	}
+	if len(otherParams) > 0 {
+		g.printf("\n// %sParams is query and header parameters of %s\ntype %sParams struct {\n", name, name, name)
+		for _, parameter := range otherParams {
+			if parameter.Description != "" {
+				g.printf("// %s\n", parameter.Description)
+			}
+			fieldType := g.goType(parameter.Schema)
+			if !parameter.Required {
+				fieldType = optional(fieldType)
+			}
+			g.printf("%s %s\n", goName(parameter.Name), fieldType)
+		}
+		g.printf("}\n")
+		args = append(args, fmt.Sprintf("params *%sParams", name))
+	}
+
+	result, resultMedia := g.response(route)
+	results := "error"
+	if result != "" {
+		results = fmt.Sprintf("(%s, error)", result)
+	}
+
+	g.printf("\n")
+	g.comment(fmt.Sprintf("%s calls %s %s", name, route.Method, route.Path), operation.Summary)
+	g.printf("func (c *Client) %s(%s) %s {\n", name, strings.Join(args, ", "), results)
+	g.printf("req := newRequest(%s, %s)\n", methodConstant(route.Method), g.pathExpression(route.Path, pathParams))
+	if bodyType != "" {
+		g.printf("req.contentType = %q\nreq.body = body\n", bodyMedia)
+	}
+	if len(otherParams) > 0 {
+		g.printf("if params != nil {\n")
+		for _, parameter := range otherParams {
+			field := "params." + goName(parameter.Name)
+			target := "req.query"
+			if parameter.In == "header" {
+				target = "req.header"
+			}
+			if parameter.Required {
+				g.printf("%s.Set(%q, %s)\n", target, parameter.Name, g.formatValue(parameter.Schema, field))
+				continue
+			}
+			g.printf("if %s != nil {\n%s.Set(%q, %s)\n}\n", field, target, parameter.Name, g.formatValue(parameter.Schema, "*"+field))
+		}
+		g.printf("}\n")
+	}
+
+	switch {
+	case result == "":
+		g.printf("return discard(c.do(ctx, req))\n")
+	case resultMedia == "application/json":
+		// Accept header is not set: some operations respond with other representation when it's set
+		g.printf("return decodeJSON[%s](c.do(ctx, req))\n", result)
+	default:
+		g.printf("req.header.Set(\"Accept\", %q)\nreturn c.do(ctx, req)\n", resultMedia)
+	}
+	g.printf("}\n")
+}
+
+// requestBody is type of body argument: JSON body is typed by its schema and plain text is string.
+// Other media types are not supported by client
+func (g *generator) requestBody(route Route) (string, string) {
+	body := route.Operation.RequestBody
+	if body == nil {
+		return "", ""
+	}
+	if media, ok := body.Content["application/json"]; ok && media.Schema != nil {
+		return g.goType(media.Schema), "application/json"
+	}
+	if _, ok := body.Content["text/plain"]; ok {
+		return "string", "text/plain"
+	}
+	g.fail("%s %s: request body has no JSON or plain text content", route.Method, route.Path)
+	return "", ""
+}
+
+// response is result type of first successful response. Object is returned by pointer and
+// event stream is returned as response, it should be closed by caller
+func (g *generator) response(route Route) (string, string) {
+	statuses := make([]string, 0, len(route.Operation.Responses))
+	for status := range route.Operation.Responses {
+		if strings.HasPrefix(status, "2") {
+			statuses = append(statuses, status)
+		}
+	}
+	if len(statuses) == 0 {
+		g.fail("%s %s: successful response is not described", route.Method, route.Path)
+		return "", ""
+	}
+	sort.Strings(statuses)
+
+	response := route.Operation.Responses[statuses[0]]
+	if len(response.Content) == 0 {
+		return "", ""
+	}
+	if media, ok := response.Content["application/json"]; ok && media.Schema != nil {
+		result := g.goType(media.Schema)
+		if resolved := g.document.Resolve(media.Schema); media.Schema.Ref != "" && resolved != nil && resolved.Type == "object" {
+			result = "*" + result
+		}
+		return result, "application/json"
+	}
+	if _, ok := response.Content["text/event-stream"]; ok {
+		return "*http.Response", "text/event-stream"
+	}
+	g.fail("%s %s: response %s has no JSON or event stream content", route.Method, route.Path, statuses[0])
+	return "", ""
+}
+
+func (g *generator) goType(schema *Schema) string {
+	if schema == nil {
+		return "any"
+	}
+	if schema.Ref != "" {
+		if g.document.Resolve(schema) == nil {
+			g.fail("unknown schema %s", schema.Ref)
+		}
+		return goName(RefName(schema.Ref))
+	}
+
+	switch schema.Type {
+	case "string":
+		if schema.Format == "date-time" {
+			g.imports["time"] = true
+			return "time.Time"
+		}
+		return "string"
+	case "integer":
+		switch schema.Format {
+		case "int32", "int64", "uint64":
+			return schema.Format
+		}
+		return "int"
+	case "number":
+		if schema.Format == "float" {
+			return "float32"
+		}
+		return "float64"
+	case "boolean":
+		return "bool"
+	case "array":
+		return "[]" + g.goType(schema.Items)
+	case "object":
+		if additional := schema.AdditionalProperties; additional != nil && additional.Schema != nil {
+			return "map[string]" + g.goType(additional.Schema)
+		}
+		if len(schema.Properties) == 0 {
+			return "map[string]any"
+		}
+		g.fail("inline object schema with properties is not supported, move it to components")
+	}
+	return "any"
+}
+
+// pathExpression builds Go expression of request path with escaped path parameters
+func (g *generator) pathExpression(path string, parameters []*Parameter) string {
+	byName := map[string]*Parameter{}
+	for _, parameter := range parameters {
+		byName[parameter.Name] = parameter
+	}
+
+	var parts []string
+	for path != "" {
+		start := strings.Index(path, "{")
+		if start < 0 {
+			parts = append(parts, strconv.Quote(path))
+			break
+		}
+		end := strings.Index(path[start:], "}") + start
+		if start > 0 {
+			parts = append(parts, strconv.Quote(path[:start]))
+		}
+		parameter, ok := byName[path[start+1:end]]
+		if !ok {
+			g.fail("path parameter %s is not described", path[start+1:end])
+			return `""`
+		}
+		g.imports["net/url"] = true
+		parts = append(parts, fmt.Sprintf("url.PathEscape(%s)", g.formatValue(parameter.Schema, argName(parameter))))
+		path = path[end+1:]
+	}
+	return strings.Join(parts, " + ")
+}
+
+// formatValue converts value of parameter to string
+func (g *generator) formatValue(schema *Schema, value string) string {
+	if g.goType(schema) == "string" {
+		return value
+	}
+	if resolved := g.document.Resolve(schema); resolved != nil && resolved.Type == "string" && len(resolved.Enum) > 0 {
+		return fmt.Sprintf("string(%s)", value)
+	}
+	g.imports["fmt"] = true
+	return fmt.Sprintf("fmt.Sprint(%s)", value)
+}
+
+func (g *generator) comment(title string, description string) {
+	g.printf("// %s\n", title)
+	if description != "" {
+		g.printf("//\n// %s\n", description)
+	}
+}
+
+// optional makes type of value which can be omitted, slices and maps are nil when they are omitted
+func optional(goType string) string {
+	if strings.HasPrefix(goType, "[]") || strings.HasPrefix(goType, "map[") || goType == "any" {
+		return goType
+	}
+	return "*" + goType
+}
+
+// argName is name of argument of path parameter, schema name is used when parameter name is Go keyword
+func argName(parameter *Parameter) string {
+	name := parameter.Name
+	if token.IsKeyword(name) {
+		if parameter.Schema == nil || parameter.Schema.Ref == "" {
+			return name + "Param"
+		}
+		name = RefName(parameter.Schema.Ref)
+	}
+	words := splitWords(name)
+	words[0] = strings.ToLower(words[0])
+	for i := 1; i < len(words); i++ {
+		words[i] = capitalize(words[i])
+	}
+	return strings.Join(words, "")
+}
+
+// goName converts name of specification to exported Go name, for example last_applied_at to LastAppliedAt
+func goName(name string) string {
+	words := splitWords(name)
+	for i, word := range words {
+		words[i] = capitalize(word)
+	}
+	return strings.Join(words, "")
+}
+
+// splitWords splits name by separators and by upper case letter following lower case letter
+func splitWords(name string) []string {
+	var (
+		words []string
+		word  []rune
+	)
+	runes := []rune(name)
+	for i, r := range runes {
+		if !unicode.IsLetter(r) && !unicode.IsDigit(r) {
+			if len(word) > 0 {
+				words = append(words, string(word))
+			}
+			word = nil
+			continue
+		}
+		if unicode.IsUpper(r) && i > 0 && unicode.IsLower(runes[i-1]) && len(word) > 0 {
+			words = append(words, string(word))
+			word = nil
+		}
+		word = append(word, r)
+	}
+	if len(word) > 0 {
+		words = append(words, string(word))
+	}
+	if len(words) == 0 {
+		return []string{"_"}
+	}
+	return words
+}
+
+func capitalize(word string) string {
+	if initialisms[strings.ToLower(word)] {
+		upper := strings.ToUpper(word)
+		if strings.HasSuffix(upper, "S") && len(upper) > 2 {
+			return upper[:len(upper)-1] + "s"
+		}
+		return upper
+	}
+	runes := []rune(word)
+	runes[0] = unicode.ToUpper(runes[0])
+	return string(runes)
+}
+
+func methodConstant(method string) string {
+	switch method {
+	case http.MethodGet:
+		return "http.MethodGet"
+	case http.MethodPost:
+		return "http.MethodPost"
+	case http.MethodPut:
+		return "http.MethodPut"
+	case http.MethodPatch:
+		return "http.MethodPatch"
+	case http.MethodDelete:
+		return "http.MethodDelete"
+	}
+	return strconv.Quote(method)
+}
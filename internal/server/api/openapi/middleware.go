@@ -0,0 +1,243 @@
+package openapi
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	v2 "github.com/desepticon55/metrics-collector/internal/server/api/metrics/http/v2"
+	"go.uber.org/zap"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+const problemContentType = "application/problem+json"
+
+type compiledRoute struct {
+	Route
+	segments []string
+}
+
+// NewValidationMiddleware validates parameters and JSON body of request against operation of document.
+// Requests to paths which are not described by document are passed to next handler as is
+func NewValidationMiddleware(document *Document, logger *zap.Logger) func(http.Handler) http.Handler {
+	var routes []compiledRoute
+	for _, route := range document.Routes() {
+		routes = append(routes, compiledRoute{Route: route, segments: strings.Split(route.Path, "/")})
+	}
+
+	return func(next http.Handler) http.Handler {
+		return http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+			route, pathParams, ok := matchRoute(routes, request)
+			if !ok {
+				next.ServeHTTP(writer, request)
+				return
+			}
+
+			if errs := document.validateParameters(route.Operation, request, pathParams); len(errs) > 0 {
+				logger.Debug("Request parameters are invalid", zap.String("operation", route.Operation.OperationID))
+				writeValidationErrors(writer, request, route.Operation, http.StatusBadRequest, "Request parameters are invalid", errs, false)
+				return
+			}
+
+			body := route.Operation.RequestBody
+			if body == nil || (body.SignatureHeader != "" && request.Header.Get(body.SignatureHeader) != "") {
+				next.ServeHTTP(writer, request)
+				return
+			}
+			schema, ok := body.schema(request.Header.Get("Content-Type"))
+			if !ok {
+				next.ServeHTTP(writer, request)
+				return
+			}
+
+			data, err := io.ReadAll(request.Body)
+			if err != nil {
+				http.Error(writer, "Error during read request body", http.StatusBadRequest)
+				return
+			}
+			request.Body = io.NopCloser(bytes.NewReader(data))
+
+			status := body.ValidationStatus
+			if status == 0 {
+				status = http.StatusBadRequest
+			}
+			if len(bytes.TrimSpace(data)) == 0 {
+				if body.Required {
+					errs := []ValidationError{newError(nil, "required", "request body is required")}
+					writeValidationErrors(writer, request, route.Operation, status, "Request body is invalid", errs, false)
+					return
+				}
+				next.ServeHTTP(writer, request)
+				return
+			}
+
+			decoder := json.NewDecoder(bytes.NewReader(data))
+			decoder.UseNumber()
+			var value any
+			if err := decoder.Decode(&value); err != nil {
+				writeMalformedBody(writer, request, route.Operation, err)
+				return
+			}
+			if errs := document.Validate(schema, value); len(errs) > 0 {
+				logger.Debug("Request body is invalid", zap.String("operation", route.Operation.OperationID))
+				_, isArray := value.([]any)
+				writeValidationErrors(writer, request, route.Operation, status, "Request body is invalid", errs, isArray)
+				return
+			}
+			next.ServeHTTP(writer, request)
+		})
+	}
+}
+
+// matchRoute finds route of request method with path template matching request path.
+// Route with more literal segments wins when several templates match path
+func matchRoute(routes []compiledRoute, request *http.Request) (compiledRoute, map[string]string, bool) {
+	segments := strings.Split(request.URL.EscapedPath(), "/")
+
+	var (
+		matched   compiledRoute
+		params    map[string]string
+		bestScore = -1
+	)
+	for _, route := range routes {
+		if route.Method != request.Method || len(route.segments) != len(segments) {
+			continue
+		}
+
+		score := 0
+		candidate := map[string]string{}
+		for i, segment := range route.segments {
+			if name, ok := strings.CutPrefix(segment, "{"); ok {
+				// empty value is validated against parameter schema by operations responding with problem details (v2 API),
+				// for the rest path doesn't match like in router
+				value, err := url.PathUnescape(segments[i])
+				if err != nil || (value == "" && !respondsWithProblem(route.Operation)) {
+					score = -1
+					break
+				}
+				candidate[strings.TrimSuffix(name, "}")] = value
+				continue
+			}
+			if segment != segments[i] {
+				score = -1
+				break
+			}
+			score++
+		}
+
+		if score > bestScore {
+			matched, params, bestScore = route, candidate, score
+		}
+	}
+	return matched, params, bestScore >= 0
+}
+
+func (d *Document) validateParameters(operation *Operation, request *http.Request, pathParams map[string]string) []ValidationError {
+	query := request.URL.Query()
+
+	var errs []ValidationError
+	for _, parameter := range operation.Parameters {
+		var (
+			value   string
+			present bool
+		)
+		switch parameter.In {
+		case "path":
+			value, present = pathParams[parameter.Name]
+		case "query":
+			_, present = query[parameter.Name]
+			value = query.Get(parameter.Name)
+		case "header":
+			value = request.Header.Get(parameter.Name)
+			present = value != ""
+		}
+
+		path := []string{parameter.Name}
+		if !present {
+			if parameter.Required {
+				errs = append(errs, newError(path, "required", "is required"))
+			}
+			continue
+		}
+		errs = append(errs, d.validate(parameter.Schema, d.parseParameter(parameter.Schema, value), path)...)
+	}
+	return errs
+}
+
+// schema finds schema of JSON body, JSON is expected when content type is not set.
+// Body of other media types or without schema is not validated
+func (b *RequestBody) schema(contentType string) (*Schema, bool) {
+	mediaType := "application/json"
+	if contentType != "" {
+		parsed, _, err := mime.ParseMediaType(contentType)
+		if err != nil {
+			return nil, false
+		}
+		mediaType = parsed
+	}
+
+	if mediaType != "application/json" {
+		return nil, false
+	}
+
+	media, ok := b.Content[mediaType]
+	if !ok || media.Schema == nil {
+		return nil, false
+	}
+	return media.Schema, true
+}
+
+func writeMalformedBody(writer http.ResponseWriter, request *http.Request, operation *Operation, err error) {
+	detail := fmt.Sprintf("Request body is not valid JSON: %v", err)
+	if respondsWithProblem(operation) {
+		v2.WriteProblem(writer, request, http.StatusBadRequest, v2.CodeMalformedRequest, detail)
+		return
+	}
+	http.Error(writer, detail, http.StatusBadRequest)
+}
+
+// writeValidationErrors responds with problem details when operation describes it, otherwise with plain text.
+// First element of path is index of item when body is array
+func writeValidationErrors(writer http.ResponseWriter, request *http.Request, operation *Operation, status int, detail string, errs []ValidationError, indexed bool) {
+	if !respondsWithProblem(operation) {
+		messages := make([]string, 0, len(errs))
+		for _, e := range errs {
+			field := strings.Join(e.Path, ".")
+			if field == "" {
+				messages = append(messages, e.Message)
+				continue
+			}
+			messages = append(messages, fmt.Sprintf("%s %s", field, e.Message))
+		}
+		http.Error(writer, fmt.Sprintf("%s: %s", detail, strings.Join(messages, "; ")), status)
+		return
+	}
+
+	fieldErrors := make([]v2.FieldError, 0, len(errs))
+	for _, e := range errs {
+		path := e.Path
+		fieldError := v2.FieldError{Rule: e.Rule, Message: e.Message}
+		if indexed && len(path) > 0 {
+			if index, err := strconv.Atoi(path[0]); err == nil {
+				fieldError.Index = &index
+				path = path[1:]
+			}
+		}
+		fieldError.Field = strings.Join(path, ".")
+		fieldErrors = append(fieldErrors, fieldError)
+	}
+	v2.WriteProblem(writer, request, status, v2.CodeValidationFailed, detail, fieldErrors...)
+}
+
+func respondsWithProblem(operation *Operation) bool {
+	for _, response := range operation.Responses {
+		if _, ok := response.Content[problemContentType]; ok {
+			return true
+		}
+	}
+	return false
+}
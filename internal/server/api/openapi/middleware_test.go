@@ -0,0 +1,133 @@
+package openapi
+
+import (
+	"encoding/json"
+	v2 "github.com/desepticon55/metrics-collector/internal/server/api/metrics/http/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.uber.org/zap"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestNewValidationMiddleware(t *testing.T) {
+	document, err := Load()
+	require.NoError(t, err)
+
+	var body string
+	handler := NewValidationMiddleware(document, zap.NewNop())(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		data, _ := io.ReadAll(request.Body)
+		body = string(data)
+		writer.WriteHeader(http.StatusOK)
+	}))
+
+	tests := []struct {
+		name        string
+		method      string
+		path        string
+		contentType string
+		body        string
+		header      map[string]string
+		status      int
+		message     string
+	}{
+		{name: "valid path parameters", method: http.MethodGet, path: "/value/gauge/Alloc", status: http.StatusOK},
+		{name: "unknown metric type", method: http.MethodGet, path: "/value/histogram/Alloc", status: http.StatusBadRequest, message: "type should be one of: gauge, counter"},
+		{name: "valid query parameters", method: http.MethodGet, path: "/forecast/gauge/Alloc?method=holt&window=30m&threshold=0.5", status: http.StatusOK},
+		{name: "unknown forecast method", method: http.MethodGet, path: "/forecast/gauge/Alloc?method=arima", status: http.StatusBadRequest, message: "method should be one of: linear, holt"},
+		{name: "invalid duration", method: http.MethodGet, path: "/rate/counter/PollCount?window=minute", status: http.StatusBadRequest, message: "window should be duration"},
+		{name: "invalid number", method: http.MethodGet, path: "/forecast/gauge/Alloc?threshold=high", status: http.StatusBadRequest, message: "threshold should be number"},
+		{name: "invalid header", method: http.MethodGet, path: "/stream", header: map[string]string{"Last-Event-ID": "-1"}, status: http.StatusBadRequest, message: "Last-Event-ID should be uint64"},
+		{name: "valid body", method: http.MethodPost, path: "/update/", contentType: "application/json", body: `{"id":"Alloc","type":"gauge","value":1.5}`, status: http.StatusOK},
+		{name: "body without content type", method: http.MethodPost, path: "/update/", body: `{"id":"Alloc"}`, status: http.StatusBadRequest, message: "type is required"},
+		{name: "invalid body", method: http.MethodPost, path: "/updates/", contentType: "application/json", body: `[{"id":"PollCount","type":"counter","delta":1.5}]`, status: http.StatusBadRequest, message: "0.delta should be integer"},
+		{name: "malformed body", method: http.MethodPost, path: "/silences", contentType: "application/json", body: `{`, status: http.StatusBadRequest, message: "Request body is not valid JSON"},
+		{name: "body without schema", method: http.MethodPost, path: "/updates/", contentType: "application/x-protobuf", body: "\x0a\x00", status: http.StatusOK},
+		{name: "line protocol", method: http.MethodPost, path: "/write", contentType: "text/plain", body: "cpu usage=0.5", status: http.StatusOK},
+		{name: "line protocol with charset", method: http.MethodPost, path: "/write", contentType: "text/plain; charset=utf-8", body: "cpu,host=a usage=0.5,count=3i", status: http.StatusOK},
+		{name: "undescribed path", method: http.MethodGet, path: "/debug/pprof/", status: http.StatusOK},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			body = ""
+			request := httptest.NewRequest(tt.method, tt.path, strings.NewReader(tt.body))
+			if tt.contentType != "" {
+				request.Header.Set("Content-Type", tt.contentType)
+			}
+			for name, value := range tt.header {
+				request.Header.Set(name, value)
+			}
+			recorder := httptest.NewRecorder()
+			handler.ServeHTTP(recorder, request)
+
+			assert.Equal(t, tt.status, recorder.Code)
+			if tt.status == http.StatusOK {
+				assert.Equal(t, tt.body, body, "body should be passed to handler")
+				return
+			}
+			assert.Contains(t, recorder.Body.String(), tt.message)
+		})
+	}
+}
+
+func TestNewValidationMiddleware_Problem(t *testing.T) {
+	document, err := Load()
+	require.NoError(t, err)
+
+	called := false
+	handler := NewValidationMiddleware(document, zap.NewNop())(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		called = true
+	}))
+
+	body := `[{"id":"Alloc","type":"gauge","value":1},{"id":"","type":"histogram","value":"1","labels":{}}]`
+	request := httptest.NewRequest(http.MethodPost, "/api/v2/metrics", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	recorder := httptest.NewRecorder()
+	handler.ServeHTTP(recorder, request)
+
+	assert.False(t, called)
+	assert.Equal(t, http.StatusUnprocessableEntity, recorder.Code)
+	assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"))
+
+	var problem v2.Problem
+	require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+	assert.Equal(t, v2.CodeValidationFailed, problem.Code)
+
+	type fieldError struct {
+		index int
+		field string
+		rule  string
+	}
+	var actual []fieldError
+	for _, e := range problem.Errors {
+		require.NotNil(t, e.Index)
+		actual = append(actual, fieldError{index: *e.Index, field: e.Field, rule: e.Rule})
+	}
+	assert.Equal(t, []fieldError{
+		{index: 1, field: "id", rule: "minLength"},
+		{index: 1, field: "labels", rule: "additionalProperties"},
+		{index: 1, field: "type", rule: "enum"},
+		{index: 1, field: "value", rule: "type"},
+	}, actual)
+
+	for _, path := range []string{"/api/v2/metrics/histogram/Alloc", "/api/v2/metrics/gauge/"} {
+		request = httptest.NewRequest(http.MethodGet, path, nil)
+		recorder = httptest.NewRecorder()
+		handler.ServeHTTP(recorder, request)
+		assert.Equal(t, http.StatusBadRequest, recorder.Code, path)
+		assert.Equal(t, "application/problem+json", recorder.Header().Get("Content-Type"), path)
+		require.NoError(t, json.Unmarshal(recorder.Body.Bytes(), &problem))
+		assert.Equal(t, v2.CodeValidationFailed, problem.Code, path)
+	}
+
+	// body of signed request is validated by handler after signature
+	called = false
+	request = httptest.NewRequest(http.MethodPost, "/api/v2/metrics", strings.NewReader(body))
+	request.Header.Set("Content-Type", "application/json")
+	request.Header.Set("HashSHA256", "signature")
+	handler.ServeHTTP(httptest.NewRecorder(), request)
+	assert.True(t, called)
+}
@@ -0,0 +1,187 @@
+package openapi
+
+import (
+	"encoding/json"
+	"fmt"
+	"math"
+	"slices"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+// ValidationError is violation of schema keyword (rule) by value at path.
+// Path holds property names and array indexes from root of value
+type ValidationError struct {
+	Path    []string
+	Rule    string
+	Message string
+}
+
+// Validate checks value decoded from JSON with UseNumber against schema
+func (d *Document) Validate(schema *Schema, value any) []ValidationError {
+	return d.validate(schema, value, nil)
+}
+
+func (d *Document) validate(schema *Schema, value any, path []string) []ValidationError {
+	schema = d.Resolve(schema)
+	if schema == nil {
+		return nil
+	}
+
+	switch schema.Type {
+	case "object":
+		object, ok := value.(map[string]any)
+		if !ok {
+			return typeError(schema, path)
+		}
+		return d.validateObject(schema, object, path)
+	case "array":
+		array, ok := value.([]any)
+		if !ok {
+			return typeError(schema, path)
+		}
+		var errs []ValidationError
+		if schema.MinItems != nil && len(array) < *schema.MinItems {
+			errs = append(errs, newError(path, "minItems", "should contain at least %d items", *schema.MinItems))
+		}
+		for i, item := range array {
+			errs = append(errs, d.validate(schema.Items, item, appendPath(path, strconv.Itoa(i)))...)
+		}
+		return errs
+	case "string":
+		s, ok := value.(string)
+		if !ok {
+			return typeError(schema, path)
+		}
+		return validateString(schema, s, path)
+	case "integer", "number":
+		number, ok := value.(json.Number)
+		if !ok {
+			return typeError(schema, path)
+		}
+		return validateNumber(schema, number, path)
+	case "boolean":
+		if _, ok := value.(bool); !ok {
+			return typeError(schema, path)
+		}
+	}
+	return nil
+}
+
+func (d *Document) validateObject(schema *Schema, object map[string]any, path []string) []ValidationError {
+	var errs []ValidationError
+	for _, name := range schema.Required {
+		if _, ok := object[name]; !ok {
+			errs = append(errs, newError(appendPath(path, name), "required", "is required"))
+		}
+	}
+
+	names := make([]string, 0, len(object))
+	for name := range object {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	for _, name := range names {
+		property, ok := schema.Properties[name]
+		switch {
+		case ok:
+			errs = append(errs, d.validate(property, object[name], appendPath(path, name))...)
+		case schema.AdditionalProperties == nil:
+		case !schema.AdditionalProperties.Allowed:
+			errs = append(errs, newError(appendPath(path, name), "additionalProperties", "is not allowed"))
+		default:
+			errs = append(errs, d.validate(schema.AdditionalProperties.Schema, object[name], appendPath(path, name))...)
+		}
+	}
+	return errs
+}
+
+func validateString(schema *Schema, s string, path []string) []ValidationError {
+	var errs []ValidationError
+	if len(schema.Enum) > 0 && !slices.Contains(schema.Enum, s) {
+		errs = append(errs, newError(path, "enum", "should be one of: %s", strings.Join(schema.Enum, ", ")))
+	}
+	if schema.MinLength != nil && utf8.RuneCountInString(s) < *schema.MinLength {
+		errs = append(errs, newError(path, "minLength", "should be at least %d characters long", *schema.MinLength))
+	}
+	if schema.MaxLength != nil && utf8.RuneCountInString(s) > *schema.MaxLength {
+		errs = append(errs, newError(path, "maxLength", "should be at most %d characters long", *schema.MaxLength))
+	}
+
+	var err error
+	switch schema.Format {
+	case "date-time":
+		_, err = time.Parse(time.RFC3339, s)
+	case "duration":
+		_, err = time.ParseDuration(s)
+	}
+	if err != nil {
+		errs = append(errs, newError(path, "format", "should be %s", schema.Format))
+	}
+	return errs
+}
+
+func validateNumber(schema *Schema, number json.Number, path []string) []ValidationError {
+	value, err := number.Float64()
+	if err != nil {
+		return typeError(schema, path)
+	}
+	if schema.Type == "integer" {
+		if value != math.Trunc(value) {
+			return typeError(schema, path)
+		}
+		if schema.Format == "uint64" {
+			_, err = strconv.ParseUint(number.String(), 10, 64)
+		} else {
+			_, err = strconv.ParseInt(number.String(), 10, 64)
+		}
+		if err != nil {
+			return []ValidationError{newError(path, "format", "should be %s", integerFormat(schema))}
+		}
+	}
+	if schema.Minimum != nil && value < *schema.Minimum {
+		return []ValidationError{newError(path, "minimum", "should be at least %g", *schema.Minimum)}
+	}
+	return nil
+}
+
+// parseParameter converts value of path, query or header parameter to value which can be validated by schema
+func (d *Document) parseParameter(schema *Schema, value string) any {
+	switch d.Resolve(schema).Type {
+	case "integer", "number":
+		if _, err := strconv.ParseFloat(value, 64); err == nil {
+			return json.Number(value)
+		}
+	case "boolean":
+		if parsed, err := strconv.ParseBool(value); err == nil {
+			return parsed
+		}
+	default:
+		return value
+	}
+	// value of another type is reported as type mismatch
+	return struct{}{}
+}
+
+func typeError(schema *Schema, path []string) []ValidationError {
+	return []ValidationError{newError(path, "type", "should be %s", schema.Type)}
+}
+
+func integerFormat(schema *Schema) string {
+	if schema.Format == "" {
+		return "integer"
+	}
+	return schema.Format
+}
+
+func newError(path []string, rule string, format string, args ...any) ValidationError {
+	return ValidationError{Path: path, Rule: rule, Message: fmt.Sprintf(format, args...)}
+}
+
+func appendPath(path []string, element string) []string {
+	return append(path[:len(path):len(path)], element)
+}
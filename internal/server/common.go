@@ -21,6 +21,11 @@ func NewRetrier(maxAttempts int, baseDelay time.Duration, maxDelay time.Duration
 }
 
 func (r *Retrier) RunSQL(fn func() error) error {
+	return r.Run(fn, isRetriableError)
+}
+
+// Run calls fn until it succeeds, returns error which is not retriable or attempts are exhausted
+func (r *Retrier) Run(fn func() error, isRetriable func(err error) bool) error {
 	var err error
 	for attempt := 0; attempt <= r.MaxAttempts; attempt++ {
 		err = fn()
@@ -28,9 +33,12 @@ func (r *Retrier) RunSQL(fn func() error) error {
 			return nil
 		}
 
-		if !isRetriableError(err) {
+		if !isRetriable(err) {
 			return err
 		}
+		if attempt == r.MaxAttempts {
+			break
+		}
 
 		log.Printf("Attempt %d failed with retriable error: %v. Retrying...", attempt, err)
 
@@ -23,6 +23,10 @@ func TestParseConfig_ShouldReturnDefaultValues(t *testing.T) {
 	assert.False(t, config.EnabledHTTPS)
 	assert.Empty(t, config.HashKey)
 	assert.Empty(t, config.CryptoKey)
+	assert.Equal(t, "./cmd/cert/server.crt", config.Certificate)
+	assert.Empty(t, config.ClientCA)
+	assert.Empty(t, config.UpstreamAddress)
+	assert.Equal(t, "http", config.UpstreamProtocol)
 }
 
 func TestParseConfig_ShouldReturnEnvOverrides(t *testing.T) {
@@ -69,6 +73,7 @@ func TestParseConfig_ShouldReturnFileOverrides(t *testing.T) {
 			HashKey:            "filehash",
 			CryptoKey:          "filecrypto",
 			TrustedSubnet:      "172.18.208.1/32",
+			GRPCAddress:        "192.168.1.1:3200",
 		}, nil
 	})
 
@@ -81,4 +86,12 @@ func TestParseConfig_ShouldReturnFileOverrides(t *testing.T) {
 	assert.Equal(t, "filehash", config.HashKey)
 	assert.Equal(t, "filecrypto", config.CryptoKey)
 	assert.Equal(t, "172.18.208.1/32", config.TrustedSubnet)
+	assert.False(t, config.EnabledGRPC)
+	assert.Equal(t, "192.168.1.1:3200", config.GRPCAddress)
+}
+
+func TestConfig_Validate(t *testing.T) {
+	assert.NoError(t, Config{}.Validate())
+	assert.NoError(t, Config{EnabledGRPC: true, GRPCAddress: "localhost:3200"}.Validate())
+	assert.Error(t, Config{EnabledGRPC: true}.Validate())
 }
@@ -91,3 +91,21 @@ func UnmarshalMetric(data []byte) (Metric, error) {
 		return nil, fmt.Errorf("unsupported metric type: %s", base.Type)
 	}
 }
+
+// MetricsListener is notified about every batch of metrics applied by storage.
+// Listener is called synchronously in order of applying, so it should not block or call storage back
+type MetricsListener func(metrics []Metric)
+
+// CloneMetric makes a copy of metric detached from storage internals
+func CloneMetric(metric Metric) Metric {
+	switch m := metric.(type) {
+	case *Gauge:
+		clone := *m
+		return &clone
+	case *Counter:
+		clone := *m
+		return &clone
+	default:
+		return metric
+	}
+}
@@ -1,10 +1,14 @@
 package server
 
 import (
+	"errors"
 	"fmt"
 	"github.com/desepticon55/metrics-collector/internal/common"
 )
 
+// ErrReadOnly is returned when server doesn't accept writes, for example replication follower before promotion
+var ErrReadOnly = errors.New("server is a replication follower, metrics are accepted by primary only")
+
 type MetricNotFoundError struct {
 	metricName string
 	metricType common.MetricType
@@ -18,6 +22,24 @@ func NewMetricNotFoundError(metricName string, metricType common.MetricType) *Me
 	return &MetricNotFoundError{metricName: metricName, metricType: metricType}
 }
 
+// PartialSaveError is returned when only part of metrics is saved, for example when one of shards failed.
+// Saved metrics stay saved, so only failed ones should be sent again
+type PartialSaveError struct {
+	Saved  []common.MetricResponseDto `json:"saved"`
+	Failed []FailedMetric             `json:"failed"`
+}
+
+// FailedMetric is metric which was not saved with reason
+type FailedMetric struct {
+	ID    string            `json:"id"`
+	MType common.MetricType `json:"type"`
+	Error string            `json:"error"`
+}
+
+func (e *PartialSaveError) Error() string {
+	return fmt.Sprintf("%d of %d metrics were not saved, first error: %s", len(e.Failed), len(e.Saved)+len(e.Failed), e.Failed[0].Error)
+}
+
 type ValidationError struct {
 	error
 }
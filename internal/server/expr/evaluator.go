@@ -0,0 +1,276 @@
+package expr
+
+import (
+	"context"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"math"
+	"path"
+)
+
+// Sample is a value of single series. Name is empty for scalars and aggregations
+type Sample struct {
+	Name  string  `json:"name"`
+	Value float64 `json:"value"`
+}
+
+type Vector []Sample
+
+// Function receives evaluated arguments. Durations are passed as scalars in seconds
+type Function func(ctx context.Context, args []Vector) (Vector, error)
+
+type metricsSource interface {
+	FindAllMetrics(ctx context.Context) []common.MetricResponseDto
+}
+
+// Evaluator evaluates expressions against current values of metrics.
+// Gauges are represented by value and counters by accumulated delta
+type Evaluator struct {
+	source    metricsSource
+	functions map[string]Function
+}
+
+func NewEvaluator(source metricsSource) *Evaluator {
+	e := &Evaluator{source: source, functions: make(map[string]Function)}
+	e.RegisterFunction("sum", aggregate(func(values []float64) float64 {
+		total := 0.0
+		for _, value := range values {
+			total += value
+		}
+		return total
+	}, true))
+	e.RegisterFunction("count", aggregate(func(values []float64) float64 {
+		return float64(len(values))
+	}, true))
+	e.RegisterFunction("avg", aggregate(func(values []float64) float64 {
+		total := 0.0
+		for _, value := range values {
+			total += value
+		}
+		return total / float64(len(values))
+	}, false))
+	e.RegisterFunction("min", aggregate(func(values []float64) float64 {
+		result := math.Inf(1)
+		for _, value := range values {
+			result = math.Min(result, value)
+		}
+		return result
+	}, false))
+	e.RegisterFunction("max", aggregate(func(values []float64) float64 {
+		result := math.Inf(-1)
+		for _, value := range values {
+			result = math.Max(result, value)
+		}
+		return result
+	}, false))
+	e.RegisterFunction("abs", func(ctx context.Context, args []Vector) (Vector, error) {
+		if len(args) != 1 {
+			return nil, fmt.Errorf("abs expects 1 argument, got %d", len(args))
+		}
+		result := make(Vector, 0, len(args[0]))
+		for _, sample := range args[0] {
+			result = append(result, Sample{Name: sample.Name, Value: math.Abs(sample.Value)})
+		}
+		return result, nil
+	})
+	return e
+}
+
+// RegisterFunction makes function available in expressions. Registered function replaces existing one with the same name
+func (e *Evaluator) RegisterFunction(name string, function Function) {
+	e.functions[name] = function
+}
+
+// Eval evaluates expression. Every evaluation uses consistent snapshot of metrics
+func (e *Evaluator) Eval(ctx context.Context, node Node) (Vector, error) {
+	return node.eval(&evalContext{ctx: ctx, evaluator: e})
+}
+
+// EvalScalar evaluates expression which should produce exactly one value
+func (e *Evaluator) EvalScalar(ctx context.Context, node Node) (float64, error) {
+	vector, err := e.Eval(ctx, node)
+	if err != nil {
+		return 0, err
+	}
+	if len(vector) != 1 {
+		return 0, fmt.Errorf("expected single value, got %d", len(vector))
+	}
+	return vector[0].Value, nil
+}
+
+type evalContext struct {
+	ctx       context.Context
+	evaluator *Evaluator
+	metrics   []common.MetricResponseDto
+	loaded    bool
+}
+
+func (c *evalContext) allMetrics() []common.MetricResponseDto {
+	if !c.loaded {
+		c.metrics = c.evaluator.source.FindAllMetrics(c.ctx)
+		c.loaded = true
+	}
+	return c.metrics
+}
+
+func (n *NumberLiteral) eval(ctx *evalContext) (Vector, error) {
+	return Vector{{Value: n.Value}}, nil
+}
+
+func (n *Selector) eval(ctx *evalContext) (Vector, error) {
+	if _, err := path.Match(n.Pattern, ""); err != nil {
+		return nil, fmt.Errorf("incorrect pattern '%s': %w", n.Pattern, err)
+	}
+
+	var result Vector
+	for _, metric := range ctx.allMetrics() {
+		if matched, _ := path.Match(n.Pattern, metric.ID); !matched {
+			continue
+		}
+		switch {
+		case metric.MType == common.Gauge && metric.Value != nil:
+			result = append(result, Sample{Name: metric.ID, Value: *metric.Value})
+		case metric.MType == common.Counter && metric.Delta != nil:
+			result = append(result, Sample{Name: metric.ID, Value: float64(*metric.Delta)})
+		}
+	}
+	return result, nil
+}
+
+func (n *Call) eval(ctx *evalContext) (Vector, error) {
+	function, ok := ctx.evaluator.functions[n.Function]
+	if !ok {
+		return nil, fmt.Errorf("unknown function '%s'", n.Function)
+	}
+
+	args := make([]Vector, 0, len(n.Args))
+	for _, argNode := range n.Args {
+		arg, err := argNode.eval(ctx)
+		if err != nil {
+			return nil, err
+		}
+		args = append(args, arg)
+	}
+	return function(ctx.ctx, args)
+}
+
+func (n *Negation) eval(ctx *evalContext) (Vector, error) {
+	vector, err := n.Expr.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	result := make(Vector, 0, len(vector))
+	for _, sample := range vector {
+		result = append(result, Sample{Name: sample.Name, Value: -sample.Value})
+	}
+	return result, nil
+}
+
+func (n *Binary) eval(ctx *evalContext) (Vector, error) {
+	left, err := n.Left.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+	right, err := n.Right.eval(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	if n.IsComparison() {
+		// comparison filters left operand
+		return combine(left, right, func(l, r float64) (float64, bool) {
+			return l, Compare(l, n.Op, r)
+		}), nil
+	}
+
+	return combine(left, right, func(l, r float64) (float64, bool) {
+		var value float64
+		switch n.Op {
+		case "+":
+			value = l + r
+		case "-":
+			value = l - r
+		case "*":
+			value = l * r
+		case "/":
+			value = l / r
+		}
+		return value, !math.IsNaN(value) && !math.IsInf(value, 0)
+	}), nil
+}
+
+// Compare applies comparison operator
+func Compare(left float64, op string, right float64) bool {
+	switch op {
+	case ">":
+		return left > right
+	case ">=":
+		return left >= right
+	case "<":
+		return left < right
+	case "<=":
+		return left <= right
+	case "==":
+		return left == right
+	case "!=":
+		return left != right
+	default:
+		return false
+	}
+}
+
+// combine applies operation to operands. Single value is applied to every value of other operand,
+// otherwise values are matched by series name. Operation result is dropped when it is not ok
+func combine(left Vector, right Vector, operation func(l, r float64) (float64, bool)) Vector {
+	var result Vector
+	add := func(name string, l, r float64) {
+		if value, ok := operation(l, r); ok {
+			result = append(result, Sample{Name: name, Value: value})
+		}
+	}
+
+	switch {
+	case len(left) == 1 && len(right) == 1:
+		name := left[0].Name
+		if name == "" {
+			name = right[0].Name
+		}
+		add(name, left[0].Value, right[0].Value)
+	case len(left) == 1:
+		for _, sample := range right {
+			add(sample.Name, left[0].Value, sample.Value)
+		}
+	case len(right) == 1:
+		for _, sample := range left {
+			add(sample.Name, sample.Value, right[0].Value)
+		}
+	default:
+		rightValues := make(map[string]float64, len(right))
+		for _, sample := range right {
+			rightValues[sample.Name] = sample.Value
+		}
+		for _, sample := range left {
+			if value, ok := rightValues[sample.Name]; ok {
+				add(sample.Name, sample.Value, value)
+			}
+		}
+	}
+	return result
+}
+
+// aggregate makes function reducing all values of all arguments to single value.
+// When allowEmpty is false the result is empty for empty input
+func aggregate(reduce func(values []float64) float64, allowEmpty bool) Function {
+	return func(ctx context.Context, args []Vector) (Vector, error) {
+		var values []float64
+		for _, arg := range args {
+			for _, sample := range arg {
+				values = append(values, sample.Value)
+			}
+		}
+		if len(values) == 0 && !allowEmpty {
+			return Vector{}, nil
+		}
+		return Vector{{Value: reduce(values)}}, nil
+	}
+}
@@ -0,0 +1,75 @@
+package expr
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+type stubSource []common.MetricResponseDto
+
+func (s stubSource) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	return s
+}
+
+func gauge(name string, value float64) common.MetricResponseDto {
+	return common.MetricResponseDto{ID: name, MType: common.Gauge, Value: &value}
+}
+
+func counter(name string, delta int64) common.MetricResponseDto {
+	return common.MetricResponseDto{ID: name, MType: common.Counter, Delta: &delta}
+}
+
+func TestEvaluator_Eval(t *testing.T) {
+	evaluator := NewEvaluator(stubSource{
+		gauge("TotalMemory", 1000),
+		gauge("FreeMemory", 250),
+		gauge("CPUutilization1", 10),
+		gauge("CPUutilization2", 95),
+		counter("PollCount", 7),
+		gauge("disk;host=a", 3),
+	})
+
+	tests := []struct {
+		name     string
+		expr     string
+		expected Vector
+	}{
+		{name: "arithmetic", expr: "(TotalMemory - FreeMemory) / TotalMemory * 100", expected: Vector{{Name: "TotalMemory", Value: 75}}},
+		{name: "aggregation", expr: "sum(CPUutilization*)", expected: Vector{{Value: 105}}},
+		{name: "comparison filters series", expr: "CPUutilization* > 90", expected: Vector{{Name: "CPUutilization2", Value: 95}}},
+		{name: "counter", expr: "PollCount * 2", expected: Vector{{Name: "PollCount", Value: 14}}},
+		{name: "duration", expr: "5m + 1e3", expected: Vector{{Value: 1300}}},
+		{name: "negation", expr: "-FreeMemory", expected: Vector{{Name: "FreeMemory", Value: -250}}},
+		{name: "quoted selector", expr: `"disk;host=a"`, expected: Vector{{Name: "disk;host=a", Value: 3}}},
+		{name: "division by zero is dropped", expr: "FreeMemory / 0", expected: nil},
+		{name: "missing metric", expr: "Unknown > 1", expected: nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			node, err := Parse(tt.expr)
+			assert.NoError(t, err)
+
+			result, err := evaluator.Eval(context.Background(), node)
+			assert.NoError(t, err)
+			assert.ElementsMatch(t, tt.expected, result)
+		})
+	}
+}
+
+func TestParse_Errors(t *testing.T) {
+	for _, input := range []string{"", "(a + b", "a +", "5x", "a b", "sum(a b)", `"a`} {
+		_, err := Parse(input)
+		assert.Error(t, err, input)
+	}
+}
+
+func TestEvaluator_UnknownFunction(t *testing.T) {
+	node, err := Parse("unknown(a)")
+	assert.NoError(t, err)
+
+	_, err = NewEvaluator(stubSource{}).Eval(context.Background(), node)
+	assert.Error(t, err)
+}
@@ -0,0 +1,143 @@
+package expr
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode"
+)
+
+type tokenKind int
+
+const (
+	tokenEOF tokenKind = iota
+	tokenNumber
+	tokenIdent
+	tokenString
+	tokenOperator
+	tokenLeftParen
+	tokenRightParen
+	tokenComma
+)
+
+type token struct {
+	kind   tokenKind
+	text   string
+	number float64
+	pos    int
+}
+
+// duration suffixes are converted to seconds, e.g. 5m = 300
+var durationUnits = map[string]float64{
+	"ms": 0.001,
+	"s":  1,
+	"m":  60,
+	"h":  60 * 60,
+	"d":  24 * 60 * 60,
+	"w":  7 * 24 * 60 * 60,
+}
+
+var operators = []string{">=", "<=", "==", "!=", ">", "<", "+", "-", "*", "/"}
+
+func tokenize(input string) ([]token, error) {
+	var tokens []token
+	for pos := 0; pos < len(input); {
+		c := rune(input[pos])
+		switch {
+		case unicode.IsSpace(c):
+			pos++
+		case c == '(':
+			tokens = append(tokens, token{kind: tokenLeftParen, text: "(", pos: pos})
+			pos++
+		case c == ')':
+			tokens = append(tokens, token{kind: tokenRightParen, text: ")", pos: pos})
+			pos++
+		case c == ',':
+			tokens = append(tokens, token{kind: tokenComma, text: ",", pos: pos})
+			pos++
+		case c == '"' || c == '`':
+			end := strings.IndexRune(input[pos+1:], c)
+			if end < 0 {
+				return nil, fmt.Errorf("unterminated string at position %d", pos)
+			}
+			tokens = append(tokens, token{kind: tokenString, text: input[pos+1 : pos+1+end], pos: pos})
+			pos += end + 2
+		case unicode.IsDigit(c) || (c == '.' && pos+1 < len(input) && unicode.IsDigit(rune(input[pos+1]))):
+			t, next, err := scanNumber(input, pos)
+			if err != nil {
+				return nil, err
+			}
+			tokens = append(tokens, t)
+			pos = next
+		case isIdentStart(c):
+			next := pos + 1
+			for next < len(input) && isIdentPart(rune(input[next])) {
+				next++
+			}
+			tokens = append(tokens, token{kind: tokenIdent, text: input[pos:next], pos: pos})
+			pos = next
+		default:
+			operator := ""
+			for _, op := range operators {
+				if strings.HasPrefix(input[pos:], op) {
+					operator = op
+					break
+				}
+			}
+			if operator == "" {
+				return nil, fmt.Errorf("unexpected character '%c' at position %d", c, pos)
+			}
+			tokens = append(tokens, token{kind: tokenOperator, text: operator, pos: pos})
+			pos += len(operator)
+		}
+	}
+	return append(tokens, token{kind: tokenEOF, pos: len(input)}), nil
+}
+
+func scanNumber(input string, pos int) (token, int, error) {
+	next := pos
+	for next < len(input) && (unicode.IsDigit(rune(input[next])) || input[next] == '.') {
+		next++
+	}
+	if next < len(input) && (input[next] == 'e' || input[next] == 'E') {
+		exponent := next + 1
+		if exponent < len(input) && (input[exponent] == '+' || input[exponent] == '-') {
+			exponent++
+		}
+		if exponent < len(input) && unicode.IsDigit(rune(input[exponent])) {
+			next = exponent
+			for next < len(input) && unicode.IsDigit(rune(input[next])) {
+				next++
+			}
+		}
+	}
+
+	value, err := strconv.ParseFloat(input[pos:next], 64)
+	if err != nil {
+		return token{}, 0, fmt.Errorf("incorrect number '%s' at position %d", input[pos:next], pos)
+	}
+
+	unitEnd := next
+	for unitEnd < len(input) && unicode.IsLetter(rune(input[unitEnd])) {
+		unitEnd++
+	}
+	if unitEnd > next {
+		multiplier, ok := durationUnits[input[next:unitEnd]]
+		if !ok {
+			return token{}, 0, fmt.Errorf("unknown duration unit '%s' at position %d", input[next:unitEnd], next)
+		}
+		value *= multiplier
+	}
+
+	return token{kind: tokenNumber, text: input[pos:unitEnd], number: value, pos: pos}, unitEnd, nil
+}
+
+func isIdentStart(c rune) bool {
+	return unicode.IsLetter(c) || c == '_'
+}
+
+// identifiers are metric names or glob patterns (see path.Match), so '*' placed right after
+// identifier is a part of pattern. Use spaces around multiplication operator
+func isIdentPart(c rune) bool {
+	return unicode.IsLetter(c) || unicode.IsDigit(c) || strings.ContainsRune("_.:*?[]", c)
+}
@@ -0,0 +1,197 @@
+package expr
+
+import (
+	"fmt"
+)
+
+// Node of parsed expression
+type Node interface {
+	eval(ctx *evalContext) (Vector, error)
+}
+
+// NumberLiteral is a number or a duration converted to seconds
+type NumberLiteral struct {
+	Value float64
+}
+
+// Selector selects current values of metrics which names match pattern
+type Selector struct {
+	Pattern string
+}
+
+// Call of registered function
+type Call struct {
+	Function string
+	Args     []Node
+}
+
+// Binary is arithmetic or comparison operation
+type Binary struct {
+	Op    string
+	Left  Node
+	Right Node
+}
+
+// IsComparison reports whether operation compares operands
+func (b *Binary) IsComparison() bool {
+	switch b.Op {
+	case ">", ">=", "<", "<=", "==", "!=":
+		return true
+	default:
+		return false
+	}
+}
+
+type Negation struct {
+	Expr Node
+}
+
+// Parse expression, e.g. "(TotalMemory - FreeMemory) / TotalMemory * 100" or "sum(CPUutilization*) > 90"
+func Parse(input string) (Node, error) {
+	tokens, err := tokenize(input)
+	if err != nil {
+		return nil, err
+	}
+
+	p := &parser{tokens: tokens}
+	node, err := p.parseComparison()
+	if err != nil {
+		return nil, err
+	}
+	if p.peek().kind != tokenEOF {
+		return nil, fmt.Errorf("unexpected '%s' at position %d", p.peek().text, p.peek().pos)
+	}
+	return node, nil
+}
+
+type parser struct {
+	tokens []token
+	pos    int
+}
+
+func (p *parser) peek() token {
+	return p.tokens[p.pos]
+}
+
+func (p *parser) next() token {
+	t := p.tokens[p.pos]
+	if t.kind != tokenEOF {
+		p.pos++
+	}
+	return t
+}
+
+func (p *parser) parseComparison() (Node, error) {
+	left, err := p.parseAdditive()
+	if err != nil {
+		return nil, err
+	}
+
+	if t := p.peek(); t.kind == tokenOperator {
+		node := &Binary{Op: t.text}
+		if node.IsComparison() {
+			p.next()
+			right, err := p.parseAdditive()
+			if err != nil {
+				return nil, err
+			}
+			node.Left, node.Right = left, right
+			return node, nil
+		}
+	}
+	return left, nil
+}
+
+func (p *parser) parseAdditive() (Node, error) {
+	left, err := p.parseMultiplicative()
+	if err != nil {
+		return nil, err
+	}
+
+	for t := p.peek(); t.kind == tokenOperator && (t.text == "+" || t.text == "-"); t = p.peek() {
+		p.next()
+		right, err := p.parseMultiplicative()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: t.text, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseMultiplicative() (Node, error) {
+	left, err := p.parseUnary()
+	if err != nil {
+		return nil, err
+	}
+
+	for t := p.peek(); t.kind == tokenOperator && (t.text == "*" || t.text == "/"); t = p.peek() {
+		p.next()
+		right, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		left = &Binary{Op: t.text, Left: left, Right: right}
+	}
+	return left, nil
+}
+
+func (p *parser) parseUnary() (Node, error) {
+	if t := p.peek(); t.kind == tokenOperator && t.text == "-" {
+		p.next()
+		node, err := p.parseUnary()
+		if err != nil {
+			return nil, err
+		}
+		return &Negation{Expr: node}, nil
+	}
+	return p.parsePrimary()
+}
+
+func (p *parser) parsePrimary() (Node, error) {
+	t := p.next()
+	switch t.kind {
+	case tokenNumber:
+		return &NumberLiteral{Value: t.number}, nil
+	case tokenString:
+		return &Selector{Pattern: t.text}, nil
+	case tokenIdent:
+		if p.peek().kind != tokenLeftParen {
+			return &Selector{Pattern: t.text}, nil
+		}
+		p.next()
+		call := &Call{Function: t.text}
+		if p.peek().kind == tokenRightParen {
+			p.next()
+			return call, nil
+		}
+		for {
+			arg, err := p.parseComparison()
+			if err != nil {
+				return nil, err
+			}
+			call.Args = append(call.Args, arg)
+
+			separator := p.next()
+			if separator.kind == tokenRightParen {
+				return call, nil
+			}
+			if separator.kind != tokenComma {
+				return nil, fmt.Errorf("expected ',' or ')' at position %d", separator.pos)
+			}
+		}
+	case tokenLeftParen:
+		node, err := p.parseComparison()
+		if err != nil {
+			return nil, err
+		}
+		if closing := p.next(); closing.kind != tokenRightParen {
+			return nil, fmt.Errorf("expected ')' at position %d", closing.pos)
+		}
+		return node, nil
+	case tokenEOF:
+		return nil, fmt.Errorf("unexpected end of expression")
+	default:
+		return nil, fmt.Errorf("unexpected '%s' at position %d", t.text, t.pos)
+	}
+}
@@ -23,7 +23,7 @@ func (m Mapper) MapRequestToDomainModel(dto common.MetricRequestDto) (server.Met
 	switch dto.MType {
 	case common.Gauge:
 		if dto.Value == nil {
-			return nil, fmt.Errorf("value is required for gauge type")
+			return nil, server.NewValidationError(fmt.Errorf("value is required for gauge type"))
 		}
 		return &server.Gauge{
 			BaseMetric: server.BaseMetric{Name: dto.ID, Type: common.Gauge},
@@ -31,14 +31,14 @@ func (m Mapper) MapRequestToDomainModel(dto common.MetricRequestDto) (server.Met
 		}, nil
 	case common.Counter:
 		if dto.Delta == nil {
-			return nil, fmt.Errorf("delta is required for counter type")
+			return nil, server.NewValidationError(fmt.Errorf("delta is required for counter type"))
 		}
 		return &server.Counter{
 			BaseMetric: server.BaseMetric{Name: dto.ID, Type: common.Counter},
 			Value:      *dto.Delta,
 		}, nil
 	default:
-		return nil, fmt.Errorf("unsupported metric type: %s", dto.MType)
+		return nil, server.NewValidationError(fmt.Errorf("unsupported metric type: %s", dto.MType))
 	}
 }
 
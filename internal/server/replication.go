@@ -0,0 +1,13 @@
+package server
+
+import "time"
+
+// ReplicationStatus is position of follower in stream of writes applied by primary server
+type ReplicationStatus struct {
+	Role          string     `json:"role"`
+	Primary       string     `json:"primary,omitempty"`
+	Connected     bool       `json:"connected"`
+	Epoch         string     `json:"epoch,omitempty"`
+	LastSeq       uint64     `json:"last_seq"`
+	LastAppliedAt *time.Time `json:"last_applied_at,omitempty"`
+}
@@ -0,0 +1,111 @@
+package server
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+)
+
+// RulesConfig is content of rules file
+type RulesConfig struct {
+	EvaluationInterval int                   `json:"evaluation_interval"`
+	RecordingRules     []RecordingRuleConfig `json:"recording_rules"`
+	AlertRules         []AlertRuleConfig     `json:"alert_rules"`
+	Notifications      NotificationConfig    `json:"notifications"`
+	AnomalyDetection   AnomalyConfig         `json:"anomaly_detection"`
+	SLOs               []SLOConfig           `json:"slos"`
+}
+
+// AlertRuleConfig describes alert rule, e.g. "CPUutilization* > 90 for 5m".
+// Firing alert is resolved when value crosses threshold by more than hysteresis
+type AlertRuleConfig struct {
+	Name       string  `json:"name"`
+	Expr       string  `json:"expr"`
+	Hysteresis float64 `json:"hysteresis"`
+}
+
+// RecordingRuleConfig describes derived gauge, e.g. "MemoryUsedPercent" with expression
+// "(TotalMemory - FreeMemory) / TotalMemory * 100". Rules are evaluated in order of definition,
+// so rule can use results of previous ones
+type RecordingRuleConfig struct {
+	Name string `json:"name"`
+	Expr string `json:"expr"`
+}
+
+// NotificationConfig describes where alert transitions are delivered.
+// Notifications of the same alert with the same status are not repeated during throttle interval
+type NotificationConfig struct {
+	ThrottleInterval int                         `json:"throttle_interval"`
+	MaxRetries       int                         `json:"max_retries"`
+	Channels         []NotificationChannelConfig `json:"channels"`
+}
+
+// NotificationChannelConfig describes single channel. Type is one of "webhook", "slack" or "email".
+// URL is used by webhook and slack channels, SMTP settings are used by email channel
+type NotificationChannelConfig struct {
+	Name         string   `json:"name"`
+	Type         string   `json:"type"`
+	URL          string   `json:"url"`
+	SMTPAddress  string   `json:"smtp_address"`
+	SMTPUsername string   `json:"smtp_username"`
+	SMTPPassword string   `json:"smtp_password"`
+	From         string   `json:"from"`
+	To           []string `json:"to"`
+}
+
+// AnomalyConfig enables detection of outliers on gauges matched by Metrics patterns (all gauges when empty).
+// Detector keeps exponentially weighted mean and variance with smoothing factor Alpha, value is anomalous
+// when its z-score exceeds Threshold. First Warmup samples of every gauge are used only for learning
+type AnomalyConfig struct {
+	Enabled   bool     `json:"enabled"`
+	Alpha     float64  `json:"alpha"`
+	Threshold float64  `json:"threshold"`
+	Warmup    int      `json:"warmup"`
+	Metrics   []string `json:"metrics"`
+}
+
+// SLOConfig describes service level objective over pair of counters, e.g. good_requests / total_requests
+// should be at least Target (0.999) during last WindowDays days (30 by default)
+type SLOConfig struct {
+	Name       string  `json:"name"`
+	Good       string  `json:"good"`
+	Total      string  `json:"total"`
+	Target     float64 `json:"target"`
+	WindowDays int     `json:"window_days"`
+}
+
+func LoadRulesConfig(filePath string) (RulesConfig, error) {
+	config := RulesConfig{
+		EvaluationInterval: 10,
+		Notifications:      NotificationConfig{ThrottleInterval: 300, MaxRetries: 3},
+		AnomalyDetection:   AnomalyConfig{Alpha: 0.1, Threshold: 3, Warmup: 30},
+	}
+	if filePath == "" {
+		return config, nil
+	}
+
+	fileContent, err := os.ReadFile(filePath)
+	if err != nil {
+		return config, fmt.Errorf("could not read rules file: %w", err)
+	}
+
+	if err := json.Unmarshal(fileContent, &config); err != nil {
+		return config, fmt.Errorf("could not unmarshal rules JSON: %w", err)
+	}
+
+	if config.EvaluationInterval <= 0 {
+		return config, fmt.Errorf("evaluation interval should be positive")
+	}
+	if config.Notifications.ThrottleInterval < 0 || config.Notifications.MaxRetries < 0 {
+		return config, fmt.Errorf("notification throttle interval and max retries should not be negative")
+	}
+	if config.AnomalyDetection.Alpha <= 0 || config.AnomalyDetection.Alpha >= 1 || config.AnomalyDetection.Threshold <= 0 {
+		return config, fmt.Errorf("anomaly detection alpha should be between 0 and 1 and threshold should be positive")
+	}
+	for i := range config.SLOs {
+		if config.SLOs[i].WindowDays == 0 {
+			config.SLOs[i].WindowDays = 30
+		}
+	}
+	return config, nil
+}
@@ -0,0 +1,18 @@
+package alerts
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/server"
+)
+
+type alertStorage interface {
+	// SaveAlertStates replaces all stored alert states
+	SaveAlertStates(ctx context.Context, states []server.AlertState) error
+
+	FindAllAlertStates(ctx context.Context) ([]server.AlertState, error)
+}
+
+type notifier interface {
+	// Notify is called with alerts moved to firing or resolved state
+	Notify(transitions []server.AlertState)
+}
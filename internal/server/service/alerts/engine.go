@@ -0,0 +1,231 @@
+package alerts
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/expr"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Resolved alerts are kept visible during this period
+const resolvedRetention = 15 * time.Minute
+
+type stateKey struct {
+	rule   string
+	series string
+}
+
+// Engine periodically evaluates alert rules and moves alerts between
+// pending, firing and resolved states
+type Engine struct {
+	mu        sync.Mutex
+	rules     []Rule
+	evaluator *expr.Evaluator
+	storage   alertStorage
+	notifier  notifier
+	logger    *zap.Logger
+	states    map[stateKey]*server.AlertState
+}
+
+func New(rules []Rule, evaluator *expr.Evaluator, storage alertStorage, notifier notifier, logger *zap.Logger) *Engine {
+	return &Engine{
+		rules:     rules,
+		evaluator: evaluator,
+		storage:   storage,
+		notifier:  notifier,
+		logger:    logger,
+		states:    make(map[stateKey]*server.AlertState),
+	}
+}
+
+// Run restores persisted alert states and evaluates rules with interval until context is done
+func (e *Engine) Run(ctx context.Context, interval time.Duration) {
+	if err := e.restore(ctx); err != nil {
+		e.logger.Error("Error during restore alert states", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := e.Evaluate(ctx, now); err != nil {
+				e.logger.Error("Error during save alert states", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (e *Engine) restore(ctx context.Context) error {
+	states, err := e.storage.FindAllAlertStates(ctx)
+	if err != nil {
+		return err
+	}
+
+	ruleNames := make(map[string]bool, len(e.rules))
+	for _, rule := range e.rules {
+		ruleNames[rule.Name] = true
+	}
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	for _, state := range states {
+		if !ruleNames[state.Rule] {
+			continue
+		}
+		restored := state
+		e.states[stateKey{rule: state.Rule, series: state.Series}] = &restored
+	}
+	return nil
+}
+
+// Evaluate all rules at the moment. States are persisted when any of them was changed
+func (e *Engine) Evaluate(ctx context.Context, now time.Time) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	changed := false
+	var allTransitions []server.AlertState
+	for _, rule := range e.rules {
+		transitions, ruleChanged := e.evaluateRule(ctx, rule, now)
+		changed = changed || ruleChanged
+		allTransitions = append(allTransitions, transitions...)
+		for _, transition := range transitions {
+			e.logger.Info("Alert state was changed",
+				zap.String("rule", transition.Rule),
+				zap.String("series", transition.Series),
+				zap.String("status", string(transition.Status)),
+				zap.Float64("value", transition.Value))
+		}
+	}
+
+	if len(allTransitions) > 0 {
+		e.notifier.Notify(allTransitions)
+	}
+
+	if !changed {
+		return nil
+	}
+	return e.storage.SaveAlertStates(ctx, e.snapshot())
+}
+
+// evaluateRule updates states of rule series and returns states moved to firing or resolved
+func (e *Engine) evaluateRule(ctx context.Context, rule Rule, now time.Time) ([]server.AlertState, bool) {
+	values, err := e.evaluator.Eval(ctx, rule.condition.Left)
+	if err != nil {
+		e.logger.Error("Error during evaluate alert rule", zap.String("rule", rule.Name), zap.Error(err))
+		return nil, false
+	}
+	threshold, err := e.evaluator.EvalScalar(ctx, rule.condition.Right)
+	if err != nil {
+		e.logger.Error("Error during evaluate alert rule threshold", zap.String("rule", rule.Name), zap.Error(err))
+		return nil, false
+	}
+
+	var transitions []server.AlertState
+	changed := false
+	seen := make(map[string]bool, len(values))
+	for _, sample := range values {
+		series := sample.Name
+		if series == "" {
+			series = rule.Name
+		}
+		seen[series] = true
+
+		key := stateKey{rule: rule.Name, series: series}
+		state := e.states[key]
+		firing := state != nil && state.Status == server.AlertFiring
+		active := rule.isActive(sample.Value, threshold, firing)
+
+		switch {
+		case state == nil || state.Status == server.AlertResolved:
+			if !active {
+				continue
+			}
+			state = &server.AlertState{Rule: rule.Name, Series: series, Status: server.AlertPending, ActiveAt: now}
+			e.states[key] = state
+			changed = true
+		case !active && state.Status == server.AlertPending:
+			delete(e.states, key)
+			changed = true
+			continue
+		case !active && firing:
+			resolvedAt := now
+			state.Status = server.AlertResolved
+			state.ResolvedAt = &resolvedAt
+			state.Value = sample.Value
+			transitions = append(transitions, *state)
+			changed = true
+			continue
+		}
+
+		state.Value = sample.Value
+		if state.Status == server.AlertPending && now.Sub(state.ActiveAt) >= rule.For {
+			firedAt := now
+			state.Status = server.AlertFiring
+			state.FiredAt = &firedAt
+			transitions = append(transitions, *state)
+			changed = true
+		}
+	}
+
+	for key, state := range e.states {
+		if key.rule != rule.Name || seen[key.series] && state.Status != server.AlertResolved {
+			continue
+		}
+		switch state.Status {
+		case server.AlertPending:
+			delete(e.states, key)
+			changed = true
+		case server.AlertFiring:
+			resolvedAt := now
+			state.Status = server.AlertResolved
+			state.ResolvedAt = &resolvedAt
+			transitions = append(transitions, *state)
+			changed = true
+		case server.AlertResolved:
+			if now.Sub(*state.ResolvedAt) >= resolvedRetention {
+				delete(e.states, key)
+				changed = true
+			}
+		}
+	}
+	return transitions, changed
+}
+
+func (e *Engine) snapshot() []server.AlertState {
+	states := make([]server.AlertState, 0, len(e.states))
+	for _, state := range e.states {
+		states = append(states, *state)
+	}
+	return states
+}
+
+// FindAllAlerts returns all rules with current states of their alerts
+func (e *Engine) FindAllAlerts(ctx context.Context) []server.AlertRuleStatus {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	result := make([]server.AlertRuleStatus, 0, len(e.rules))
+	for _, rule := range e.rules {
+		status := server.AlertRuleStatus{Name: rule.Name, Expr: rule.Expr, Hysteresis: rule.Hysteresis, Alerts: make([]*server.AlertState, 0)}
+		for key, state := range e.states {
+			if key.rule == rule.Name {
+				alert := *state
+				status.Alerts = append(status.Alerts, &alert)
+			}
+		}
+		sort.Slice(status.Alerts, func(i, j int) bool {
+			return status.Alerts[i].Series < status.Alerts[j].Series
+		})
+		result = append(result, status)
+	}
+	return result
+}
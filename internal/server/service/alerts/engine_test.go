@@ -0,0 +1,126 @@
+package alerts
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/expr"
+	"github.com/desepticon55/metrics-collector/internal/server/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type stubSource struct {
+	values map[string]float64
+}
+
+func (s *stubSource) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	var metrics []common.MetricResponseDto
+	for name, value := range s.values {
+		v := value
+		metrics = append(metrics, common.MetricResponseDto{ID: name, MType: common.Gauge, Value: &v})
+	}
+	return metrics
+}
+
+type stubNotifier struct {
+	transitions []server.AlertState
+}
+
+func (n *stubNotifier) Notify(transitions []server.AlertState) {
+	n.transitions = append(n.transitions, transitions...)
+}
+
+func alertStatus(engine *Engine, series string) server.AlertStatus {
+	for _, rule := range engine.FindAllAlerts(context.Background()) {
+		for _, alert := range rule.Alerts {
+			if alert.Series == series {
+				return alert.Status
+			}
+		}
+	}
+	return ""
+}
+
+func TestNewRule(t *testing.T) {
+	rule, err := NewRule(server.AlertRuleConfig{Name: "HighCPU", Expr: "CPUutilization* > 90 for 5m"})
+	assert.NoError(t, err)
+	assert.Equal(t, 5*time.Minute, rule.For)
+
+	rule, err = NewRule(server.AlertRuleConfig{Name: "LowMemory", Expr: "FreeMemory < 1e9"})
+	assert.NoError(t, err)
+	assert.Equal(t, time.Duration(0), rule.For)
+
+	_, err = NewRule(server.AlertRuleConfig{Name: "NotComparison", Expr: "FreeMemory + 1"})
+	assert.Error(t, err)
+
+	_, err = NewRule(server.AlertRuleConfig{Name: "BadDuration", Expr: "FreeMemory < 1 for soon"})
+	assert.Error(t, err)
+
+	_, err = NewRules([]server.AlertRuleConfig{{Name: "A", Expr: "a > 1"}, {Name: "A", Expr: "b > 1"}})
+	assert.Error(t, err)
+}
+
+func TestEngine_Evaluate(t *testing.T) {
+	rules, err := NewRules([]server.AlertRuleConfig{{Name: "HighCPU", Expr: "CPUutilization* > 90 for 5m", Hysteresis: 5}})
+	assert.NoError(t, err)
+
+	source := &stubSource{values: map[string]float64{"CPUutilization1": 95, "CPUutilization2": 10}}
+	storage := memory.NewAlertStorage("")
+	notifier := &stubNotifier{}
+	engine := New(rules, expr.NewEvaluator(source), storage, notifier, zap.NewNop())
+	ctx := context.Background()
+	start := time.Now()
+
+	assert.NoError(t, engine.Evaluate(ctx, start))
+	assert.Equal(t, server.AlertPending, alertStatus(engine, "CPUutilization1"))
+	assert.Equal(t, server.AlertStatus(""), alertStatus(engine, "CPUutilization2"))
+
+	assert.NoError(t, engine.Evaluate(ctx, start.Add(5*time.Minute)))
+	assert.Equal(t, server.AlertFiring, alertStatus(engine, "CPUutilization1"))
+
+	// value is below threshold but within hysteresis
+	source.values["CPUutilization1"] = 87
+	assert.NoError(t, engine.Evaluate(ctx, start.Add(6*time.Minute)))
+	assert.Equal(t, server.AlertFiring, alertStatus(engine, "CPUutilization1"))
+
+	source.values["CPUutilization1"] = 80
+	assert.NoError(t, engine.Evaluate(ctx, start.Add(7*time.Minute)))
+	assert.Equal(t, server.AlertResolved, alertStatus(engine, "CPUutilization1"))
+
+	if assert.Len(t, notifier.transitions, 2) {
+		assert.Equal(t, server.AlertFiring, notifier.transitions[0].Status)
+		assert.Equal(t, server.AlertResolved, notifier.transitions[1].Status)
+	}
+
+	assert.NoError(t, engine.Evaluate(ctx, start.Add(7*time.Minute+resolvedRetention)))
+	assert.Equal(t, server.AlertStatus(""), alertStatus(engine, "CPUutilization1"))
+}
+
+func TestEngine_PendingAlertIsDroppedAndStateIsRestored(t *testing.T) {
+	rules, err := NewRules([]server.AlertRuleConfig{
+		{Name: "LowMemory", Expr: "FreeMemory < 100"},
+		{Name: "HighCPU", Expr: "CPUutilization1 > 90 for 1m"},
+	})
+	assert.NoError(t, err)
+
+	source := &stubSource{values: map[string]float64{"FreeMemory": 50, "CPUutilization1": 95}}
+	storage := memory.NewAlertStorage("")
+	engine := New(rules, expr.NewEvaluator(source), storage, &stubNotifier{}, zap.NewNop())
+	ctx := context.Background()
+	now := time.Now()
+
+	assert.NoError(t, engine.Evaluate(ctx, now))
+	assert.Equal(t, server.AlertFiring, alertStatus(engine, "FreeMemory"))
+	assert.Equal(t, server.AlertPending, alertStatus(engine, "CPUutilization1"))
+
+	delete(source.values, "CPUutilization1")
+	assert.NoError(t, engine.Evaluate(ctx, now.Add(time.Second)))
+	assert.Equal(t, server.AlertStatus(""), alertStatus(engine, "CPUutilization1"))
+
+	restored := New(rules, expr.NewEvaluator(source), storage, &stubNotifier{}, zap.NewNop())
+	assert.NoError(t, restored.restore(ctx))
+	assert.Equal(t, server.AlertFiring, alertStatus(restored, "FreeMemory"))
+}
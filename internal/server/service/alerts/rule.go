@@ -0,0 +1,82 @@
+package alerts
+
+import (
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/expr"
+	"regexp"
+	"time"
+)
+
+var forClause = regexp.MustCompile(`^(.*?)\s+for\s+(\S+)\s*$`)
+
+// Rule is parsed alert rule "<expression> <comparison> <threshold> [for <duration>]"
+type Rule struct {
+	Name       string
+	Expr       string
+	For        time.Duration
+	Hysteresis float64
+	condition  *expr.Binary
+}
+
+func NewRule(config server.AlertRuleConfig) (Rule, error) {
+	if config.Name == "" {
+		return Rule{}, fmt.Errorf("alert rule name should be filled")
+	}
+	if config.Hysteresis < 0 {
+		return Rule{}, fmt.Errorf("alert rule '%s': hysteresis should not be negative", config.Name)
+	}
+
+	rule := Rule{Name: config.Name, Expr: config.Expr, Hysteresis: config.Hysteresis}
+	condition := config.Expr
+	if match := forClause.FindStringSubmatch(config.Expr); match != nil {
+		duration, err := time.ParseDuration(match[2])
+		if err != nil {
+			return Rule{}, fmt.Errorf("alert rule '%s': incorrect duration '%s': %w", config.Name, match[2], err)
+		}
+		condition = match[1]
+		rule.For = duration
+	}
+
+	node, err := expr.Parse(condition)
+	if err != nil {
+		return Rule{}, fmt.Errorf("alert rule '%s': %w", config.Name, err)
+	}
+	binary, ok := node.(*expr.Binary)
+	if !ok || !binary.IsComparison() {
+		return Rule{}, fmt.Errorf("alert rule '%s': expression should be a comparison", config.Name)
+	}
+	rule.condition = binary
+	return rule, nil
+}
+
+// NewRules parses all configured alert rules
+func NewRules(configs []server.AlertRuleConfig) ([]Rule, error) {
+	names := make(map[string]bool, len(configs))
+	rules := make([]Rule, 0, len(configs))
+	for _, config := range configs {
+		rule, err := NewRule(config)
+		if err != nil {
+			return nil, err
+		}
+		if names[rule.Name] {
+			return nil, fmt.Errorf("alert rule '%s' is duplicated", rule.Name)
+		}
+		names[rule.Name] = true
+		rules = append(rules, rule)
+	}
+	return rules, nil
+}
+
+// isActive checks condition for value. Firing alert keeps firing until value crosses threshold by hysteresis
+func (r Rule) isActive(value float64, threshold float64, firing bool) bool {
+	if firing && r.Hysteresis > 0 {
+		switch r.condition.Op {
+		case ">", ">=":
+			threshold -= r.Hysteresis
+		case "<", "<=":
+			threshold += r.Hysteresis
+		}
+	}
+	return expr.Compare(value, r.condition.Op, threshold)
+}
@@ -0,0 +1,7 @@
+package anomaly
+
+import "github.com/desepticon55/metrics-collector/internal/server"
+
+type notifier interface {
+	Notify(transitions []server.AlertState)
+}
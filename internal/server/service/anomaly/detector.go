@@ -0,0 +1,159 @@
+package anomaly
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"go.uber.org/zap"
+	"math"
+	"path"
+	"sort"
+	"sync"
+	"time"
+)
+
+// RuleName is used as rule of synthetic alerts raised by detector
+const RuleName = "anomaly"
+
+type baseline struct {
+	mean      float64
+	variance  float64
+	value     float64
+	zScore    float64
+	samples   int
+	anomalous bool
+	since     time.Time
+}
+
+// Detector learns EWMA mean and variance of every gauge as values are saved
+// and raises synthetic alerts when value deviates from baseline
+type Detector struct {
+	mu        sync.Mutex
+	config    server.AnomalyConfig
+	notifier  notifier
+	logger    *zap.Logger
+	baselines map[string]*baseline
+}
+
+func New(config server.AnomalyConfig, notifier notifier, logger *zap.Logger) *Detector {
+	return &Detector{
+		config:    config,
+		notifier:  notifier,
+		logger:    logger,
+		baselines: make(map[string]*baseline),
+	}
+}
+
+// Observe is a storage listener, it updates baselines of saved gauges
+func (d *Detector) Observe(metrics []server.Metric) {
+	if !d.config.Enabled {
+		return
+	}
+
+	now := time.Now()
+	var transitions []server.AlertState
+
+	d.mu.Lock()
+	for _, metric := range metrics {
+		gauge, ok := metric.(*server.Gauge)
+		if !ok || !d.matches(gauge.Name) || math.IsNaN(gauge.Value) || math.IsInf(gauge.Value, 0) {
+			continue
+		}
+		if transition, changed := d.observe(gauge.Name, gauge.Value, now); changed {
+			transitions = append(transitions, transition)
+		}
+	}
+	d.mu.Unlock()
+
+	for _, transition := range transitions {
+		d.logger.Info("Anomaly state was changed",
+			zap.String("series", transition.Series),
+			zap.String("status", string(transition.Status)),
+			zap.Float64("value", transition.Value))
+	}
+	if len(transitions) > 0 {
+		d.notifier.Notify(transitions)
+	}
+}
+
+func (d *Detector) matches(name string) bool {
+	if len(d.config.Metrics) == 0 {
+		return true
+	}
+	for _, pattern := range d.config.Metrics {
+		if matched, _ := path.Match(pattern, name); matched {
+			return true
+		}
+	}
+	return false
+}
+
+// observe scores value against current baseline and then updates baseline with it,
+// so persistent shift of level becomes new normal
+func (d *Detector) observe(name string, value float64, now time.Time) (server.AlertState, bool) {
+	b, ok := d.baselines[name]
+	if !ok {
+		d.baselines[name] = &baseline{mean: value, value: value, samples: 1}
+		return server.AlertState{}, false
+	}
+
+	diff := value - b.mean
+	b.zScore = 0
+	if stdDev := math.Sqrt(b.variance); stdDev > 0 {
+		b.zScore = diff / stdDev
+	}
+	b.value = value
+	b.samples++
+
+	increment := d.config.Alpha * diff
+	b.mean += increment
+	b.variance = (1 - d.config.Alpha) * (b.variance + diff*increment)
+
+	if b.samples <= d.config.Warmup {
+		return server.AlertState{}, false
+	}
+
+	outlier := math.Abs(b.zScore) > d.config.Threshold
+	switch {
+	case outlier && !b.anomalous:
+		b.anomalous = true
+		b.since = now
+		firedAt := now
+		return server.AlertState{Rule: RuleName, Series: name, Status: server.AlertFiring, Value: value, ActiveAt: now, FiredAt: &firedAt}, true
+	case !outlier && b.anomalous:
+		b.anomalous = false
+		resolvedAt := now
+		return server.AlertState{Rule: RuleName, Series: name, Status: server.AlertResolved, Value: value, ActiveAt: b.since, ResolvedAt: &resolvedAt}, true
+	}
+	return server.AlertState{}, false
+}
+
+// FindAllAnomalies returns baselines of all observed gauges, anomalous first
+func (d *Detector) FindAllAnomalies(ctx context.Context) []server.AnomalyBaseline {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := make([]server.AnomalyBaseline, 0, len(d.baselines))
+	for name, b := range d.baselines {
+		item := server.AnomalyBaseline{
+			Metric:    name,
+			Mean:      b.mean,
+			StdDev:    math.Sqrt(b.variance),
+			Value:     b.value,
+			ZScore:    b.zScore,
+			Samples:   b.samples,
+			Anomalous: b.anomalous,
+		}
+		if b.anomalous {
+			since := b.since
+			item.Since = &since
+		}
+		result = append(result, item)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Anomalous != result[j].Anomalous {
+			return result[i].Anomalous
+		}
+		return result[i].Metric < result[j].Metric
+	})
+	return result
+}
@@ -0,0 +1,66 @@
+package anomaly
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"testing"
+)
+
+type stubNotifier struct {
+	transitions []server.AlertState
+}
+
+func (n *stubNotifier) Notify(transitions []server.AlertState) {
+	n.transitions = append(n.transitions, transitions...)
+}
+
+func gauge(name string, value float64) server.Metric {
+	return &server.Gauge{BaseMetric: server.BaseMetric{Name: name, Type: common.Gauge}, Value: value}
+}
+
+func TestDetector_Observe(t *testing.T) {
+	notifier := &stubNotifier{}
+	detector := New(server.AnomalyConfig{Enabled: true, Alpha: 0.1, Threshold: 3, Warmup: 10, Metrics: []string{"Heap*"}}, notifier, zap.NewNop())
+
+	// normal range of values
+	for i := 0; i < 50; i++ {
+		detector.Observe([]server.Metric{gauge("HeapAlloc", 100+float64(i%5)), gauge("Other", 1000*float64(i%2))})
+	}
+	assert.Empty(t, notifier.transitions)
+
+	detector.Observe([]server.Metric{gauge("HeapAlloc", 200)})
+	if assert.Len(t, notifier.transitions, 1) {
+		assert.Equal(t, RuleName, notifier.transitions[0].Rule)
+		assert.Equal(t, "HeapAlloc", notifier.transitions[0].Series)
+		assert.Equal(t, server.AlertFiring, notifier.transitions[0].Status)
+	}
+
+	anomalies := detector.FindAllAnomalies(context.Background())
+	if assert.Len(t, anomalies, 1) {
+		assert.True(t, anomalies[0].Anomalous)
+		assert.Equal(t, 51, anomalies[0].Samples)
+		assert.Greater(t, anomalies[0].ZScore, 3.0)
+		assert.NotNil(t, anomalies[0].Since)
+	}
+
+	detector.Observe([]server.Metric{gauge("HeapAlloc", 110)})
+	if assert.Len(t, notifier.transitions, 2) {
+		assert.Equal(t, server.AlertResolved, notifier.transitions[1].Status)
+	}
+}
+
+func TestDetector_WarmupAndDisabled(t *testing.T) {
+	notifier := &stubNotifier{}
+	detector := New(server.AnomalyConfig{Enabled: true, Alpha: 0.1, Threshold: 3, Warmup: 10}, notifier, zap.NewNop())
+	for _, value := range []float64{1, 2, 1, 1000} {
+		detector.Observe([]server.Metric{gauge("HeapAlloc", value)})
+	}
+	assert.Empty(t, notifier.transitions)
+
+	disabled := New(server.AnomalyConfig{Alpha: 0.1, Threshold: 3}, notifier, zap.NewNop())
+	disabled.Observe([]server.Metric{gauge("HeapAlloc", 1)})
+	assert.Empty(t, disabled.FindAllAnomalies(context.Background()))
+}
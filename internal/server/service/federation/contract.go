@@ -0,0 +1,23 @@
+package federation
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+)
+
+type metricsService interface {
+	SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error)
+
+	FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error)
+
+	FindAllMetrics(ctx context.Context) []common.MetricResponseDto
+}
+
+type metricsSender interface {
+	SendMetrics(destination string, metrics []common.MetricRequestDto) error
+}
+
+// rejection is implemented by errors of sender when upstream rejects batch itself, so retry of the same batch fails too
+type rejection interface {
+	Rejected() bool
+}
@@ -0,0 +1,142 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Label of forwarded metrics with name of collector which accepted them
+const SourceLabel = "source"
+
+const (
+	minRetryDelay = 1 * time.Second
+	maxRetryDelay = 1 * time.Minute
+)
+
+// Forwarder wraps metrics service and forwards every accepted batch to upstream collector.
+// Batches are queued first, so upstream outage delays delivery instead of dropping data
+type Forwarder struct {
+	metricsService
+	queue       *Queue
+	sender      metricsSender
+	destination string
+	source      string
+	logger      *zap.Logger
+}
+
+func New(service metricsService, queue *Queue, sender metricsSender, destination string, source string, logger *zap.Logger) *Forwarder {
+	return &Forwarder{
+		metricsService: service,
+		queue:          queue,
+		sender:         sender,
+		destination:    destination,
+		source:         source,
+		logger:         logger,
+	}
+}
+
+// SaveMetrics saves metrics and queues them for forwarding. Failure of queueing does not fail saving.
+// When batch is saved partially only saved metrics are forwarded, failed ones are forwarded once client resends them
+func (f *Forwarder) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	response, err := f.metricsService.SaveMetrics(ctx, request)
+	var partialError *server.PartialSaveError
+	if errors.As(err, &partialError) {
+		request = withoutFailed(request, partialError.Failed)
+	} else if err != nil {
+		return response, err
+	}
+	if len(request) == 0 {
+		return response, err
+	}
+
+	dropped, queueErr := f.queue.Push(withSource(request, f.source))
+	if queueErr != nil {
+		f.logger.Error("Error during queue metrics for upstream", zap.Error(queueErr))
+	}
+	if dropped != nil {
+		f.logger.Warn("Upstream queue is full, the oldest batch is dropped", zap.Int("metrics", len(dropped)))
+	}
+	return response, err
+}
+
+// withoutFailed removes failed metrics from request. Request is forwarded instead of saved metrics,
+// because saved counters hold accumulated values instead of deltas
+func withoutFailed(request []common.MetricRequestDto, failed []server.FailedMetric) []common.MetricRequestDto {
+	type key struct {
+		id    string
+		mType common.MetricType
+	}
+	failedKeys := make(map[key]bool, len(failed))
+	for _, metric := range failed {
+		failedKeys[key{id: metric.ID, mType: metric.MType}] = true
+	}
+
+	result := make([]common.MetricRequestDto, 0, len(request))
+	for _, metric := range request {
+		if !failedKeys[key{id: metric.ID, mType: metric.MType}] {
+			result = append(result, metric)
+		}
+	}
+	return result
+}
+
+// withSource adds source label to metrics. Label set by downstream collector is kept
+func withSource(request []common.MetricRequestDto, source string) []common.MetricRequestDto {
+	result := make([]common.MetricRequestDto, 0, len(request))
+	for _, metric := range request {
+		name, labels := common.SplitLabels(metric.ID)
+		if _, ok := labels[SourceLabel]; !ok {
+			if labels == nil {
+				labels = make(map[string]string, 1)
+			}
+			labels[SourceLabel] = source
+		}
+		metric.ID = common.JoinLabels(name, labels)
+		result = append(result, metric)
+	}
+	return result
+}
+
+// Run delivers queued batches in order until context is done. Failed delivery is retried with exponential backoff.
+// Batch rejected by upstream (for example because of wrong key) is kept and retried with the longest delay,
+// so it's delivered once configuration is fixed instead of being lost
+func (f *Forwarder) Run(ctx context.Context) {
+	delay := minRetryDelay
+	for {
+		seq, batch, ok := f.queue.Peek()
+		if !ok {
+			select {
+			case <-ctx.Done():
+				return
+			case <-f.queue.Ready():
+				continue
+			}
+		}
+
+		if err := f.sender.SendMetrics(f.destination, batch); err != nil {
+			var rejected rejection
+			if errors.As(err, &rejected) && rejected.Rejected() {
+				delay = maxRetryDelay
+				f.logger.Error("Upstream rejected metrics, batch is kept in queue", zap.Error(err), zap.Int("queued", f.queue.Len()), zap.Duration("retry in", delay))
+			} else {
+				f.logger.Warn("Error during forward metrics to upstream", zap.Error(err), zap.Int("queued", f.queue.Len()), zap.Duration("retry in", delay))
+			}
+			select {
+			case <-ctx.Done():
+				return
+			case <-time.After(delay):
+			}
+			delay = min(delay*2, maxRetryDelay)
+			continue
+		}
+
+		delay = minRetryDelay
+		if err := f.queue.Ack(seq); err != nil {
+			f.logger.Error("Error during remove forwarded batch from queue", zap.Error(err))
+		}
+	}
+}
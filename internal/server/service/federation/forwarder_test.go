@@ -0,0 +1,148 @@
+package federation
+
+import (
+	"context"
+	"errors"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubService struct {
+	err error
+}
+
+func (s *stubService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	return nil, s.err
+}
+
+func (s *stubService) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error) {
+	return common.MetricResponseDto{}, nil
+}
+
+func (s *stubService) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	return nil
+}
+
+type stubSender struct {
+	mu       sync.Mutex
+	failures int
+	err      error
+	calls    int
+	sent     [][]common.MetricRequestDto
+}
+
+type rejectedError struct{}
+
+func (rejectedError) Error() string {
+	return "invalid signature"
+}
+
+func (rejectedError) Rejected() bool {
+	return true
+}
+
+func (s *stubSender) SendMetrics(destination string, metrics []common.MetricRequestDto) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.calls++
+	if s.err != nil {
+		return s.err
+	}
+	if s.failures > 0 {
+		s.failures--
+		return errors.New("upstream is unavailable")
+	}
+	s.sent = append(s.sent, metrics)
+	return nil
+}
+
+func (s *stubSender) sentBatches() [][]common.MetricRequestDto {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([][]common.MetricRequestDto(nil), s.sent...)
+}
+
+func TestForwarder_SaveMetrics(t *testing.T) {
+	queue, _ := NewQueue("", 10)
+	forwarder := New(&stubService{}, queue, &stubSender{}, "upstream", "dc1", zap.NewNop())
+
+	_, err := forwarder.SaveMetrics(context.Background(), append(batch("Alloc"), batch("disk;mount=/")[0], batch("cpu;source=edge")[0]))
+	assert.NoError(t, err)
+
+	_, queued, ok := queue.Peek()
+	if assert.True(t, ok) {
+		ids := make([]string, 0, len(queued))
+		for _, metric := range queued {
+			ids = append(ids, metric.ID)
+		}
+		assert.Equal(t, []string{"Alloc;source=dc1", "disk;mount=/;source=dc1", "cpu;source=edge"}, ids)
+	}
+
+	// rejected batch is not forwarded
+	forwarder = New(&stubService{err: errors.New("invalid metric")}, queue, &stubSender{}, "upstream", "dc1", zap.NewNop())
+	_, err = forwarder.SaveMetrics(context.Background(), batch("Alloc"))
+	assert.Error(t, err)
+	assert.Equal(t, 1, queue.Len())
+
+	// only saved part of partially saved batch is forwarded
+	partialError := &server.PartialSaveError{Failed: []server.FailedMetric{{ID: "Frees", MType: common.Gauge, Error: "shard is unavailable"}}}
+	forwarder = New(&stubService{err: partialError}, queue, &stubSender{}, "upstream", "dc1", zap.NewNop())
+	_, err = forwarder.SaveMetrics(context.Background(), append(batch("Alloc"), batch("Frees")...))
+	assert.ErrorAs(t, err, &partialError)
+	if assert.Equal(t, 2, queue.Len()) {
+		seq, _, _ := queue.Peek()
+		assert.NoError(t, queue.Ack(seq))
+		_, queued, _ := queue.Peek()
+		assert.Equal(t, []common.MetricRequestDto{withSource(batch("Alloc"), "dc1")[0]}, queued)
+	}
+}
+
+func TestForwarder_RunRetriesUntilDelivered(t *testing.T) {
+	queue, _ := NewQueue("", 10)
+	sender := &stubSender{failures: 1}
+	forwarder := New(&stubService{}, queue, sender, "upstream", "dc1", zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go forwarder.Run(ctx)
+
+	_, err := forwarder.SaveMetrics(ctx, batch("Alloc"))
+	assert.NoError(t, err)
+	_, err = forwarder.SaveMetrics(ctx, batch("Frees"))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		return len(sender.sentBatches()) == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, "Alloc;source=dc1", sender.sentBatches()[0][0].ID)
+	assert.Equal(t, "Frees;source=dc1", sender.sentBatches()[1][0].ID)
+	assert.Equal(t, 0, queue.Len())
+}
+
+func TestForwarder_RunKeepsRejectedBatch(t *testing.T) {
+	queue, _ := NewQueue("", 10)
+	sender := &stubSender{err: rejectedError{}}
+	forwarder := New(&stubService{}, queue, sender, "upstream", "dc1", zap.NewNop())
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go forwarder.Run(ctx)
+
+	_, err := forwarder.SaveMetrics(ctx, batch("Alloc"))
+	assert.NoError(t, err)
+
+	assert.Eventually(t, func() bool {
+		sender.mu.Lock()
+		defer sender.mu.Unlock()
+		return sender.calls == 1
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, 1, queue.Len())
+	assert.Empty(t, sender.sentBatches())
+}
@@ -0,0 +1,189 @@
+package federation
+
+import (
+	"encoding/json"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const batchFileExtension = ".json"
+
+// Queue is FIFO of metric batches waiting for delivery. Every batch is kept in separate file of directory,
+// so queue survives restart. Empty directory disables persistence
+type Queue struct {
+	mu       sync.Mutex
+	dir      string
+	capacity int
+	seqs     []uint64
+	batches  map[uint64][]common.MetricRequestDto
+	next     uint64
+	ready    chan struct{}
+}
+
+// NewQueue creates queue holding up to capacity batches and loads batches left in directory
+func NewQueue(dir string, capacity int) (*Queue, error) {
+	q := &Queue{
+		dir:      dir,
+		capacity: capacity,
+		batches:  make(map[uint64][]common.MetricRequestDto),
+		next:     1,
+		ready:    make(chan struct{}, 1),
+	}
+	if dir == "" {
+		return q, nil
+	}
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	if err := q.load(); err != nil {
+		return nil, err
+	}
+	return q, nil
+}
+
+func (q *Queue) load() error {
+	entries, err := os.ReadDir(q.dir)
+	if err != nil {
+		return err
+	}
+	for _, entry := range entries {
+		seq, err := strconv.ParseUint(strings.TrimSuffix(entry.Name(), batchFileExtension), 10, 64)
+		if err != nil || entry.IsDir() || !strings.HasSuffix(entry.Name(), batchFileExtension) {
+			continue
+		}
+		content, err := os.ReadFile(filepath.Join(q.dir, entry.Name()))
+		if err != nil {
+			return err
+		}
+		var batch []common.MetricRequestDto
+		if err := json.Unmarshal(content, &batch); err != nil {
+			return fmt.Errorf("queued batch '%s': %w", entry.Name(), err)
+		}
+		q.seqs = append(q.seqs, seq)
+		q.batches[seq] = batch
+		q.next = max(q.next, seq+1)
+	}
+	sort.Slice(q.seqs, func(i, j int) bool {
+		return q.seqs[i] < q.seqs[j]
+	})
+	if len(q.seqs) > 0 {
+		q.signal()
+	}
+	return nil
+}
+
+// Push appends batch to the tail of queue. When queue is full the oldest batch is dropped and returned
+func (q *Queue) Push(batch []common.MetricRequestDto) ([]common.MetricRequestDto, error) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	seq := q.next
+	if err := q.write(seq, batch); err != nil {
+		return nil, err
+	}
+	q.next++
+	q.seqs = append(q.seqs, seq)
+	q.batches[seq] = batch
+
+	var dropped []common.MetricRequestDto
+	if q.capacity > 0 && len(q.seqs) > q.capacity {
+		oldest := q.seqs[0]
+		dropped = q.batches[oldest]
+		if err := q.remove(oldest); err != nil {
+			return nil, err
+		}
+	}
+	q.signal()
+	return dropped, nil
+}
+
+// Peek returns the oldest batch with its sequence number without removing it
+func (q *Queue) Peek() (uint64, []common.MetricRequestDto, bool) {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if len(q.seqs) == 0 {
+		return 0, nil, false
+	}
+	return q.seqs[0], q.batches[q.seqs[0]], true
+}
+
+// Ack removes delivered batch. Batch already dropped from queue is ignored
+func (q *Queue) Ack(seq uint64) error {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	if _, ok := q.batches[seq]; !ok {
+		return nil
+	}
+	return q.remove(seq)
+}
+
+// Len returns number of batches waiting for delivery
+func (q *Queue) Len() int {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	return len(q.seqs)
+}
+
+// Ready is signalled when batch is pushed to queue
+func (q *Queue) Ready() <-chan struct{} {
+	return q.ready
+}
+
+func (q *Queue) signal() {
+	select {
+	case q.ready <- struct{}{}:
+	default:
+	}
+}
+
+// remove drops batch from queue even when its file can't be deleted, so delivered batch is not sent again until restart
+func (q *Queue) remove(seq uint64) error {
+	delete(q.batches, seq)
+	for i, queued := range q.seqs {
+		if queued == seq {
+			q.seqs = append(q.seqs[:i], q.seqs[i+1:]...)
+			break
+		}
+	}
+	if q.dir == "" {
+		return nil
+	}
+	if err := os.Remove(q.path(seq)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// write stores batch to temporary file and renames it, so partially written batch is never loaded
+func (q *Queue) write(seq uint64, batch []common.MetricRequestDto) error {
+	if q.dir == "" {
+		return nil
+	}
+	file, err := os.CreateTemp(q.dir, "batch.*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := json.NewEncoder(file).Encode(batch); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), q.path(seq))
+}
+
+func (q *Queue) path(seq uint64) string {
+	return filepath.Join(q.dir, fmt.Sprintf("%020d%s", seq, batchFileExtension))
+}
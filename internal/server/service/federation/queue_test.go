@@ -0,0 +1,58 @@
+package federation
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func batch(id string) []common.MetricRequestDto {
+	value := 1.5
+	return []common.MetricRequestDto{{ID: id, MType: common.Gauge, Value: &value}}
+}
+
+func TestQueue_PersistsBatches(t *testing.T) {
+	dir := t.TempDir()
+	queue, err := NewQueue(dir, 10)
+	assert.NoError(t, err)
+
+	for _, id := range []string{"first", "second", "third"} {
+		dropped, err := queue.Push(batch(id))
+		assert.NoError(t, err)
+		assert.Nil(t, dropped)
+	}
+	seq, _, ok := queue.Peek()
+	assert.True(t, ok)
+	assert.NoError(t, queue.Ack(seq))
+
+	restored, err := NewQueue(dir, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, restored.Len())
+	_, first, ok := restored.Peek()
+	assert.True(t, ok)
+	assert.Equal(t, batch("second"), first)
+
+	// sequence continues after restored batches
+	_, err = restored.Push(batch("fourth"))
+	assert.NoError(t, err)
+	restored, err = NewQueue(dir, 10)
+	assert.NoError(t, err)
+	assert.Equal(t, 3, restored.Len())
+}
+
+func TestQueue_DropsOldestWhenFull(t *testing.T) {
+	queue, err := NewQueue("", 2)
+	assert.NoError(t, err)
+
+	for _, id := range []string{"first", "second"} {
+		_, err := queue.Push(batch(id))
+		assert.NoError(t, err)
+	}
+	dropped, err := queue.Push(batch("third"))
+	assert.NoError(t, err)
+	assert.Equal(t, batch("first"), dropped)
+	assert.Equal(t, 2, queue.Len())
+
+	_, oldest, _ := queue.Peek()
+	assert.Equal(t, batch("second"), oldest)
+}
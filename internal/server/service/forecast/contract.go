@@ -0,0 +1,12 @@
+package forecast
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server/service/history"
+	"time"
+)
+
+type historySource interface {
+	FindPoints(ctx context.Context, name string, metricType common.MetricType, since time.Time) []history.Point
+}
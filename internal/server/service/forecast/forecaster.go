@@ -0,0 +1,119 @@
+package forecast
+
+import (
+	"context"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server/expr"
+	"time"
+)
+
+// Forecaster projects gauges using their recent history
+type Forecaster struct {
+	history historySource
+}
+
+func New(history historySource) *Forecaster {
+	return &Forecaster{history: history}
+}
+
+// Forecast fits model over window of gauge history and projects it ahead of current moment
+func (f *Forecaster) Forecast(ctx context.Context, name string, method Method, window time.Duration, ahead time.Duration, threshold *float64) (common.ForecastResponseDto, error) {
+	now := time.Now()
+	points := f.history.FindPoints(ctx, name, common.Gauge, now.Add(-window))
+	m, err := fit(method, points)
+	if err != nil {
+		return common.ForecastResponseDto{}, fmt.Errorf("gauge '%s': %w", name, err)
+	}
+
+	at := now.Add(ahead)
+	response := common.ForecastResponseDto{
+		ID:        name,
+		MType:     common.Gauge,
+		Method:    string(method),
+		Window:    window.String(),
+		Samples:   len(points),
+		Slope:     m.slope,
+		At:        at,
+		Value:     m.valueAt(at),
+		Threshold: threshold,
+	}
+	if threshold != nil {
+		if crossesAt, ok := m.crossing(*threshold); ok {
+			response.CrossesAt = &crossesAt
+			response.CrossesIn = max(crossesAt.Sub(now), 0).Round(time.Second).String()
+		}
+	}
+	return response, nil
+}
+
+// RegisterFunctions makes forecasts available in expressions:
+// predict_linear(gauges, window, ahead) and predict_holt(gauges, window, ahead) return projected values,
+// time_to_threshold(gauges, window, threshold) returns seconds until linear trend reaches threshold.
+// Series without enough history or not moving to threshold are dropped
+func (f *Forecaster) RegisterFunctions(evaluator *expr.Evaluator) {
+	evaluator.RegisterFunction("predict_linear", f.predict("predict_linear", Linear))
+	evaluator.RegisterFunction("predict_holt", f.predict("predict_holt", Holt))
+	evaluator.RegisterFunction("time_to_threshold", func(ctx context.Context, args []expr.Vector) (expr.Vector, error) {
+		window, threshold, err := scalarArgs("time_to_threshold", args)
+		if err != nil {
+			return nil, err
+		}
+
+		now := time.Now()
+		return f.apply(ctx, args[0], Linear, window, func(m model) (float64, bool) {
+			crossesAt, ok := m.crossing(threshold)
+			if !ok {
+				return 0, false
+			}
+			return max(crossesAt.Sub(now), 0).Seconds(), true
+		}), nil
+	})
+}
+
+func (f *Forecaster) predict(name string, method Method) expr.Function {
+	return func(ctx context.Context, args []expr.Vector) (expr.Vector, error) {
+		window, ahead, err := scalarArgs(name, args)
+		if err != nil {
+			return nil, err
+		}
+
+		at := time.Now().Add(seconds(ahead))
+		return f.apply(ctx, args[0], method, window, func(m model) (float64, bool) {
+			return m.valueAt(at), true
+		}), nil
+	}
+}
+
+func (f *Forecaster) apply(ctx context.Context, series expr.Vector, method Method, window float64, project func(m model) (float64, bool)) expr.Vector {
+	since := time.Now().Add(-seconds(window))
+	result := make(expr.Vector, 0, len(series))
+	for _, sample := range series {
+		m, err := fit(method, f.history.FindPoints(ctx, sample.Name, common.Gauge, since))
+		if err != nil {
+			continue
+		}
+		if value, ok := project(m); ok {
+			result = append(result, expr.Sample{Name: sample.Name, Value: value})
+		}
+	}
+	return result
+}
+
+// scalarArgs checks that function got series and two scalars
+func scalarArgs(name string, args []expr.Vector) (float64, float64, error) {
+	if len(args) != 3 {
+		return 0, 0, fmt.Errorf("%s expects 3 arguments, got %d", name, len(args))
+	}
+	if len(args[1]) != 1 || len(args[2]) != 1 {
+		return 0, 0, fmt.Errorf("%s expects scalar second and third arguments", name)
+	}
+	if args[1][0].Value <= 0 {
+		return 0, 0, fmt.Errorf("%s expects positive window", name)
+	}
+	return args[1][0].Value, args[2][0].Value, nil
+}
+
+func seconds(value float64) time.Duration {
+	return time.Duration(value * float64(time.Second))
+}
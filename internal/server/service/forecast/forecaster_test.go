@@ -0,0 +1,99 @@
+package forecast
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server/expr"
+	"github.com/desepticon55/metrics-collector/internal/server/service/history"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+type stubHistory map[string][]history.Point
+
+func (h stubHistory) FindPoints(ctx context.Context, name string, metricType common.MetricType, since time.Time) []history.Point {
+	var result []history.Point
+	for _, point := range h[name] {
+		if !point.Time.Before(since) {
+			result = append(result, point)
+		}
+	}
+	return result
+}
+
+func (h stubHistory) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	var result []common.MetricResponseDto
+	for name, points := range h {
+		value := points[len(points)-1].Value
+		result = append(result, common.MetricResponseDto{ID: name, MType: common.Gauge, Value: &value})
+	}
+	return result
+}
+
+// linearHistory returns points of last 10 minutes of gauge decreasing by 1 per second down to 3600 now
+func linearHistory() stubHistory {
+	now := time.Now()
+	var points []history.Point
+	for i := 600; i >= 0; i -= 10 {
+		points = append(points, history.Point{Time: now.Add(-time.Duration(i) * time.Second), Value: 3600 + float64(i)})
+	}
+	return stubHistory{"FreeMemory": points, "Single": points[:1]}
+}
+
+func TestForecaster_Forecast(t *testing.T) {
+	forecaster := New(linearHistory())
+	threshold := 0.0
+
+	for _, method := range []Method{Linear, Holt} {
+		response, err := forecaster.Forecast(context.Background(), "FreeMemory", method, time.Hour, 30*time.Minute, &threshold)
+		assert.NoError(t, err)
+		assert.Equal(t, 61, response.Samples)
+		assert.InDelta(t, -1.0, response.Slope, 1e-6)
+		assert.InDelta(t, 1800.0, response.Value, 1)
+		if assert.NotNil(t, response.CrossesAt) {
+			assert.InDelta(t, time.Hour.Seconds(), time.Until(*response.CrossesAt).Seconds(), 1)
+		}
+	}
+
+	above := 5000.0
+	response, err := forecaster.Forecast(context.Background(), "FreeMemory", Linear, time.Hour, time.Hour, &above)
+	assert.NoError(t, err)
+	assert.Nil(t, response.CrossesAt)
+	assert.Empty(t, response.CrossesIn)
+
+	_, err = forecaster.Forecast(context.Background(), "Single", Linear, time.Hour, time.Hour, nil)
+	assert.ErrorIs(t, err, history.ErrNotEnoughHistory)
+}
+
+func TestForecaster_RegisterFunctions(t *testing.T) {
+	source := linearHistory()
+	evaluator := expr.NewEvaluator(source)
+	New(source).RegisterFunctions(evaluator)
+
+	tests := []struct {
+		expr     string
+		expected float64
+	}{
+		{expr: "predict_linear(FreeMemory, 10m, 30m)", expected: 1800},
+		{expr: "predict_holt(FreeMemory, 10m, 30m)", expected: 1800},
+		{expr: "time_to_threshold(FreeMemory, 10m, 0)", expected: 3600},
+		{expr: "time_to_threshold(FreeMemory, 10m, 0) < 3h", expected: 3600},
+	}
+	for _, tt := range tests {
+		node, err := expr.Parse(tt.expr)
+		assert.NoError(t, err)
+
+		result, err := evaluator.Eval(context.Background(), node)
+		assert.NoError(t, err, tt.expr)
+		if assert.Len(t, result, 1, tt.expr) {
+			assert.Equal(t, "FreeMemory", result[0].Name)
+			assert.InDelta(t, tt.expected, result[0].Value, 1, tt.expr)
+		}
+	}
+
+	node, err := expr.Parse("predict_linear(FreeMemory, 10m)")
+	assert.NoError(t, err)
+	_, err = evaluator.Eval(context.Background(), node)
+	assert.Error(t, err)
+}
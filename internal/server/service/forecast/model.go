@@ -0,0 +1,103 @@
+package forecast
+
+import (
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server/service/history"
+	"time"
+)
+
+type Method string
+
+const (
+	Linear Method = "linear"
+	// Holt is Holt's linear trend method, i.e. Holt-Winters smoothing without seasonal component
+	Holt Method = "holt"
+)
+
+// Smoothing factors of level and trend of Holt method
+const (
+	holtAlpha = 0.5
+	holtBeta  = 0.3
+)
+
+// model projects value as level + slope * (seconds since origin)
+type model struct {
+	origin time.Time
+	level  float64
+	slope  float64
+}
+
+func (m model) valueAt(at time.Time) float64 {
+	return m.level + m.slope*at.Sub(m.origin).Seconds()
+}
+
+// crossing returns moment when trend reaches threshold. It is false when trend moves away from threshold
+func (m model) crossing(threshold float64) (time.Time, bool) {
+	if m.level == threshold {
+		return m.origin, true
+	}
+	if m.slope == 0 {
+		return time.Time{}, false
+	}
+	seconds := (threshold - m.level) / m.slope
+	if seconds < 0 {
+		return time.Time{}, false
+	}
+	return m.origin.Add(time.Duration(seconds * float64(time.Second))), true
+}
+
+func fit(method Method, points []history.Point) (model, error) {
+	if len(points) < 2 || !points[len(points)-1].Time.After(points[0].Time) {
+		return model{}, history.ErrNotEnoughHistory
+	}
+
+	switch method {
+	case Linear:
+		return fitLinear(points), nil
+	case Holt:
+		return fitHolt(points), nil
+	default:
+		return model{}, fmt.Errorf("unknown forecast method '%s'", method)
+	}
+}
+
+// fitLinear fits least squares line. Level is fitted value at the last point
+func fitLinear(points []history.Point) model {
+	origin := points[len(points)-1].Time
+	n := float64(len(points))
+
+	var sumX, sumY, sumXY, sumXX float64
+	for _, point := range points {
+		x := point.Time.Sub(origin).Seconds()
+		sumX += x
+		sumY += point.Value
+		sumXY += x * point.Value
+		sumXX += x * x
+	}
+
+	slope := (n*sumXY - sumX*sumY) / (n*sumXX - sumX*sumX)
+	intercept := (sumY - slope*sumX) / n
+	return model{origin: origin, level: intercept, slope: slope}
+}
+
+// fitHolt smooths level and trend. Points are not evenly spaced, so trend is kept per second
+func fitHolt(points []history.Point) model {
+	level := points[0].Value
+	slope := 0.0
+	previous := points[0].Time
+	for i, point := range points[1:] {
+		dt := point.Time.Sub(previous).Seconds()
+		if dt <= 0 {
+			continue
+		}
+		if i == 0 {
+			slope = (point.Value - level) / dt
+		}
+
+		previousLevel := level
+		level = holtAlpha*point.Value + (1-holtAlpha)*(level+slope*dt)
+		slope = holtBeta*(level-previousLevel)/dt + (1-holtBeta)*slope
+		previous = point.Time
+	}
+	return model{origin: previous, level: level, slope: slope}
+}
@@ -0,0 +1,133 @@
+package history
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Upper bound of points kept for single series regardless of retention
+const maxPoints = 10000
+
+var ErrNotEnoughHistory = errors.New("not enough history")
+
+// Point is a value of metric at the moment it was saved
+type Point struct {
+	Time  time.Time `json:"time"`
+	Value float64   `json:"value"`
+}
+
+type seriesKey struct {
+	name       string
+	metricType common.MetricType
+}
+
+// History keeps recent values of every metric in memory as they are saved
+type History struct {
+	mu        sync.RWMutex
+	retention time.Duration
+	series    map[seriesKey][]Point
+}
+
+func New(retention time.Duration) *History {
+	return &History{retention: retention, series: make(map[seriesKey][]Point)}
+}
+
+// Observe is a storage listener, it appends saved values to history
+func (h *History) Observe(metrics []server.Metric) {
+	h.observe(metrics, time.Now())
+}
+
+func (h *History) observe(metrics []server.Metric, now time.Time) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+
+	for _, metric := range metrics {
+		var value float64
+		switch m := metric.(type) {
+		case *server.Gauge:
+			value = m.Value
+		case *server.Counter:
+			value = float64(m.Value)
+		default:
+			continue
+		}
+
+		key := seriesKey{name: metric.GetName(), metricType: metric.GetType()}
+		points := append(h.series[key], Point{Time: now, Value: value})
+
+		expired := sort.Search(len(points), func(i int) bool {
+			return now.Sub(points[i].Time) <= h.retention
+		})
+		if len(points)-expired > maxPoints {
+			expired = len(points) - maxPoints
+		}
+		// expired points are resliced away, append moves retained ones to new array when capacity is exhausted
+		points = points[expired:]
+		h.series[key] = points
+	}
+}
+
+// FindPoints returns values of metric saved since the moment
+func (h *History) FindPoints(ctx context.Context, name string, metricType common.MetricType, since time.Time) []Point {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	points := h.series[seriesKey{name: name, metricType: metricType}]
+	first := sort.Search(len(points), func(i int) bool {
+		return !points[i].Time.Before(since)
+	})
+	return append([]Point(nil), points[first:]...)
+}
+
+// CounterRate computes per-second rate of counter over window. The last point before window
+// is used as a base, window without points has not enough history.
+// Decrease of value is treated as counter reset, so value after reset is counted as increase
+func (h *History) CounterRate(ctx context.Context, name string, window time.Duration) (float64, int, error) {
+	return h.counterRate(name, window, time.Now())
+}
+
+func (h *History) counterRate(name string, window time.Duration, now time.Time) (float64, int, error) {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	points := h.series[seriesKey{name: name, metricType: common.Counter}]
+	if len(points) < 2 {
+		return 0, 0, fmt.Errorf("%w for counter '%s'", ErrNotEnoughHistory, name)
+	}
+
+	start := now.Add(-window)
+	first := sort.Search(len(points), func(i int) bool {
+		return !points[i].Time.Before(start)
+	})
+	if first == len(points) {
+		return 0, 0, fmt.Errorf("%w for counter '%s' in window %s", ErrNotEnoughHistory, name, window)
+	}
+	if first > 0 && points[first].Time.After(start) {
+		first--
+	}
+	selected := points[first:]
+	if len(selected) < 2 {
+		return 0, 0, fmt.Errorf("%w for counter '%s' in window %s", ErrNotEnoughHistory, name, window)
+	}
+
+	increase := 0.0
+	for i := 1; i < len(selected); i++ {
+		delta := selected[i].Value - selected[i-1].Value
+		if delta < 0 {
+			delta = selected[i].Value
+		}
+		increase += delta
+	}
+
+	elapsed := selected[len(selected)-1].Time.Sub(selected[0].Time).Seconds()
+	if elapsed <= 0 {
+		return 0, 0, fmt.Errorf("%w for counter '%s'", ErrNotEnoughHistory, name)
+	}
+	return increase / elapsed, len(selected), nil
+}
@@ -0,0 +1,77 @@
+package history
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/stretchr/testify/assert"
+	"testing"
+	"time"
+)
+
+func counter(name string, value int64) server.Metric {
+	return &server.Counter{BaseMetric: server.BaseMetric{Name: name, Type: common.Counter}, Value: value}
+}
+
+func gauge(name string, value float64) server.Metric {
+	return &server.Gauge{BaseMetric: server.BaseMetric{Name: name, Type: common.Gauge}, Value: value}
+}
+
+func TestHistory_CounterRate(t *testing.T) {
+	history := New(time.Hour)
+	start := time.Now().Add(-10 * time.Minute)
+
+	// counter grows by 10 every 10 seconds
+	for i := 0; i <= 60; i++ {
+		history.observe([]server.Metric{counter("PollCount", int64(i*10))}, start.Add(time.Duration(i)*10*time.Second))
+	}
+	now := start.Add(10 * time.Minute)
+
+	rate, samples, err := history.counterRate("PollCount", time.Minute, now)
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.0, rate, 1e-9)
+	assert.Equal(t, 7, samples)
+
+	// point before window is a base of the first point in window
+	rate, samples, err = history.counterRate("PollCount", time.Minute, now.Add(55*time.Second))
+	assert.NoError(t, err)
+	assert.InDelta(t, 1.0, rate, 1e-9)
+	assert.Equal(t, 2, samples)
+
+	// window without points has not enough history
+	_, _, err = history.counterRate("PollCount", time.Minute, now.Add(time.Hour))
+	assert.ErrorIs(t, err, ErrNotEnoughHistory)
+
+	_, _, err = history.counterRate("Unknown", time.Minute, now)
+	assert.ErrorIs(t, err, ErrNotEnoughHistory)
+}
+
+func TestHistory_CounterReset(t *testing.T) {
+	history := New(time.Hour)
+	start := time.Now()
+
+	history.observe([]server.Metric{counter("Requests", 100)}, start)
+	history.observe([]server.Metric{counter("Requests", 150)}, start.Add(10*time.Second))
+	// server was restarted without restore
+	history.observe([]server.Metric{counter("Requests", 30)}, start.Add(20*time.Second))
+
+	rate, _, err := history.counterRate("Requests", time.Minute, start.Add(20*time.Second))
+	assert.NoError(t, err)
+	assert.InDelta(t, 4.0, rate, 1e-9)
+}
+
+func TestHistory_Retention(t *testing.T) {
+	history := New(time.Minute)
+	start := time.Now().Add(-5 * time.Minute)
+
+	for i := 0; i < 5; i++ {
+		history.observe([]server.Metric{gauge("Alloc", float64(i))}, start.Add(time.Duration(i)*time.Minute))
+	}
+
+	points := history.FindPoints(context.Background(), "Alloc", common.Gauge, time.Time{})
+	if assert.Len(t, points, 2) {
+		assert.Equal(t, 3.0, points[0].Value)
+		assert.Equal(t, 4.0, points[1].Value)
+	}
+	assert.Empty(t, history.FindPoints(context.Background(), "Alloc", common.Counter, time.Time{}))
+}
@@ -0,0 +1,199 @@
+package notify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"net"
+	"net/http"
+	"net/smtp"
+	"strings"
+	"time"
+)
+
+// Notification is a group of alert transitions of the same rule
+type Notification struct {
+	Rule   string              `json:"rule"`
+	Alerts []server.AlertState `json:"alerts"`
+}
+
+// Channel delivers notifications to single destination
+type Channel interface {
+	Name() string
+
+	Send(ctx context.Context, notification Notification) error
+}
+
+// permanentError is returned when repeated delivery can not succeed
+type permanentError struct {
+	err error
+}
+
+func (e permanentError) Error() string {
+	return e.err.Error()
+}
+
+func (e permanentError) Unwrap() error {
+	return e.err
+}
+
+// NewChannels creates channels from configuration
+func NewChannels(configs []server.NotificationChannelConfig) ([]Channel, error) {
+	channels := make([]Channel, 0, len(configs))
+	names := make(map[string]bool, len(configs))
+	for i, config := range configs {
+		name := config.Name
+		if name == "" {
+			name = fmt.Sprintf("%s-%d", config.Type, i)
+		}
+		if names[name] {
+			return nil, fmt.Errorf("duplicate notification channel '%s'", name)
+		}
+		names[name] = true
+
+		switch config.Type {
+		case "webhook":
+			if config.URL == "" {
+				return nil, fmt.Errorf("notification channel '%s' should have url", name)
+			}
+			channels = append(channels, NewWebhookChannel(name, config.URL))
+		case "slack":
+			if config.URL == "" {
+				return nil, fmt.Errorf("notification channel '%s' should have url", name)
+			}
+			channels = append(channels, NewSlackChannel(name, config.URL))
+		case "email":
+			if config.SMTPAddress == "" || config.From == "" || len(config.To) == 0 {
+				return nil, fmt.Errorf("notification channel '%s' should have smtp_address, from and to", name)
+			}
+			channels = append(channels, NewEmailChannel(name, config))
+		default:
+			return nil, fmt.Errorf("unknown type '%s' of notification channel '%s'", config.Type, name)
+		}
+	}
+	return channels, nil
+}
+
+// WebhookChannel posts notification as JSON
+type WebhookChannel struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func NewWebhookChannel(name string, url string) *WebhookChannel {
+	return &WebhookChannel{name: name, url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (c *WebhookChannel) Name() string {
+	return c.name
+}
+
+func (c *WebhookChannel) Send(ctx context.Context, notification Notification) error {
+	return postJSON(ctx, c.client, c.url, notification)
+}
+
+// SlackChannel posts notification in format of Slack incoming webhook
+type SlackChannel struct {
+	name   string
+	url    string
+	client *http.Client
+}
+
+func NewSlackChannel(name string, url string) *SlackChannel {
+	return &SlackChannel{name: name, url: url, client: &http.Client{Timeout: 5 * time.Second}}
+}
+
+func (c *SlackChannel) Name() string {
+	return c.name
+}
+
+func (c *SlackChannel) Send(ctx context.Context, notification Notification) error {
+	payload := map[string]string{"text": subject(notification) + "\n" + body(notification)}
+	return postJSON(ctx, c.client, c.url, payload)
+}
+
+func postJSON(ctx context.Context, client *http.Client, url string, payload any) error {
+	content, err := json.Marshal(payload)
+	if err != nil {
+		return permanentError{err: err}
+	}
+
+	request, err := http.NewRequestWithContext(ctx, http.MethodPost, url, bytes.NewReader(content))
+	if err != nil {
+		return permanentError{err: err}
+	}
+	request.Header.Set("Content-Type", "application/json")
+
+	response, err := client.Do(request)
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	if response.StatusCode >= 300 {
+		err := fmt.Errorf("unexpected response status %d", response.StatusCode)
+		// client errors will not be fixed by retry except of rate limiting
+		if response.StatusCode < 500 && response.StatusCode != http.StatusTooManyRequests {
+			return permanentError{err: err}
+		}
+		return err
+	}
+	return nil
+}
+
+// EmailChannel sends notification as plain text email via SMTP
+type EmailChannel struct {
+	name    string
+	address string
+	auth    smtp.Auth
+	from    string
+	to      []string
+}
+
+func NewEmailChannel(name string, config server.NotificationChannelConfig) *EmailChannel {
+	var auth smtp.Auth
+	if config.SMTPUsername != "" {
+		host, _, _ := net.SplitHostPort(config.SMTPAddress)
+		auth = smtp.PlainAuth("", config.SMTPUsername, config.SMTPPassword, host)
+	}
+	return &EmailChannel{name: name, address: config.SMTPAddress, auth: auth, from: config.From, to: config.To}
+}
+
+func (c *EmailChannel) Name() string {
+	return c.name
+}
+
+func (c *EmailChannel) Send(ctx context.Context, notification Notification) error {
+	var message strings.Builder
+	message.WriteString("From: " + c.from + "\r\n")
+	message.WriteString("To: " + strings.Join(c.to, ", ") + "\r\n")
+	message.WriteString("Subject: " + subject(notification) + "\r\n")
+	message.WriteString("Content-Type: text/plain; charset=utf-8\r\n\r\n")
+	message.WriteString(strings.ReplaceAll(body(notification), "\n", "\r\n"))
+	return smtp.SendMail(c.address, c.auth, c.from, c.to, []byte(message.String()))
+}
+
+func subject(notification Notification) string {
+	firing := 0
+	for _, alert := range notification.Alerts {
+		if alert.Status == server.AlertFiring {
+			firing++
+		}
+	}
+	status := "RESOLVED"
+	if firing > 0 {
+		status = "FIRING"
+	}
+	return fmt.Sprintf("[%s] %s: %d firing, %d resolved", status, notification.Rule, firing, len(notification.Alerts)-firing)
+}
+
+func body(notification Notification) string {
+	lines := make([]string, 0, len(notification.Alerts))
+	for _, alert := range notification.Alerts {
+		lines = append(lines, fmt.Sprintf("%s %s, value %g", alert.Series, alert.Status, alert.Value))
+	}
+	return strings.Join(lines, "\n")
+}
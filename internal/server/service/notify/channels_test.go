@@ -0,0 +1,138 @@
+package notify
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/stretchr/testify/assert"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+var testNotification = Notification{
+	Rule: "HighCPU",
+	Alerts: []server.AlertState{
+		{Rule: "HighCPU", Series: "CPUutilization1", Status: server.AlertFiring, Value: 95},
+		{Rule: "HighCPU", Series: "CPUutilization2", Status: server.AlertResolved, Value: 20},
+	},
+}
+
+func TestWebhookChannel_Send(t *testing.T) {
+	var received Notification
+	stub := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&received))
+	}))
+	defer stub.Close()
+
+	err := NewWebhookChannel("ops", stub.URL).Send(context.Background(), testNotification)
+	assert.NoError(t, err)
+	assert.Equal(t, testNotification, received)
+}
+
+func TestSlackChannel_Send(t *testing.T) {
+	var received map[string]string
+	stub := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		assert.NoError(t, json.NewDecoder(request.Body).Decode(&received))
+	}))
+	defer stub.Close()
+
+	err := NewSlackChannel("slack", stub.URL).Send(context.Background(), testNotification)
+	assert.NoError(t, err)
+	assert.Equal(t, "[FIRING] HighCPU: 1 firing, 1 resolved\nCPUutilization1 firing, value 95\nCPUutilization2 resolved, value 20", received["text"])
+}
+
+func TestWebhookChannel_ClientErrorIsPermanent(t *testing.T) {
+	stub := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		writer.WriteHeader(http.StatusBadRequest)
+	}))
+	defer stub.Close()
+
+	err := NewWebhookChannel("ops", stub.URL).Send(context.Background(), testNotification)
+	assert.ErrorAs(t, err, &permanentError{})
+}
+
+// runSMTPStub accepts single SMTP session and sends received message to channel
+func runSMTPStub(t *testing.T) (string, chan string) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+
+	messages := make(chan string, 1)
+	go func() {
+		defer listener.Close()
+		conn, err := listener.Accept()
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+
+		reader := bufio.NewReader(conn)
+		reply := func(line string) {
+			conn.Write([]byte(line + "\r\n"))
+		}
+		reply("220 localhost ESMTP")
+		for {
+			line, err := reader.ReadString('\n')
+			if err != nil {
+				return
+			}
+			command := strings.ToUpper(strings.TrimSpace(line))
+			switch {
+			case strings.HasPrefix(command, "EHLO"), strings.HasPrefix(command, "HELO"):
+				reply("250 localhost")
+			case command == "DATA":
+				reply("354 end with .")
+				var message strings.Builder
+				for {
+					dataLine, err := reader.ReadString('\n')
+					if err != nil || dataLine == ".\r\n" {
+						break
+					}
+					message.WriteString(dataLine)
+				}
+				messages <- message.String()
+				reply("250 OK")
+			case command == "QUIT":
+				reply("221 bye")
+				return
+			default:
+				reply("250 OK")
+			}
+		}
+	}()
+	return listener.Addr().String(), messages
+}
+
+func TestEmailChannel_Send(t *testing.T) {
+	address, messages := runSMTPStub(t)
+
+	channel := NewEmailChannel("email", server.NotificationChannelConfig{SMTPAddress: address, From: "metrics@example.com", To: []string{"ops@example.com"}})
+	assert.NoError(t, channel.Send(context.Background(), testNotification))
+
+	message := <-messages
+	assert.Contains(t, message, "To: ops@example.com\r\n")
+	assert.Contains(t, message, "Subject: [FIRING] HighCPU: 1 firing, 1 resolved\r\n")
+	assert.Contains(t, message, "CPUutilization1 firing, value 95")
+}
+
+func TestNewChannels(t *testing.T) {
+	channels, err := NewChannels([]server.NotificationChannelConfig{
+		{Name: "ops", Type: "webhook", URL: "http://localhost/hook"},
+		{Type: "slack", URL: "http://localhost/slack"},
+	})
+	assert.NoError(t, err)
+	if assert.Len(t, channels, 2) {
+		assert.Equal(t, "ops", channels[0].Name())
+		assert.Equal(t, "slack-1", channels[1].Name())
+	}
+
+	_, err = NewChannels([]server.NotificationChannelConfig{{Type: "pager"}})
+	assert.Error(t, err)
+
+	_, err = NewChannels([]server.NotificationChannelConfig{{Type: "email", SMTPAddress: "localhost:25"}})
+	assert.Error(t, err)
+}
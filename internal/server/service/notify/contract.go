@@ -0,0 +1,10 @@
+package notify
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"time"
+)
+
+type silencer interface {
+	IsSilenced(alert server.AlertState, now time.Time) bool
+}
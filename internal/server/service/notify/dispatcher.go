@@ -0,0 +1,192 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+const (
+	queueSize   = 100
+	historySize = 1000
+)
+
+type throttleKey struct {
+	channel string
+	rule    string
+	series  string
+	status  server.AlertStatus
+}
+
+// worker delivers notifications of one channel, so slow or failing channel doesn't delay the others
+type worker struct {
+	channel Channel
+	queue   chan Notification
+}
+
+// Dispatcher groups alert transitions by rule and delivers them to all channels in background
+type Dispatcher struct {
+	workers  []worker
+	retrier  *server.Retrier
+	silencer silencer
+	throttle time.Duration
+	logger   *zap.Logger
+
+	mu      sync.Mutex
+	sent    map[throttleKey]time.Time
+	pending map[throttleKey]bool
+	history []server.NotificationRecord
+}
+
+func New(channels []Channel, retrier *server.Retrier, silencer silencer, throttle time.Duration, logger *zap.Logger) *Dispatcher {
+	workers := make([]worker, 0, len(channels))
+	for _, channel := range channels {
+		workers = append(workers, worker{channel: channel, queue: make(chan Notification, queueSize)})
+	}
+	return &Dispatcher{
+		workers:  workers,
+		retrier:  retrier,
+		silencer: silencer,
+		throttle: throttle,
+		logger:   logger,
+		sent:     make(map[throttleKey]time.Time),
+		pending:  make(map[throttleKey]bool),
+	}
+}
+
+// Notify enqueues transitions for delivery. Silenced transitions, transitions waiting for delivery and
+// repeats of transition delivered to channel during throttle interval are skipped
+func (d *Dispatcher) Notify(transitions []server.AlertState) {
+	if len(d.workers) == 0 {
+		return
+	}
+
+	now := time.Now()
+	var active []server.AlertState
+	for _, transition := range transitions {
+		if d.silencer.IsSilenced(transition, now) {
+			d.logger.Debug("Alert is silenced", zap.String("rule", transition.Rule), zap.String("series", transition.Series))
+			continue
+		}
+		active = append(active, transition)
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for key, sentAt := range d.sent {
+		if now.Sub(sentAt) >= d.throttle {
+			delete(d.sent, key)
+		}
+	}
+
+	for _, w := range d.workers {
+		groups := make(map[string][]server.AlertState)
+		for _, transition := range active {
+			key := newThrottleKey(w.channel, transition)
+			if _, ok := d.sent[key]; ok || d.pending[key] {
+				continue
+			}
+			groups[transition.Rule] = append(groups[transition.Rule], transition)
+		}
+
+		rules := make([]string, 0, len(groups))
+		for rule := range groups {
+			rules = append(rules, rule)
+		}
+		sort.Strings(rules)
+
+		for _, rule := range rules {
+			select {
+			case w.queue <- Notification{Rule: rule, Alerts: groups[rule]}:
+				for _, transition := range groups[rule] {
+					d.pending[newThrottleKey(w.channel, transition)] = true
+				}
+			default:
+				d.logger.Error("Notification queue is full, notification was dropped", zap.String("channel", w.channel.Name()), zap.String("rule", rule))
+			}
+		}
+	}
+}
+
+func newThrottleKey(channel Channel, transition server.AlertState) throttleKey {
+	return throttleKey{channel: channel.Name(), rule: transition.Rule, series: transition.Series, status: transition.Status}
+}
+
+// Run delivers enqueued notifications until context is done. Every channel is delivered by its own worker
+func (d *Dispatcher) Run(ctx context.Context) {
+	var wg sync.WaitGroup
+	for _, w := range d.workers {
+		wg.Add(1)
+		go func(w worker) {
+			defer wg.Done()
+			for {
+				select {
+				case <-ctx.Done():
+					return
+				case notification := <-w.queue:
+					d.deliver(ctx, w.channel, notification)
+				}
+			}
+		}(w)
+	}
+	wg.Wait()
+}
+
+// deliver sends notification with retries. Transitions are throttled only after successful delivery,
+// so failed one is sent again on the next transition
+func (d *Dispatcher) deliver(ctx context.Context, channel Channel, notification Notification) {
+	record := server.NotificationRecord{Channel: channel.Name(), Rule: notification.Rule}
+	for _, alert := range notification.Alerts {
+		record.Alerts = append(record.Alerts, fmt.Sprintf("%s %s", alert.Series, alert.Status))
+	}
+
+	err := d.retrier.Run(func() error {
+		record.Attempts++
+		return channel.Send(ctx, notification)
+	}, func(err error) bool {
+		var permanent permanentError
+		return !errors.As(err, &permanent) && ctx.Err() == nil
+	})
+
+	record.Time = time.Now()
+	record.Delivered = err == nil
+	if err != nil {
+		record.Error = err.Error()
+		d.logger.Error("Error during send notification",
+			zap.String("channel", record.Channel),
+			zap.String("rule", record.Rule),
+			zap.Int("attempts", record.Attempts),
+			zap.Error(err))
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+	for _, alert := range notification.Alerts {
+		key := newThrottleKey(channel, alert)
+		delete(d.pending, key)
+		if record.Delivered {
+			d.sent[key] = record.Time
+		}
+	}
+	d.history = append(d.history, record)
+	if len(d.history) > historySize {
+		d.history = d.history[len(d.history)-historySize:]
+	}
+}
+
+// FindAllNotifications returns delivery history, newest first
+func (d *Dispatcher) FindAllNotifications(ctx context.Context) []server.NotificationRecord {
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := make([]server.NotificationRecord, 0, len(d.history))
+	for i := len(d.history) - 1; i >= 0; i-- {
+		result = append(result, d.history[i])
+	}
+	return result
+}
@@ -0,0 +1,161 @@
+package notify
+
+import (
+	"context"
+	"errors"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"sync"
+	"testing"
+	"time"
+)
+
+type stubChannel struct {
+	mu       sync.Mutex
+	failures []error
+	received []Notification
+}
+
+func (c *stubChannel) Name() string {
+	return "stub"
+}
+
+func (c *stubChannel) Send(ctx context.Context, notification Notification) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if len(c.failures) > 0 {
+		err := c.failures[0]
+		c.failures = c.failures[1:]
+		return err
+	}
+	c.received = append(c.received, notification)
+	return nil
+}
+
+type stubSilencer struct {
+	series string
+}
+
+func (s stubSilencer) IsSilenced(alert server.AlertState, now time.Time) bool {
+	return alert.Series == s.series
+}
+
+func waitHistory(t *testing.T, dispatcher *Dispatcher, size int) []server.NotificationRecord {
+	var history []server.NotificationRecord
+	assert.Eventually(t, func() bool {
+		history = dispatcher.FindAllNotifications(context.Background())
+		return len(history) >= size
+	}, time.Second, 5*time.Millisecond)
+	return history
+}
+
+func TestDispatcher_GroupsByRuleAndThrottles(t *testing.T) {
+	channel := &stubChannel{}
+	dispatcher := New([]Channel{channel}, server.NewRetrier(0, 0, 0), stubSilencer{series: "CPUutilization3"}, time.Hour, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+
+	dispatcher.Notify([]server.AlertState{
+		{Rule: "HighCPU", Series: "CPUutilization1", Status: server.AlertFiring},
+		{Rule: "LowMemory", Series: "FreeMemory", Status: server.AlertFiring},
+		{Rule: "HighCPU", Series: "CPUutilization2", Status: server.AlertFiring},
+		{Rule: "HighCPU", Series: "CPUutilization3", Status: server.AlertFiring},
+	})
+	// silenced and repeated transitions are skipped, resolution is delivered
+	dispatcher.Notify([]server.AlertState{{Rule: "LowMemory", Series: "FreeMemory", Status: server.AlertFiring}})
+	dispatcher.Notify([]server.AlertState{{Rule: "LowMemory", Series: "FreeMemory", Status: server.AlertResolved}})
+
+	history := waitHistory(t, dispatcher, 3)
+	assert.Len(t, history, 3)
+	assert.Equal(t, []string{"FreeMemory resolved"}, history[0].Alerts)
+	assert.Equal(t, []string{"FreeMemory firing"}, history[1].Alerts)
+	assert.Equal(t, []string{"CPUutilization1 firing", "CPUutilization2 firing"}, history[2].Alerts)
+	assert.True(t, history[2].Delivered)
+}
+
+func TestDispatcher_Retries(t *testing.T) {
+	channel := &stubChannel{failures: []error{errors.New("connection refused"), errors.New("timeout")}}
+	dispatcher := New([]Channel{channel}, server.NewRetrier(3, time.Millisecond, time.Millisecond), stubSilencer{}, 0, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+
+	dispatcher.Notify([]server.AlertState{{Rule: "HighCPU", Series: "CPUutilization1", Status: server.AlertFiring}})
+
+	history := waitHistory(t, dispatcher, 1)
+	assert.True(t, history[0].Delivered)
+	assert.Equal(t, 3, history[0].Attempts)
+	assert.Len(t, channel.received, 1)
+}
+
+func TestDispatcher_PermanentErrorIsNotRetried(t *testing.T) {
+	channel := &stubChannel{failures: []error{permanentError{err: errors.New("bad request")}}}
+	dispatcher := New([]Channel{channel}, server.NewRetrier(3, time.Millisecond, time.Millisecond), stubSilencer{}, 0, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+
+	dispatcher.Notify([]server.AlertState{{Rule: "HighCPU", Series: "CPUutilization1", Status: server.AlertFiring}})
+
+	history := waitHistory(t, dispatcher, 1)
+	assert.False(t, history[0].Delivered)
+	assert.Equal(t, 1, history[0].Attempts)
+	assert.Equal(t, "bad request", history[0].Error)
+}
+
+func TestDispatcher_FailedDeliveryIsNotThrottled(t *testing.T) {
+	channel := &stubChannel{failures: []error{permanentError{err: errors.New("bad gateway")}}}
+	dispatcher := New([]Channel{channel}, server.NewRetrier(0, 0, 0), stubSilencer{}, time.Hour, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+
+	transition := server.AlertState{Rule: "HighCPU", Series: "CPUutilization1", Status: server.AlertFiring}
+	dispatcher.Notify([]server.AlertState{transition})
+	assert.False(t, waitHistory(t, dispatcher, 1)[0].Delivered)
+
+	dispatcher.Notify([]server.AlertState{transition})
+	assert.True(t, waitHistory(t, dispatcher, 2)[0].Delivered)
+
+	// delivered transition is throttled
+	dispatcher.Notify([]server.AlertState{transition})
+	time.Sleep(20 * time.Millisecond)
+	assert.Len(t, dispatcher.FindAllNotifications(ctx), 2)
+}
+
+type blockedChannel struct {
+	release chan struct{}
+}
+
+func (c *blockedChannel) Name() string {
+	return "blocked"
+}
+
+func (c *blockedChannel) Send(ctx context.Context, notification Notification) error {
+	select {
+	case <-c.release:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+func TestDispatcher_ChannelsAreDeliveredIndependently(t *testing.T) {
+	blocked := &blockedChannel{release: make(chan struct{})}
+	channel := &stubChannel{}
+	dispatcher := New([]Channel{blocked, channel}, server.NewRetrier(0, 0, 0), stubSilencer{}, time.Hour, zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go dispatcher.Run(ctx)
+
+	dispatcher.Notify([]server.AlertState{{Rule: "HighCPU", Series: "CPUutilization1", Status: server.AlertFiring}})
+	history := waitHistory(t, dispatcher, 1)
+	assert.Equal(t, "stub", history[0].Channel)
+
+	close(blocked.release)
+	history = waitHistory(t, dispatcher, 2)
+	assert.Equal(t, "blocked", history[0].Channel)
+}
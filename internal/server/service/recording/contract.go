@@ -0,0 +1,10 @@
+package recording
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+)
+
+type metricsService interface {
+	SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error)
+}
@@ -0,0 +1,128 @@
+package recording
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/expr"
+	"go.uber.org/zap"
+	"time"
+)
+
+// Rule is parsed recording rule
+type Rule struct {
+	Name       string
+	Expr       string
+	expression expr.Node
+}
+
+// NewRules parses all configured recording rules
+func NewRules(configs []server.RecordingRuleConfig) ([]Rule, error) {
+	names := make(map[string]bool, len(configs))
+	rules := make([]Rule, 0, len(configs))
+	for _, config := range configs {
+		if config.Name == "" {
+			return nil, fmt.Errorf("recording rule name should be filled")
+		}
+		if names[config.Name] {
+			return nil, fmt.Errorf("recording rule '%s' is duplicated", config.Name)
+		}
+		names[config.Name] = true
+
+		node, err := expr.Parse(config.Expr)
+		if err != nil {
+			return nil, fmt.Errorf("recording rule '%s': %w", config.Name, err)
+		}
+		rules = append(rules, Rule{Name: config.Name, Expr: config.Expr, expression: node})
+	}
+	return rules, nil
+}
+
+// Recorder periodically evaluates recording rules and saves results as gauges
+type Recorder struct {
+	rules     []Rule
+	evaluator *expr.Evaluator
+	service   metricsService
+	logger    *zap.Logger
+}
+
+func New(rules []Rule, evaluator *expr.Evaluator, service metricsService, logger *zap.Logger) *Recorder {
+	return &Recorder{
+		rules:     rules,
+		evaluator: evaluator,
+		service:   service,
+		logger:    logger,
+	}
+}
+
+// Run evaluates rules with interval until context is done
+func (r *Recorder) Run(ctx context.Context, interval time.Duration) {
+	if len(r.rules) == 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+			if err := r.Evaluate(ctx); err != nil {
+				r.logger.Error("Error during save recorded metrics", zap.Error(err))
+			}
+		}
+	}
+}
+
+// Evaluate all rules one by one, so every rule sees results of the previous ones. Replication follower
+// doesn't record anything, results come from primary, and starts recording after promotion
+func (r *Recorder) Evaluate(ctx context.Context) error {
+	for _, rule := range r.rules {
+		values, err := r.evaluator.Eval(ctx, rule.expression)
+		if err != nil {
+			r.logger.Error("Error during evaluate recording rule", zap.String("rule", rule.Name), zap.Error(err))
+			continue
+		}
+
+		request, err := toRequest(rule, values)
+		if err != nil {
+			r.logger.Error("Error during evaluate recording rule", zap.String("rule", rule.Name), zap.Error(err))
+			continue
+		}
+		if len(request) == 0 {
+			continue
+		}
+
+		if _, err := r.service.SaveMetrics(ctx, request); err != nil {
+			if errors.Is(err, server.ErrReadOnly) {
+				r.logger.Debug("Recording rules are skipped on replication follower")
+				return nil
+			}
+			return fmt.Errorf("recording rule '%s': %w", rule.Name, err)
+		}
+	}
+	return nil
+}
+
+// toRequest names results by rule. Labels of source series are kept, so results of
+// "CPUutilization * 100" over series with different labels do not collide
+func toRequest(rule Rule, values expr.Vector) ([]common.MetricRequestDto, error) {
+	request := make([]common.MetricRequestDto, 0, len(values))
+	names := make(map[string]bool, len(values))
+	for _, sample := range values {
+		_, labels := common.SplitLabels(sample.Name)
+		name := common.JoinLabels(rule.Name, labels)
+		if names[name] {
+			return nil, fmt.Errorf("result has several series without distinct labels, aggregate them")
+		}
+		names[name] = true
+
+		value := sample.Value
+		request = append(request, common.MetricRequestDto{ID: name, MType: common.Gauge, Value: &value})
+	}
+	return request, nil
+}
@@ -0,0 +1,102 @@
+package recording
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/expr"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"testing"
+)
+
+// stubService stores gauges in map, so saved results are visible to next rules
+type stubService struct {
+	metrics map[string]float64
+}
+
+func (s *stubService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	for _, metric := range request {
+		s.metrics[metric.ID] = *metric.Value
+	}
+	return nil, nil
+}
+
+func (s *stubService) FindAllMetrics(ctx context.Context) []common.MetricResponseDto {
+	var result []common.MetricResponseDto
+	for name, value := range s.metrics {
+		v := value
+		result = append(result, common.MetricResponseDto{ID: name, MType: common.Gauge, Value: &v})
+	}
+	return result
+}
+
+func TestRecorder_Evaluate(t *testing.T) {
+	rules, err := NewRules([]server.RecordingRuleConfig{
+		{Name: "MemoryUsedPercent", Expr: "(TotalMemory - FreeMemory) / TotalMemory * 100"},
+		{Name: "CPUutilizationTotal", Expr: "sum(CPUutilization*)"},
+		{Name: "DiskFreePercent", Expr: "100 - disk*"},
+		{Name: "MemoryUsedHigh", Expr: "MemoryUsedPercent > 50"},
+		{Name: "Missing", Expr: "Unknown * 2"},
+	})
+	assert.NoError(t, err)
+
+	service := &stubService{metrics: map[string]float64{
+		"TotalMemory":     1000,
+		"FreeMemory":      250,
+		"CPUutilization1": 10,
+		"CPUutilization2": 30,
+		"disk;host=a":     40,
+		"disk;host=b":     70,
+	}}
+	recorder := New(rules, expr.NewEvaluator(service), service, zap.NewNop())
+
+	assert.NoError(t, recorder.Evaluate(context.Background()))
+	assert.Equal(t, 75.0, service.metrics["MemoryUsedPercent"])
+	assert.Equal(t, 40.0, service.metrics["CPUutilizationTotal"])
+	assert.Equal(t, 60.0, service.metrics["DiskFreePercent;host=a"])
+	assert.Equal(t, 30.0, service.metrics["DiskFreePercent;host=b"])
+	assert.Equal(t, 75.0, service.metrics["MemoryUsedHigh"])
+	assert.NotContains(t, service.metrics, "Missing")
+}
+
+func TestRecorder_CollidingSeriesAreSkipped(t *testing.T) {
+	rules, err := NewRules([]server.RecordingRuleConfig{{Name: "CPUutilizationPercent", Expr: "CPUutilization* * 100"}})
+	assert.NoError(t, err)
+
+	service := &stubService{metrics: map[string]float64{"CPUutilization1": 0.1, "CPUutilization2": 0.3}}
+	recorder := New(rules, expr.NewEvaluator(service), service, zap.NewNop())
+
+	assert.NoError(t, recorder.Evaluate(context.Background()))
+	assert.NotContains(t, service.metrics, "CPUutilizationPercent")
+}
+
+func TestNewRules(t *testing.T) {
+	_, err := NewRules([]server.RecordingRuleConfig{{Name: "", Expr: "a"}})
+	assert.Error(t, err)
+
+	_, err = NewRules([]server.RecordingRuleConfig{{Name: "a", Expr: "b +"}})
+	assert.Error(t, err)
+
+	_, err = NewRules([]server.RecordingRuleConfig{{Name: "a", Expr: "b"}, {Name: "a", Expr: "c"}})
+	assert.Error(t, err)
+}
+
+// readOnlyService rejects writes like replication follower
+type readOnlyService struct {
+	stubService
+}
+
+func (s *readOnlyService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	return nil, server.ErrReadOnly
+}
+
+func TestRecorder_FollowerSkipsRules(t *testing.T) {
+	rules, err := NewRules([]server.RecordingRuleConfig{{Name: "MemoryFree", Expr: "FreeMemory"}})
+	assert.NoError(t, err)
+
+	service := &readOnlyService{stubService{metrics: map[string]float64{"FreeMemory": 250}}}
+	recorder := New(rules, expr.NewEvaluator(service), service, zap.NewNop())
+
+	assert.NoError(t, recorder.Evaluate(context.Background()))
+}
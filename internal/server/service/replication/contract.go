@@ -0,0 +1,25 @@
+package replication
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+)
+
+type metricsService interface {
+	SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error)
+
+	FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error)
+
+	FindAllMetrics(ctx context.Context) []common.MetricResponseDto
+}
+
+type replicaStorage interface {
+	ReplaceMetrics(ctx context.Context, metrics []server.Metric) error
+
+	RestoreSnapshot(ctx context.Context, metrics []server.Metric) error
+}
+
+type metricMapper interface {
+	MapRequestToDomainModel(request common.MetricRequestDto) (server.Metric, error)
+}
@@ -0,0 +1,209 @@
+package replication
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/proto/metrics"
+	"go.uber.org/zap"
+	"sync"
+	"time"
+)
+
+// Roles of server reported in replication status
+const (
+	RolePrimary  = "primary"
+	RoleFollower = "follower"
+)
+
+const retryDelay = 1 * time.Second
+
+var ErrReadOnly = server.ErrReadOnly
+
+var errPromoted = errors.New("follower was promoted")
+
+// Follower applies writes streamed by primary server and serves reads. Batches hold absolute values
+// of metrics, so counters are never accumulated twice when batch is applied again after reconnect.
+// Until promotion follower rejects writes, after promotion it stops replication and accumulates
+// counters on top of replicated values. Replication is asynchronous: writes acknowledged by primary
+// but not yet applied by follower are lost when follower is promoted, so failover may lose increments
+// but never counts them twice
+type Follower struct {
+	metricsService
+	mu            sync.Mutex
+	primary       string
+	client        metrics.ReplicationServiceClient
+	storage       replicaStorage
+	mapper        metricMapper
+	logger        *zap.Logger
+	epoch         string
+	lastSeq       uint64
+	lastAppliedAt *time.Time
+	connected     bool
+	promoted      chan struct{}
+	cancel        context.CancelFunc
+}
+
+func New(primary string, client metrics.ReplicationServiceClient, service metricsService, storage replicaStorage, mapper metricMapper, logger *zap.Logger) *Follower {
+	return &Follower{
+		metricsService: service,
+		primary:        primary,
+		client:         client,
+		storage:        storage,
+		mapper:         mapper,
+		logger:         logger,
+		promoted:       make(chan struct{}),
+	}
+}
+
+// SaveMetrics rejects writes until follower is promoted
+func (f *Follower) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	select {
+	case <-f.promoted:
+		return f.metricsService.SaveMetrics(ctx, request)
+	default:
+		return nil, ErrReadOnly
+	}
+}
+
+// Run replicates primary server until context is done or follower is promoted. Connection is
+// restored after failures from the last applied change
+func (f *Follower) Run(ctx context.Context) {
+	f.mu.Lock()
+	if f.isPromoted() {
+		f.mu.Unlock()
+		return
+	}
+	ctx, f.cancel = context.WithCancel(ctx)
+	f.mu.Unlock()
+
+	for {
+		err := f.replicate(ctx)
+		f.setConnected(false)
+		if ctx.Err() != nil || errors.Is(err, errPromoted) {
+			return
+		}
+		f.logger.Warn("Replication from primary was interrupted", zap.String("primary", f.primary), zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(retryDelay):
+		}
+	}
+}
+
+func (f *Follower) replicate(ctx context.Context) error {
+	f.mu.Lock()
+	request := &metrics.ReplicationRequest{Epoch: f.epoch, LastSeq: f.lastSeq}
+	f.mu.Unlock()
+
+	stream, err := f.client.Replicate(ctx, request)
+	if err != nil {
+		return err
+	}
+	for {
+		batch, err := stream.Recv()
+		if err != nil {
+			return err
+		}
+		if err := f.apply(ctx, batch); err != nil {
+			return err
+		}
+	}
+}
+
+// apply stores batch when it continues the last applied one. Gap in sequence numbers
+// interrupts replication, so follower resumes from the last applied change
+func (f *Follower) apply(ctx context.Context, batch *metrics.ReplicationBatch) error {
+	replicated := make([]server.Metric, 0, len(batch.Metrics))
+	for _, protoMetric := range batch.Metrics {
+		metric, err := f.mapper.MapRequestToDomainModel(common.MetricRequestFromProto(protoMetric))
+		if err != nil {
+			return err
+		}
+		replicated = append(replicated, metric)
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.isPromoted() {
+		return errPromoted
+	}
+	if batch.Snapshot {
+		if err := f.storage.RestoreSnapshot(ctx, replicated); err != nil {
+			return err
+		}
+		f.logger.Info("Replication snapshot was applied", zap.String("epoch", batch.Epoch), zap.Uint64("seq", batch.Seq))
+	} else {
+		if batch.Epoch != f.epoch || batch.Seq-uint64(len(batch.Metrics)) != f.lastSeq {
+			return fmt.Errorf("batch %s/%d doesn't continue applied changes %s/%d", batch.Epoch, batch.Seq, f.epoch, f.lastSeq)
+		}
+		if err := f.storage.ReplaceMetrics(ctx, replicated); err != nil {
+			return err
+		}
+	}
+
+	now := time.Now()
+	f.epoch, f.lastSeq, f.lastAppliedAt, f.connected = batch.Epoch, batch.Seq, &now, true
+	return nil
+}
+
+// Promote stops replication and starts accepting writes. Batch being applied is completed first
+func (f *Follower) Promote(ctx context.Context) server.ReplicationStatus {
+	f.mu.Lock()
+	if !f.isPromoted() {
+		close(f.promoted)
+		if f.cancel != nil {
+			f.cancel()
+		}
+		f.connected = false
+		f.logger.Info("Follower was promoted to primary", zap.String("epoch", f.epoch), zap.Uint64("seq", f.lastSeq))
+	}
+	f.mu.Unlock()
+
+	return f.ReplicationStatus(ctx)
+}
+
+// Promoted is closed when follower is promoted
+func (f *Follower) Promoted() <-chan struct{} {
+	return f.promoted
+}
+
+func (f *Follower) ReplicationStatus(ctx context.Context) server.ReplicationStatus {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	status := server.ReplicationStatus{
+		Role:          RoleFollower,
+		Primary:       f.primary,
+		Connected:     f.connected,
+		Epoch:         f.epoch,
+		LastSeq:       f.lastSeq,
+		LastAppliedAt: f.lastAppliedAt,
+	}
+	if f.isPromoted() {
+		status.Role = RolePrimary
+		status.Primary = ""
+	}
+	return status
+}
+
+func (f *Follower) isPromoted() bool {
+	select {
+	case <-f.promoted:
+		return true
+	default:
+		return false
+	}
+}
+
+func (f *Follower) setConnected(connected bool) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	f.connected = connected
+}
@@ -0,0 +1,207 @@
+package replication
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	handler "github.com/desepticon55/metrics-collector/internal/server/api/metrics/grpc"
+	metricsAPI "github.com/desepticon55/metrics-collector/internal/server/api/metrics/http"
+	metricsMappers "github.com/desepticon55/metrics-collector/internal/server/mapper/metrics"
+	metricsServices "github.com/desepticon55/metrics-collector/internal/server/service/metrics"
+	"github.com/desepticon55/metrics-collector/internal/server/service/stream"
+	"github.com/desepticon55/metrics-collector/internal/server/storage/memory"
+	"github.com/desepticon55/metrics-collector/proto/metrics"
+	metricsv2 "github.com/desepticon55/metrics-collector/proto/metrics/v2"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/status"
+	"google.golang.org/grpc/test/bufconn"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+)
+
+type instance struct {
+	storage *memory.Storage
+	service metricsServices.Service
+}
+
+func newInstance(t *testing.T, mapper metricsMappers.Mapper) instance {
+	storage := memory.New(t.TempDir()+"/metrics.json", false, 0)
+	return instance{storage: storage, service: metricsServices.New(storage, mapper, server.NewRetrier(1, 0, 0))}
+}
+
+// dial connects to gRPC server listening in process
+func dial(t *testing.T, listener *bufconn.Listener) *grpc.ClientConn {
+	conn, err := grpc.NewClient("passthrough:///bufnet",
+		grpc.WithContextDialer(func(ctx context.Context, _ string) (net.Conn, error) {
+			return listener.DialContext(ctx)
+		}),
+		grpc.WithTransportCredentials(insecure.NewCredentials()))
+	assert.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+// startPrimary serves replication of primary instance in process and returns client connected to it
+func startPrimary(t *testing.T, primary instance, mapper metricsMappers.Mapper) metrics.ReplicationServiceClient {
+	broker := stream.New(mapper, 100)
+	primary.storage.AddListener(broker.Publish)
+
+	listener := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	metrics.RegisterReplicationServiceServer(s, &handler.ReplicationServer{Stream: broker, Service: primary.service, Epoch: "epoch1", Logger: zap.NewNop()})
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+	return metrics.NewReplicationServiceClient(dial(t, listener))
+}
+
+func counter(name string, delta int64) common.MetricRequestDto {
+	return common.MetricRequestDto{ID: name, MType: common.Counter, Delta: &delta}
+}
+
+func counterValue(t *testing.T, service metricsService, name string) int64 {
+	metric, err := service.FindOneMetric(context.Background(), name, common.Counter)
+	if err != nil {
+		return 0
+	}
+	return *metric.Delta
+}
+
+func TestFollower_Replicate(t *testing.T) {
+	ctx := context.Background()
+	mapper := metricsMappers.NewMapper(validator.New())
+	primary := newInstance(t, mapper)
+	replica := newInstance(t, mapper)
+
+	_, err := primary.service.SaveMetrics(ctx, []common.MetricRequestDto{counter("requests", 10)})
+	assert.NoError(t, err)
+
+	client := startPrimary(t, primary, mapper)
+	follower := New("primary", client, replica.service, replica.storage, mapper, zap.NewNop())
+	runCtx, cancel := context.WithCancel(ctx)
+	go follower.Run(runCtx)
+
+	// state written before follower connected is received as snapshot
+	assert.Eventually(t, func() bool {
+		return counterValue(t, follower, "requests") == 10
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = primary.service.SaveMetrics(ctx, []common.MetricRequestDto{counter("requests", 5)})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return counterValue(t, follower, "requests") == 15
+	}, 5*time.Second, 10*time.Millisecond)
+
+	_, err = follower.SaveMetrics(ctx, []common.MetricRequestDto{counter("requests", 1)})
+	assert.ErrorIs(t, err, ErrReadOnly)
+
+	// follower resumes from the last applied change after reconnect
+	cancel()
+	_, err = primary.service.SaveMetrics(ctx, []common.MetricRequestDto{counter("requests", 5)})
+	assert.NoError(t, err)
+	go follower.Run(ctx)
+	assert.Eventually(t, func() bool {
+		return follower.ReplicationStatus(ctx).LastSeq == 2
+	}, 5*time.Second, 10*time.Millisecond)
+	assert.Equal(t, int64(20), counterValue(t, follower, "requests"))
+
+	// promoted follower accumulates counters on top of replicated values
+	status := follower.Promote(ctx)
+	assert.Equal(t, RolePrimary, status.Role)
+	_, err = follower.SaveMetrics(ctx, []common.MetricRequestDto{counter("requests", 1)})
+	assert.NoError(t, err)
+	_, err = primary.service.SaveMetrics(ctx, []common.MetricRequestDto{counter("requests", 100)})
+	assert.NoError(t, err)
+	time.Sleep(50 * time.Millisecond)
+	assert.Equal(t, int64(21), counterValue(t, follower, "requests"))
+}
+
+func TestFollower_ApplyRejectsGap(t *testing.T) {
+	ctx := context.Background()
+	mapper := metricsMappers.NewMapper(validator.New())
+	replica := newInstance(t, mapper)
+	follower := New("primary", nil, replica.service, replica.storage, mapper, zap.NewNop())
+
+	batch := func(seq uint64, delta int64) *metrics.ReplicationBatch {
+		return &metrics.ReplicationBatch{Epoch: "epoch1", Seq: seq, Metrics: []*metrics.Metric{common.MetricRequestToProto(counter("requests", delta))}}
+	}
+
+	assert.NoError(t, follower.apply(ctx, &metrics.ReplicationBatch{Epoch: "epoch1", Seq: 1, Snapshot: true, Metrics: batch(1, 10).Metrics}))
+	assert.NoError(t, follower.apply(ctx, batch(2, 15)))
+	// duplicated and skipped batches are not applied
+	assert.Error(t, follower.apply(ctx, batch(2, 15)))
+	assert.Error(t, follower.apply(ctx, batch(4, 30)))
+	assert.Error(t, follower.apply(ctx, &metrics.ReplicationBatch{Epoch: "epoch2", Seq: 3, Metrics: batch(3, 20).Metrics}))
+
+	assert.Equal(t, int64(15), counterValue(t, follower, "requests"))
+	assert.Equal(t, uint64(2), follower.ReplicationStatus(ctx).LastSeq)
+}
+
+// TestFollower_Failover writes through APIs of follower before and after promotion
+func TestFollower_Failover(t *testing.T) {
+	ctx := context.Background()
+	mapper := metricsMappers.NewMapper(validator.New())
+	primary := newInstance(t, mapper)
+	replica := newInstance(t, mapper)
+
+	client := startPrimary(t, primary, mapper)
+	follower := New("primary", client, replica.service, replica.storage, mapper, zap.NewNop())
+	runCtx, cancel := context.WithCancel(ctx)
+	go follower.Run(runCtx)
+
+	updates := metricsAPI.NewCreateListMetricsHandlerFromJSON(server.Config{}, nil, follower, zap.NewNop())
+	saveHTTP := func() int {
+		request := httptest.NewRequest(http.MethodPost, "/updates/", strings.NewReader(`[{"id":"requests","type":"counter","delta":1}]`))
+		request.Header.Set("Content-Type", "application/json")
+		recorder := httptest.NewRecorder()
+		updates(recorder, request)
+		return recorder.Code
+	}
+
+	listener := bufconn.Listen(1024 * 1024)
+	s := grpc.NewServer()
+	metricsv2.RegisterMetricsServiceServer(s, &handler.MetricsServerV2{Service: follower, Logger: zap.NewNop()})
+	go s.Serve(listener)
+	t.Cleanup(s.Stop)
+	grpcClient := metricsv2.NewMetricsServiceClient(dial(t, listener))
+	saveGRPC := func() error {
+		_, err := grpcClient.SendMetrics(ctx, &metricsv2.SendMetricsRequest{Metrics: []*metricsv2.Metric{
+			{Id: "requests", Value: &metricsv2.Metric_Counter{Counter: 1}},
+		}})
+		return err
+	}
+
+	_, err := primary.service.SaveMetrics(ctx, []common.MetricRequestDto{counter("requests", 10)})
+	assert.NoError(t, err)
+	assert.Eventually(t, func() bool {
+		return counterValue(t, follower, "requests") == 10
+	}, 5*time.Second, 10*time.Millisecond)
+
+	// follower rejects writes, clients should retry them on primary
+	assert.Equal(t, http.StatusServiceUnavailable, saveHTTP())
+	assert.Equal(t, codes.FailedPrecondition, status.Code(saveGRPC()))
+
+	// primary fails after it acknowledged write which was not replicated yet
+	cancel()
+	assert.Eventually(t, func() bool {
+		return !follower.ReplicationStatus(ctx).Connected
+	}, 5*time.Second, 10*time.Millisecond)
+	_, err = primary.service.SaveMetrics(ctx, []common.MetricRequestDto{counter("requests", 5)})
+	assert.NoError(t, err)
+
+	follower.Promote(ctx)
+	assert.Equal(t, http.StatusOK, saveHTTP())
+	assert.NoError(t, saveGRPC())
+
+	// replication is asynchronous, so not replicated increment is lost and nothing is counted twice
+	assert.Equal(t, int64(15), counterValue(t, primary.service, "requests"))
+	assert.Equal(t, int64(12), counterValue(t, follower, "requests"))
+}
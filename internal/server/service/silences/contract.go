@@ -0,0 +1,13 @@
+package silences
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/server"
+)
+
+type silenceStorage interface {
+	// SaveSilence creates silence or replaces existing one with the same ID
+	SaveSilence(ctx context.Context, silence server.Silence) error
+
+	FindAllSilences(ctx context.Context) ([]server.Silence, error)
+}
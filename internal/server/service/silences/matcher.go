@@ -0,0 +1,120 @@
+package silences
+
+import (
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"path"
+	"strings"
+	"time"
+)
+
+// Maintenance window can not be longer than a week, otherwise it overlaps the next one
+const maxWindowDuration = 7 * 24 * time.Hour
+
+var weekdays = map[string]time.Weekday{
+	"sun": time.Sunday,
+	"mon": time.Monday,
+	"tue": time.Tuesday,
+	"wed": time.Wednesday,
+	"thu": time.Thursday,
+	"fri": time.Friday,
+	"sat": time.Saturday,
+}
+
+func validate(silence server.Silence) error {
+	if silence.Rule == "" && silence.Metric == "" && len(silence.Labels) == 0 {
+		return fmt.Errorf("at least one of rule, metric or labels should be set")
+	}
+	if _, err := path.Match(silence.Metric, ""); err != nil {
+		return fmt.Errorf("metric pattern: %w", err)
+	}
+	if !silence.EndsAt.After(silence.StartsAt) {
+		return fmt.Errorf("ends_at should be after starts_at")
+	}
+	if silence.Recurrence != nil {
+		if _, err := parseRecurrence(*silence.Recurrence); err != nil {
+			return fmt.Errorf("recurrence: %w", err)
+		}
+	}
+	return nil
+}
+
+type window struct {
+	weekdays map[time.Weekday]bool
+	start    time.Duration
+	duration time.Duration
+}
+
+func parseRecurrence(recurrence server.Recurrence) (window, error) {
+	result := window{weekdays: make(map[time.Weekday]bool)}
+	for _, day := range recurrence.Weekdays {
+		weekday, ok := weekdays[strings.ToLower(day)]
+		if !ok {
+			return result, fmt.Errorf("unknown weekday '%s'", day)
+		}
+		result.weekdays[weekday] = true
+	}
+
+	start, err := time.Parse("15:04", recurrence.Start)
+	if err != nil {
+		return result, fmt.Errorf("start should be in format HH:MM")
+	}
+	result.start = time.Duration(start.Hour())*time.Hour + time.Duration(start.Minute())*time.Minute
+
+	result.duration, err = time.ParseDuration(recurrence.Duration)
+	if err != nil {
+		return result, fmt.Errorf("duration: %w", err)
+	}
+	if result.duration <= 0 || result.duration > maxWindowDuration {
+		return result, fmt.Errorf("duration should be positive and not longer than a week")
+	}
+	return result, nil
+}
+
+func isActive(silence server.Silence, now time.Time) bool {
+	if now.Before(silence.StartsAt) || !now.Before(silence.EndsAt) {
+		return false
+	}
+	if silence.Recurrence == nil {
+		return true
+	}
+
+	recurrence, err := parseRecurrence(*silence.Recurrence)
+	if err != nil {
+		return false
+	}
+
+	// window which is open now could start on one of the previous days
+	now = now.UTC()
+	today := time.Date(now.Year(), now.Month(), now.Day(), 0, 0, 0, 0, time.UTC)
+	for days := 0; days <= 7; days++ {
+		start := today.AddDate(0, 0, -days).Add(recurrence.start)
+		if len(recurrence.weekdays) > 0 && !recurrence.weekdays[start.Weekday()] {
+			continue
+		}
+		if !now.Before(start) && now.Before(start.Add(recurrence.duration)) {
+			return true
+		}
+	}
+	return false
+}
+
+func matches(silence server.Silence, alert server.AlertState) bool {
+	if silence.Rule != "" && silence.Rule != alert.Rule {
+		return false
+	}
+
+	name, labels := common.SplitLabels(alert.Series)
+	if silence.Metric != "" {
+		if matched, _ := path.Match(silence.Metric, name); !matched {
+			return false
+		}
+	}
+	for key, value := range silence.Labels {
+		if labels[key] != value {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,128 @@
+package silences
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"sort"
+	"sync"
+	"time"
+)
+
+var (
+	ErrInvalidSilence  = errors.New("invalid silence")
+	ErrSilenceNotFound = errors.New("silence not found")
+)
+
+// Service keeps silences in memory and writes them through to storage
+type Service struct {
+	mu       sync.RWMutex
+	storage  silenceStorage
+	silences map[string]server.Silence
+}
+
+func New(storage silenceStorage) *Service {
+	return &Service{storage: storage, silences: make(map[string]server.Silence)}
+}
+
+// Restore loads persisted silences
+func (s *Service) Restore(ctx context.Context) error {
+	silences, err := s.storage.FindAllSilences(ctx)
+	if err != nil {
+		return err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, silence := range silences {
+		s.silences[silence.ID] = silence
+	}
+	return nil
+}
+
+func (s *Service) CreateSilence(ctx context.Context, silence server.Silence) (server.Silence, error) {
+	now := time.Now()
+	if silence.StartsAt.IsZero() {
+		silence.StartsAt = now
+	}
+	if err := validate(silence); err != nil {
+		return server.Silence{}, fmt.Errorf("%w: %v", ErrInvalidSilence, err)
+	}
+
+	id := make([]byte, 16)
+	if _, err := rand.Read(id); err != nil {
+		return server.Silence{}, err
+	}
+	silence.ID = hex.EncodeToString(id)
+	silence.CreatedAt = now
+
+	if err := s.storage.SaveSilence(ctx, silence); err != nil {
+		return server.Silence{}, err
+	}
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.silences[silence.ID] = silence
+	return silence, nil
+}
+
+// FindAllSilences returns not expired silences, or all of them when includeExpired is set
+func (s *Service) FindAllSilences(ctx context.Context, includeExpired bool) []server.Silence {
+	now := time.Now()
+
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	result := make([]server.Silence, 0, len(s.silences))
+	for _, silence := range s.silences {
+		if includeExpired || now.Before(silence.EndsAt) {
+			result = append(result, silence)
+		}
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].CreatedAt.Before(result[j].CreatedAt)
+	})
+	return result
+}
+
+// ExpireSilence ends silence immediately. Expired silence is kept for history
+func (s *Service) ExpireSilence(ctx context.Context, id string) (server.Silence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	silence, ok := s.silences[id]
+	if !ok {
+		return server.Silence{}, ErrSilenceNotFound
+	}
+
+	now := time.Now()
+	if !now.Before(silence.EndsAt) {
+		return silence, nil
+	}
+	silence.EndsAt = now
+	if silence.StartsAt.After(now) {
+		silence.StartsAt = now
+	}
+
+	if err := s.storage.SaveSilence(ctx, silence); err != nil {
+		return server.Silence{}, err
+	}
+	s.silences[id] = silence
+	return silence, nil
+}
+
+// IsSilenced checks whether any silence matches alert at the moment
+func (s *Service) IsSilenced(alert server.AlertState, now time.Time) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+
+	for _, silence := range s.silences {
+		if matches(silence, alert) && isActive(silence, now) {
+			return true
+		}
+	}
+	return false
+}
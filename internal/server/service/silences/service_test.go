@@ -0,0 +1,104 @@
+package silences
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+func TestService_CreateSilence_Validation(t *testing.T) {
+	service := New(memory.NewSilenceStorage(""))
+	ctx := context.Background()
+	now := time.Now()
+
+	tests := []struct {
+		name    string
+		silence server.Silence
+	}{
+		{name: "no matchers", silence: server.Silence{EndsAt: now.Add(time.Hour)}},
+		{name: "ends before start", silence: server.Silence{Rule: "HighCPU", StartsAt: now, EndsAt: now.Add(-time.Hour)}},
+		{name: "bad pattern", silence: server.Silence{Metric: "[", EndsAt: now.Add(time.Hour)}},
+		{name: "bad weekday", silence: server.Silence{Rule: "HighCPU", EndsAt: now.Add(time.Hour),
+			Recurrence: &server.Recurrence{Weekdays: []string{"someday"}, Start: "02:00", Duration: "1h"}}},
+		{name: "bad start", silence: server.Silence{Rule: "HighCPU", EndsAt: now.Add(time.Hour),
+			Recurrence: &server.Recurrence{Start: "25:00", Duration: "1h"}}},
+		{name: "long window", silence: server.Silence{Rule: "HighCPU", EndsAt: now.Add(time.Hour),
+			Recurrence: &server.Recurrence{Start: "02:00", Duration: "200h"}}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := service.CreateSilence(ctx, tt.silence)
+			assert.ErrorIs(t, err, ErrInvalidSilence)
+		})
+	}
+}
+
+func TestService_IsSilenced(t *testing.T) {
+	service := New(memory.NewSilenceStorage(""))
+	ctx := context.Background()
+	now := time.Now()
+
+	_, err := service.CreateSilence(ctx, server.Silence{Rule: "HighCPU", Metric: "CPU*", Labels: map[string]string{"host": "a"}, StartsAt: now, EndsAt: now.Add(time.Hour)})
+	assert.NoError(t, err)
+
+	assert.True(t, service.IsSilenced(server.AlertState{Rule: "HighCPU", Series: "CPUutilization1;dc=x;host=a"}, now))
+	assert.False(t, service.IsSilenced(server.AlertState{Rule: "HighCPU", Series: "CPUutilization1;host=b"}, now))
+	assert.False(t, service.IsSilenced(server.AlertState{Rule: "HighCPU", Series: "Memory;host=a"}, now))
+	assert.False(t, service.IsSilenced(server.AlertState{Rule: "LowMemory", Series: "CPUutilization1;host=a"}, now))
+	assert.False(t, service.IsSilenced(server.AlertState{Rule: "HighCPU", Series: "CPUutilization1;host=a"}, now.Add(2*time.Hour)))
+}
+
+func TestService_MaintenanceWindow(t *testing.T) {
+	service := New(memory.NewSilenceStorage(""))
+	ctx := context.Background()
+	// 2024-06-01 is Saturday
+	saturday := time.Date(2024, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	_, err := service.CreateSilence(ctx, server.Silence{
+		Rule:       "HighCPU",
+		StartsAt:   saturday.AddDate(0, 0, -7),
+		EndsAt:     saturday.AddDate(1, 0, 0),
+		Recurrence: &server.Recurrence{Weekdays: []string{"Sat"}, Start: "23:00", Duration: "3h"},
+	})
+	assert.NoError(t, err)
+
+	alert := server.AlertState{Rule: "HighCPU", Series: "CPUutilization1"}
+	assert.False(t, service.IsSilenced(alert, saturday.Add(22*time.Hour)))
+	assert.True(t, service.IsSilenced(alert, saturday.Add(23*time.Hour+30*time.Minute)))
+	// window started on Saturday lasts until Sunday 02:00
+	assert.True(t, service.IsSilenced(alert, saturday.Add(25*time.Hour)))
+	assert.False(t, service.IsSilenced(alert, saturday.Add(26*time.Hour)))
+	assert.False(t, service.IsSilenced(alert, saturday.Add(47*time.Hour+30*time.Minute)))
+}
+
+func TestService_ExpireAndRestore(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "silences.json")
+	service := New(memory.NewSilenceStorage(file))
+	ctx := context.Background()
+
+	silence, err := service.CreateSilence(ctx, server.Silence{Rule: "HighCPU", EndsAt: time.Now().Add(time.Hour), CreatedBy: "ops", Comment: "upgrade"})
+	assert.NoError(t, err)
+	assert.NotEmpty(t, silence.ID)
+	assert.Len(t, service.FindAllSilences(ctx, false), 1)
+
+	_, err = service.ExpireSilence(ctx, "unknown")
+	assert.ErrorIs(t, err, ErrSilenceNotFound)
+
+	_, err = service.ExpireSilence(ctx, silence.ID)
+	assert.NoError(t, err)
+	assert.Empty(t, service.FindAllSilences(ctx, false))
+	assert.False(t, service.IsSilenced(server.AlertState{Rule: "HighCPU"}, time.Now()))
+
+	restored := New(memory.NewSilenceStorage(file))
+	assert.NoError(t, restored.Restore(ctx))
+	all := restored.FindAllSilences(ctx, true)
+	if assert.Len(t, all, 1) {
+		assert.Equal(t, "upgrade", all[0].Comment)
+		assert.False(t, time.Now().Before(all[0].EndsAt))
+	}
+}
@@ -0,0 +1,23 @@
+package slo
+
+import (
+	"context"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"time"
+)
+
+type metricsService interface {
+	SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error)
+
+	FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error)
+}
+
+type sloStorage interface {
+	SaveSLOCheckpoint(ctx context.Context, checkpoint server.SLOCheckpoint) error
+
+	FindAllSLOCheckpoints(ctx context.Context) ([]server.SLOCheckpoint, error)
+
+	// DeleteSLOCheckpoints removes checkpoints of SLO taken before the moment
+	DeleteSLOCheckpoints(ctx context.Context, slo string, before time.Time) error
+}
@@ -0,0 +1,93 @@
+package slo
+
+import (
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"regexp"
+	"time"
+)
+
+// Burn rate which spends 2% of 30 days budget in one hour
+const FastBurnThreshold = 14.4
+
+var namePattern = regexp.MustCompile(`^[A-Za-z0-9_]+$`)
+
+type burnWindow struct {
+	name     string
+	duration time.Duration
+}
+
+var burnWindows = []burnWindow{
+	{name: "5m", duration: 5 * time.Minute},
+	{name: "30m", duration: 30 * time.Minute},
+	{name: "1h", duration: time.Hour},
+	{name: "6h", duration: 6 * time.Hour},
+}
+
+// Objective is validated SLO configuration
+type Objective struct {
+	Name   string
+	Good   string
+	Total  string
+	Target float64
+	Window time.Duration
+}
+
+// NewObjectives validates all configured SLOs
+func NewObjectives(configs []server.SLOConfig) ([]Objective, error) {
+	names := make(map[string]bool, len(configs))
+	objectives := make([]Objective, 0, len(configs))
+	for _, config := range configs {
+		if !namePattern.MatchString(config.Name) {
+			return nil, fmt.Errorf("SLO name '%s' should contain only letters, digits and underscores", config.Name)
+		}
+		if names[config.Name] {
+			return nil, fmt.Errorf("SLO '%s' is duplicated", config.Name)
+		}
+		names[config.Name] = true
+
+		if config.Good == "" || config.Total == "" {
+			return nil, fmt.Errorf("SLO '%s': good and total counters should be filled", config.Name)
+		}
+		if config.Target <= 0 || config.Target >= 1 {
+			return nil, fmt.Errorf("SLO '%s': target should be between 0 and 1", config.Name)
+		}
+		if config.WindowDays <= 0 {
+			return nil, fmt.Errorf("SLO '%s': window should be positive", config.Name)
+		}
+
+		objectives = append(objectives, Objective{
+			Name:   config.Name,
+			Good:   config.Good,
+			Total:  config.Total,
+			Target: config.Target,
+			Window: time.Duration(config.WindowDays) * 24 * time.Hour,
+		})
+	}
+	return objectives, nil
+}
+
+func (o Objective) attainmentGauge() string {
+	return fmt.Sprintf("slo_%s_attainment", o.Name)
+}
+
+func (o Objective) errorBudgetGauge() string {
+	return fmt.Sprintf("slo_%s_error_budget_remaining", o.Name)
+}
+
+func (o Objective) burnRateGauge(window string) string {
+	return fmt.Sprintf("slo_%s_burn_rate_%s", o.Name, window)
+}
+
+// AlertRules makes multi-window fast burn alert rule for every SLO. Alert fires when budget
+// is burning fast during the last hour and is still burning during the last 5 minutes
+func AlertRules(objectives []Objective) []server.AlertRuleConfig {
+	rules := make([]server.AlertRuleConfig, 0, len(objectives))
+	for _, objective := range objectives {
+		rules = append(rules, server.AlertRuleConfig{
+			Name: fmt.Sprintf("SLOFastBurn_%s", objective.Name),
+			Expr: fmt.Sprintf("min(%s, %s) > %g", objective.burnRateGauge("1h"), objective.burnRateGauge("5m"), FastBurnThreshold),
+		})
+	}
+	return rules
+}
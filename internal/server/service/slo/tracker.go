@@ -0,0 +1,240 @@
+package slo
+
+import (
+	"context"
+	"errors"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"go.uber.org/zap"
+	"sort"
+	"sync"
+	"time"
+)
+
+// Counters are persisted not more often than this, current values are used between checkpoints
+const checkpointInterval = time.Minute
+
+// Tracker periodically takes checkpoints of SLO counters, computes attainment,
+// error budget and burn rates and saves them as gauges
+type Tracker struct {
+	mu          sync.Mutex
+	objectives  []Objective
+	service     metricsService
+	storage     sloStorage
+	logger      *zap.Logger
+	checkpoints map[string][]server.SLOCheckpoint
+	statuses    map[string]server.SLOStatus
+}
+
+func New(objectives []Objective, service metricsService, storage sloStorage, logger *zap.Logger) *Tracker {
+	return &Tracker{
+		objectives:  objectives,
+		service:     service,
+		storage:     storage,
+		logger:      logger,
+		checkpoints: make(map[string][]server.SLOCheckpoint),
+		statuses:    make(map[string]server.SLOStatus),
+	}
+}
+
+// Run restores persisted checkpoints and evaluates SLOs with interval until context is done
+func (t *Tracker) Run(ctx context.Context, interval time.Duration) {
+	if len(t.objectives) == 0 {
+		return
+	}
+	if err := t.restore(ctx); err != nil {
+		t.logger.Error("Error during restore SLO checkpoints", zap.Error(err))
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if err := t.Evaluate(ctx, now); err != nil {
+				t.logger.Error("Error during evaluate SLOs", zap.Error(err))
+			}
+		}
+	}
+}
+
+func (t *Tracker) restore(ctx context.Context) error {
+	checkpoints, err := t.storage.FindAllSLOCheckpoints(ctx)
+	if err != nil {
+		return err
+	}
+	sort.Slice(checkpoints, func(i, j int) bool {
+		return checkpoints[i].Time.Before(checkpoints[j].Time)
+	})
+
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	for _, checkpoint := range checkpoints {
+		t.checkpoints[checkpoint.SLO] = append(t.checkpoints[checkpoint.SLO], checkpoint)
+	}
+	return nil
+}
+
+// Evaluate all SLOs at the moment
+func (t *Tracker) Evaluate(ctx context.Context, now time.Time) error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	var gauges []common.MetricRequestDto
+	var errs []error
+	for _, objective := range t.objectives {
+		current, err := t.currentCheckpoint(ctx, objective, now)
+		if err != nil {
+			errs = append(errs, err)
+			continue
+		}
+
+		if err := t.addCheckpoint(ctx, objective, current); err != nil {
+			errs = append(errs, err)
+		}
+
+		series := t.checkpoints[objective.Name]
+		if len(series) == 0 || series[len(series)-1].Time.Before(current.Time) {
+			series = append(series[:len(series):len(series)], current)
+		}
+
+		status := computeStatus(objective, series, now)
+		t.statuses[objective.Name] = status
+		gauges = append(gauges, gauge(objective.attainmentGauge(), status.Attainment), gauge(objective.errorBudgetGauge(), status.ErrorBudgetRemaining))
+		for _, window := range burnWindows {
+			gauges = append(gauges, gauge(objective.burnRateGauge(window.name), status.BurnRates[window.name]))
+		}
+	}
+
+	if len(gauges) > 0 {
+		if _, err := t.service.SaveMetrics(ctx, gauges); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// currentCheckpoint reads counters. Counter which is not reported yet is treated as zero
+func (t *Tracker) currentCheckpoint(ctx context.Context, objective Objective, now time.Time) (server.SLOCheckpoint, error) {
+	checkpoint := server.SLOCheckpoint{SLO: objective.Name, Time: now}
+	for _, counter := range []struct {
+		name  string
+		value *float64
+	}{{name: objective.Good, value: &checkpoint.Good}, {name: objective.Total, value: &checkpoint.Total}} {
+		metric, err := t.service.FindOneMetric(ctx, counter.name, common.Counter)
+		if err != nil {
+			var notFoundError *server.MetricNotFoundError
+			if errors.As(err, &notFoundError) {
+				continue
+			}
+			return checkpoint, err
+		}
+		if metric.Delta != nil {
+			*counter.value = float64(*metric.Delta)
+		}
+	}
+	return checkpoint, nil
+}
+
+// addCheckpoint persists checkpoint when previous one is old enough and drops checkpoints out of SLO window.
+// The last checkpoint before window is kept as a base of increase
+func (t *Tracker) addCheckpoint(ctx context.Context, objective Objective, checkpoint server.SLOCheckpoint) error {
+	series := t.checkpoints[objective.Name]
+	if len(series) > 0 && checkpoint.Time.Sub(series[len(series)-1].Time) < checkpointInterval {
+		return nil
+	}
+	if err := t.storage.SaveSLOCheckpoint(ctx, checkpoint); err != nil {
+		return err
+	}
+	series = append(series, checkpoint)
+
+	start := checkpoint.Time.Add(-objective.Window)
+	base := sort.Search(len(series), func(i int) bool {
+		return series[i].Time.After(start)
+	}) - 1
+	if base > 0 {
+		if err := t.storage.DeleteSLOCheckpoints(ctx, objective.Name, series[base].Time); err != nil {
+			return err
+		}
+		series = append([]server.SLOCheckpoint(nil), series[base:]...)
+	}
+	t.checkpoints[objective.Name] = series
+	return nil
+}
+
+func computeStatus(objective Objective, series []server.SLOCheckpoint, now time.Time) server.SLOStatus {
+	budget := 1 - objective.Target
+	good, total := increase(series, now.Add(-objective.Window))
+
+	status := server.SLOStatus{
+		Name:                 objective.Name,
+		Good:                 objective.Good,
+		Total:                objective.Total,
+		Target:               objective.Target,
+		Window:               objective.Window.String(),
+		Attainment:           1,
+		ErrorBudgetRemaining: 1,
+		BurnRates:            make(map[string]float64, len(burnWindows)),
+		UpdatedAt:            now,
+	}
+	if total > 0 {
+		status.Attainment = good / total
+		status.ErrorBudgetRemaining = 1 - (1-status.Attainment)/budget
+	}
+
+	for _, window := range burnWindows {
+		good, total := increase(series, now.Add(-window.duration))
+		if total > 0 {
+			status.BurnRates[window.name] = (1 - good/total) / budget
+		} else {
+			status.BurnRates[window.name] = 0
+		}
+	}
+	return status
+}
+
+// increase sums growth of counters since the moment. The last checkpoint before the moment is used as a base.
+// Decrease of counter is treated as reset, so value after reset is counted as increase
+func increase(series []server.SLOCheckpoint, since time.Time) (float64, float64) {
+	first := sort.Search(len(series), func(i int) bool {
+		return series[i].Time.After(since)
+	})
+	if first > 0 {
+		first--
+	}
+
+	var good, total float64
+	for i := first + 1; i < len(series); i++ {
+		good += counterIncrease(series[i-1].Good, series[i].Good)
+		total += counterIncrease(series[i-1].Total, series[i].Total)
+	}
+	return good, total
+}
+
+func counterIncrease(previous float64, current float64) float64 {
+	if current < previous {
+		return current
+	}
+	return current - previous
+}
+
+func gauge(name string, value float64) common.MetricRequestDto {
+	return common.MetricRequestDto{ID: name, MType: common.Gauge, Value: &value}
+}
+
+// FindAllSLOs returns statuses computed by the last evaluation
+func (t *Tracker) FindAllSLOs(ctx context.Context) []server.SLOStatus {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	result := make([]server.SLOStatus, 0, len(t.objectives))
+	for _, objective := range t.objectives {
+		if status, ok := t.statuses[objective.Name]; ok {
+			result = append(result, status)
+		}
+	}
+	return result
+}
@@ -0,0 +1,102 @@
+package slo
+
+import (
+	"context"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/desepticon55/metrics-collector/internal/server/storage/memory"
+	"github.com/stretchr/testify/assert"
+	"go.uber.org/zap"
+	"testing"
+	"time"
+)
+
+type stubService struct {
+	counters map[string]int64
+	gauges   map[string]float64
+}
+
+func (s *stubService) SaveMetrics(ctx context.Context, request []common.MetricRequestDto) ([]common.MetricResponseDto, error) {
+	for _, metric := range request {
+		s.gauges[metric.ID] = *metric.Value
+	}
+	return nil, nil
+}
+
+func (s *stubService) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (common.MetricResponseDto, error) {
+	delta, ok := s.counters[metricName]
+	if !ok {
+		return common.MetricResponseDto{}, &server.MetricNotFoundError{}
+	}
+	return common.MetricResponseDto{ID: metricName, MType: common.Counter, Delta: &delta}, nil
+}
+
+func TestTracker_Evaluate(t *testing.T) {
+	objectives, err := NewObjectives([]server.SLOConfig{{Name: "api", Good: "good_requests", Total: "total_requests", Target: 0.99, WindowDays: 1}})
+	assert.NoError(t, err)
+
+	service := &stubService{counters: map[string]int64{}, gauges: map[string]float64{}}
+	storage := memory.NewSLOStorage("")
+	tracker := New(objectives, service, storage, zap.NewNop())
+	ctx := context.Background()
+	start := time.Now().Add(-2 * time.Hour)
+
+	// counters are not reported yet
+	assert.NoError(t, tracker.Evaluate(ctx, start))
+	assert.Equal(t, 1.0, service.gauges["slo_api_attainment"])
+
+	// 1% of errors during the first hour, then 50% of errors during the last 5 minutes
+	service.counters["good_requests"] = 9900
+	service.counters["total_requests"] = 10000
+	assert.NoError(t, tracker.Evaluate(ctx, start.Add(time.Hour)))
+	service.counters["good_requests"] = 9950
+	service.counters["total_requests"] = 10100
+	assert.NoError(t, tracker.Evaluate(ctx, start.Add(2*time.Hour-5*time.Minute)))
+	service.counters["good_requests"] = 10000
+	service.counters["total_requests"] = 10200
+	assert.NoError(t, tracker.Evaluate(ctx, start.Add(2*time.Hour)))
+
+	statuses := tracker.FindAllSLOs(ctx)
+	if assert.Len(t, statuses, 1) {
+		status := statuses[0]
+		assert.InDelta(t, 10000.0/10200, status.Attainment, 1e-9)
+		assert.InDelta(t, 1-(200.0/10200)/0.01, status.ErrorBudgetRemaining, 1e-9)
+		assert.InDelta(t, 50.0, status.BurnRates["5m"], 1e-9)
+		assert.InDelta(t, 100.0/200/0.01, status.BurnRates["1h"], 1e-9)
+	}
+	assert.InDelta(t, 50.0, service.gauges["slo_api_burn_rate_5m"], 1e-9)
+
+	// counters are restored from storage
+	restored := New(objectives, service, storage, zap.NewNop())
+	assert.NoError(t, restored.restore(ctx))
+	assert.NoError(t, restored.Evaluate(ctx, start.Add(2*time.Hour+time.Second)))
+	assert.InDelta(t, 10000.0/10200, restored.FindAllSLOs(ctx)[0].Attainment, 1e-9)
+}
+
+func TestTracker_CounterReset(t *testing.T) {
+	series := []server.SLOCheckpoint{
+		{Time: time.Unix(0, 0), Good: 90, Total: 100},
+		{Time: time.Unix(60, 0), Good: 180, Total: 200},
+		{Time: time.Unix(120, 0), Good: 10, Total: 20},
+	}
+	good, total := increase(series, time.Unix(-1, 0))
+	assert.Equal(t, 100.0, good)
+	assert.Equal(t, 120.0, total)
+}
+
+func TestNewObjectives(t *testing.T) {
+	for _, config := range []server.SLOConfig{
+		{Name: "api-v1", Good: "good", Total: "total", Target: 0.99, WindowDays: 30},
+		{Name: "api", Total: "total", Target: 0.99, WindowDays: 30},
+		{Name: "api", Good: "good", Total: "total", Target: 99.9, WindowDays: 30},
+		{Name: "api", Good: "good", Total: "total", Target: 0.99},
+	} {
+		_, err := NewObjectives([]server.SLOConfig{config})
+		assert.Error(t, err, fmt.Sprint(config))
+	}
+
+	objectives, err := NewObjectives([]server.SLOConfig{{Name: "api", Good: "good", Total: "total", Target: 0.999, WindowDays: 30}})
+	assert.NoError(t, err)
+	assert.Equal(t, []server.AlertRuleConfig{{Name: "SLOFastBurn_api", Expr: "min(slo_api_burn_rate_1h, slo_api_burn_rate_5m) > 14.4"}}, AlertRules(objectives))
+}
@@ -0,0 +1,155 @@
+package stream
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"path"
+	"sync"
+)
+
+const subscriptionBufferSize = 256
+
+// Event is a single metric change with sequence number used as resume token
+type Event struct {
+	Seq    uint64                   `json:"seq"`
+	Metric common.MetricResponseDto `json:"metric"`
+}
+
+// Filter selects events by metric name pattern (path.Match syntax) and type. Empty fields match everything
+type Filter struct {
+	Name string
+	Type common.MetricType
+}
+
+func (f Filter) Match(metric common.MetricResponseDto) bool {
+	if f.Type != "" && f.Type != metric.MType {
+		return false
+	}
+	if f.Name != "" {
+		matched, err := path.Match(f.Name, metric.ID)
+		if err != nil || !matched {
+			return false
+		}
+	}
+	return true
+}
+
+type Subscription struct {
+	filter Filter
+	events chan Event
+	closed bool
+}
+
+// Events returns channel of metric changes. Channel is closed when subscriber is too slow
+// to read events or was unsubscribed
+func (s *Subscription) Events() <-chan Event {
+	return s.events
+}
+
+// Broker fans out metric changes applied by storage to subscribers and keeps
+// the last events to resume interrupted subscriptions
+type Broker struct {
+	mu          sync.Mutex
+	mapper      metricMapper
+	seq         uint64
+	history     []Event
+	historySize int
+	subscribers map[*Subscription]struct{}
+}
+
+func New(mapper metricMapper, historySize int) *Broker {
+	return &Broker{
+		mapper:      mapper,
+		history:     make([]Event, 0, historySize),
+		historySize: historySize,
+		subscribers: make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish is server.MetricsListener which should be registered in storage
+func (b *Broker) Publish(metrics []server.Metric) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	for _, metric := range metrics {
+		b.seq++
+		event := Event{Seq: b.seq, Metric: b.mapper.MapDomainModelToResponse(metric)}
+
+		if b.historySize > 0 {
+			b.history = append(b.history, event)
+			if len(b.history) > b.historySize {
+				b.history = b.history[len(b.history)-b.historySize:]
+			}
+		}
+
+		for subscription := range b.subscribers {
+			if !subscription.filter.Match(event.Metric) {
+				continue
+			}
+			select {
+			case subscription.events <- event:
+			default:
+				b.closeSubscription(subscription)
+			}
+		}
+	}
+}
+
+// Subscribe starts receiving events. When resumeFrom is not zero all retained events
+// with greater sequence number are replayed first
+func (b *Broker) Subscribe(filter Filter, resumeFrom uint64) *Subscription {
+	subscription, _ := b.Resume(filter, resumeFrom)
+	return subscription
+}
+
+// Resume works like Subscribe and also reports whether all events after resumeFrom were retained,
+// so subscriber knows that nothing was missed
+func (b *Broker) Resume(filter Filter, resumeFrom uint64) (*Subscription, bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	complete := resumeFrom == b.seq
+	var replay []Event
+	if resumeFrom > 0 && resumeFrom < b.seq {
+		complete = len(b.history) > 0 && b.history[0].Seq <= resumeFrom+1
+		for _, event := range b.history {
+			if event.Seq > resumeFrom && filter.Match(event.Metric) {
+				replay = append(replay, event)
+			}
+		}
+	}
+
+	subscription := &Subscription{
+		filter: filter,
+		events: make(chan Event, subscriptionBufferSize+len(replay)),
+	}
+	for _, event := range replay {
+		subscription.events <- event
+	}
+	b.subscribers[subscription] = struct{}{}
+	return subscription, complete
+}
+
+func (b *Broker) Unsubscribe(subscription *Subscription) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.closeSubscription(subscription)
+}
+
+// LastSeq returns sequence number of the last published event
+func (b *Broker) LastSeq() uint64 {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	return b.seq
+}
+
+func (b *Broker) closeSubscription(subscription *Subscription) {
+	if subscription.closed {
+		return
+	}
+	subscription.closed = true
+	close(subscription.events)
+	delete(b.subscribers, subscription)
+}
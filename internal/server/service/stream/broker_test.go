@@ -0,0 +1,102 @@
+package stream
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	metricsMappers "github.com/desepticon55/metrics-collector/internal/server/mapper/metrics"
+	"github.com/go-playground/validator/v10"
+	"github.com/stretchr/testify/assert"
+	"testing"
+)
+
+func gauge(name string, value float64) server.Metric {
+	return &server.Gauge{BaseMetric: server.BaseMetric{Name: name, Type: common.Gauge}, Value: value}
+}
+
+func counter(name string, value int64) server.Metric {
+	return &server.Counter{BaseMetric: server.BaseMetric{Name: name, Type: common.Counter}, Value: value}
+}
+
+func TestBroker_PublishWithFilter(t *testing.T) {
+	broker := New(metricsMappers.NewMapper(validator.New()), 10)
+	subscription := broker.Subscribe(Filter{Name: "CPU*", Type: common.Gauge}, 0)
+
+	broker.Publish([]server.Metric{gauge("CPUutilization1", 10), gauge("FreeMemory", 1), counter("CPUcount", 1)})
+
+	event := <-subscription.Events()
+	assert.Equal(t, uint64(1), event.Seq)
+	assert.Equal(t, "CPUutilization1", event.Metric.ID)
+	assert.Empty(t, subscription.Events())
+	assert.Equal(t, uint64(3), broker.LastSeq())
+}
+
+func TestBroker_Resume(t *testing.T) {
+	broker := New(metricsMappers.NewMapper(validator.New()), 2)
+	broker.Publish([]server.Metric{gauge("a", 1), gauge("b", 2), gauge("c", 3)})
+
+	subscription := broker.Subscribe(Filter{}, 1)
+	event := <-subscription.Events()
+	assert.Equal(t, uint64(2), event.Seq)
+	event = <-subscription.Events()
+	assert.Equal(t, uint64(3), event.Seq)
+	assert.Empty(t, subscription.Events())
+}
+
+func TestBroker_ResumeReportsMissedEvents(t *testing.T) {
+	broker := New(metricsMappers.NewMapper(validator.New()), 2)
+	broker.Publish([]server.Metric{gauge("a", 1), gauge("b", 2), gauge("c", 3)})
+
+	for _, tt := range []struct {
+		resumeFrom uint64
+		complete   bool
+	}{
+		{resumeFrom: 0, complete: false},
+		{resumeFrom: 1, complete: true},
+		{resumeFrom: 3, complete: true},
+		{resumeFrom: 5, complete: false},
+	} {
+		subscription, complete := broker.Resume(Filter{}, tt.resumeFrom)
+		assert.Equal(t, tt.complete, complete, tt.resumeFrom)
+		broker.Unsubscribe(subscription)
+	}
+
+	// history does not contain the second event anymore
+	broker.Publish([]server.Metric{gauge("d", 4)})
+	subscription, complete := broker.Resume(Filter{}, 1)
+	assert.False(t, complete)
+	broker.Unsubscribe(subscription)
+}
+
+func TestBroker_SlowSubscriberIsDisconnected(t *testing.T) {
+	broker := New(metricsMappers.NewMapper(validator.New()), 0)
+	subscription := broker.Subscribe(Filter{}, 0)
+
+	for i := 0; i <= subscriptionBufferSize; i++ {
+		broker.Publish([]server.Metric{gauge("a", float64(i))})
+	}
+
+	received := 0
+	for range subscription.Events() {
+		received++
+	}
+	assert.Equal(t, subscriptionBufferSize, received)
+
+	broker.Unsubscribe(subscription)
+}
+
+func TestCoalescer_KeepsLatestEventOfMetric(t *testing.T) {
+	broker := New(metricsMappers.NewMapper(validator.New()), 0)
+	subscription := broker.Subscribe(Filter{}, 0)
+	coalescer := NewCoalescer(subscription)
+
+	broker.Publish([]server.Metric{gauge("a", 1), gauge("b", 2), gauge("a", 3), counter("a", 4)})
+	broker.Unsubscribe(subscription)
+	<-coalescer.Done()
+
+	events := coalescer.Take()
+	if assert.Len(t, events, 3) {
+		assert.Equal(t, []uint64{2, 3, 4}, []uint64{events[0].Seq, events[1].Seq, events[2].Seq})
+		assert.Equal(t, 3.0, *events[1].Metric.Value)
+	}
+	assert.Empty(t, coalescer.Take())
+}
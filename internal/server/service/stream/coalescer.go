@@ -0,0 +1,72 @@
+package stream
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"sort"
+	"sync"
+)
+
+type metricKey struct {
+	name       string
+	metricType common.MetricType
+}
+
+// Coalescer reads subscription as soon as events arrive and keeps only the latest pending event
+// of every metric, so slow subscriber gets current values instead of every intermediate change
+type Coalescer struct {
+	mu      sync.Mutex
+	pending map[metricKey]Event
+	ready   chan struct{}
+	done    chan struct{}
+}
+
+func NewCoalescer(subscription *Subscription) *Coalescer {
+	c := &Coalescer{
+		pending: make(map[metricKey]Event),
+		ready:   make(chan struct{}, 1),
+		done:    make(chan struct{}),
+	}
+	go c.run(subscription)
+	return c
+}
+
+func (c *Coalescer) run(subscription *Subscription) {
+	defer close(c.done)
+
+	for event := range subscription.Events() {
+		c.mu.Lock()
+		c.pending[metricKey{name: event.Metric.ID, metricType: event.Metric.MType}] = event
+		c.mu.Unlock()
+
+		select {
+		case c.ready <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// Ready receives a value when there are pending events
+func (c *Coalescer) Ready() <-chan struct{} {
+	return c.ready
+}
+
+// Done is closed when subscription is closed. Events pending at this moment are still returned by Take
+func (c *Coalescer) Done() <-chan struct{} {
+	return c.done
+}
+
+// Take returns pending events ordered by sequence number
+func (c *Coalescer) Take() []Event {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	events := make([]Event, 0, len(c.pending))
+	for key, event := range c.pending {
+		events = append(events, event)
+		delete(c.pending, key)
+	}
+	sort.Slice(events, func(i, j int) bool {
+		return events[i].Seq < events[j].Seq
+	})
+	return events
+}
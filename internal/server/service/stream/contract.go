@@ -0,0 +1,10 @@
+package stream
+
+import (
+	"github.com/desepticon55/metrics-collector/internal/common"
+	"github.com/desepticon55/metrics-collector/internal/server"
+)
+
+type metricMapper interface {
+	MapDomainModelToResponse(domainModel server.Metric) common.MetricResponseDto
+}
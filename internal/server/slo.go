@@ -0,0 +1,26 @@
+package server
+
+import "time"
+
+// SLOCheckpoint is a pair of counter values of SLO at the moment
+type SLOCheckpoint struct {
+	SLO   string    `json:"slo"`
+	Time  time.Time `json:"time"`
+	Good  float64   `json:"good"`
+	Total float64   `json:"total"`
+}
+
+// SLOStatus is current attainment of SLO. ErrorBudgetRemaining is a share of error budget which is
+// not spent yet (negative when budget is exhausted). Burn rate is a speed of spending budget over
+// short window, burn rate 1 spends exactly whole budget during SLO window
+type SLOStatus struct {
+	Name                 string             `json:"name"`
+	Good                 string             `json:"good"`
+	Total                string             `json:"total"`
+	Target               float64            `json:"target"`
+	Window               string             `json:"window"`
+	Attainment           float64            `json:"attainment"`
+	ErrorBudgetRemaining float64            `json:"error_budget_remaining"`
+	BurnRates            map[string]float64 `json:"burn_rates"`
+	UpdatedAt            time.Time          `json:"updated_at"`
+}
@@ -0,0 +1,68 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"log"
+	"os"
+	"sync"
+)
+
+// AlertStorage keeps alert states in memory and persists them to file on every change.
+// Persistence is disabled when file is empty
+type AlertStorage struct {
+	mu     sync.Mutex
+	file   string
+	states []server.AlertState
+}
+
+func NewAlertStorage(file string) *AlertStorage {
+	storage := &AlertStorage{file: file}
+	if file != "" {
+		if err := storage.loadFromFile(); err != nil {
+			log.Printf("Error during load alert states from file: %v", err)
+		}
+	}
+	return storage
+}
+
+func (s *AlertStorage) SaveAlertStates(ctx context.Context, states []server.AlertState) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.states = append([]server.AlertState(nil), states...)
+	if s.file == "" {
+		return nil
+	}
+	return writeFileAtomically(s.file, s.states)
+}
+
+func (s *AlertStorage) FindAllAlertStates(ctx context.Context) ([]server.AlertState, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]server.AlertState(nil), s.states...), nil
+}
+
+// Flush writes alert states to file again, so states whose write has failed are not lost on shutdown
+func (s *AlertStorage) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == "" {
+		return nil
+	}
+	return writeFileAtomically(s.file, s.states)
+}
+
+func (s *AlertStorage) loadFromFile() error {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(content, &s.states)
+}
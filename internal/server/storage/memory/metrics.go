@@ -8,6 +8,7 @@ import (
 	"github.com/desepticon55/metrics-collector/internal/server"
 	"log"
 	"os"
+	"path/filepath"
 	"sync"
 	"time"
 )
@@ -18,6 +19,7 @@ type Storage struct {
 	file            string
 	autoSaveEnabled bool
 	saveInterval    time.Duration
+	listeners       []server.MetricsListener
 }
 
 func New(file string, isNeedLoadData bool, saveInterval time.Duration) *Storage {
@@ -40,7 +42,20 @@ func New(file string, isNeedLoadData bool, saveInterval time.Duration) *Storage
 	return storage
 }
 
+// AddListener registers listener notified about every saved batch of metrics
+func (s *Storage) AddListener(listener server.MetricsListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
 func (s *Storage) SaveMetrics(ctx context.Context, metrics []server.Metric) ([]server.Metric, error) {
+	savedMetrics := s.applyMetrics(metrics)
+	return savedMetrics, s.persist()
+}
+
+func (s *Storage) applyMetrics(metrics []server.Metric) []server.Metric {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
@@ -66,15 +81,59 @@ func (s *Storage) SaveMetrics(ctx context.Context, metrics []server.Metric) ([]s
 		}
 	}
 
-	if !s.autoSaveEnabled {
-		err := s.saveToFile()
-		if err != nil {
-			log.Printf("Error during save metrics to file: %v", err)
-			return savedMetrics, err
-		}
+	s.notifyListeners(savedMetrics)
+	return savedMetrics
+}
+
+// ReplaceMetrics sets metrics to given values without accumulation of counters.
+// It's used to apply state replicated from primary server
+func (s *Storage) ReplaceMetrics(ctx context.Context, metrics []server.Metric) error {
+	s.replace(metrics, false)
+	return s.persist()
+}
+
+// RestoreSnapshot replaces all stored metrics with snapshot
+func (s *Storage) RestoreSnapshot(ctx context.Context, metrics []server.Metric) error {
+	s.replace(metrics, true)
+	return s.persist()
+}
+
+func (s *Storage) replace(metrics []server.Metric, reset bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if reset {
+		s.metrics = make(map[string]server.Metric, len(metrics))
 	}
+	for _, metric := range metrics {
+		s.metrics[fmt.Sprintf("%s_%s", metric.GetName(), metric.GetType())] = server.CloneMetric(metric)
+	}
+	s.notifyListeners(metrics)
+}
 
-	return savedMetrics, nil
+func (s *Storage) persist() error {
+	if s.autoSaveEnabled {
+		return nil
+	}
+	if err := s.saveToFile(); err != nil {
+		log.Printf("Error during save metrics to file: %v", err)
+		return err
+	}
+	return nil
+}
+
+func (s *Storage) notifyListeners(savedMetrics []server.Metric) {
+	if len(s.listeners) == 0 || len(savedMetrics) == 0 {
+		return
+	}
+
+	snapshot := make([]server.Metric, 0, len(savedMetrics))
+	for _, metric := range savedMetrics {
+		snapshot = append(snapshot, server.CloneMetric(metric))
+	}
+	for _, listener := range s.listeners {
+		listener(snapshot)
+	}
 }
 
 func (s *Storage) FindOneMetric(ctx context.Context, metricName string, metricType common.MetricType) (server.Metric, bool) {
@@ -97,6 +156,15 @@ func (s *Storage) FindAllMetrics(ctx context.Context) ([]server.Metric, error) {
 	return values, nil
 }
 
+// Flush writes metrics to file. Metrics saved with interval are written by the next periodic save only,
+// so storage should be flushed on shutdown
+func (s *Storage) Flush() error {
+	if s.file == "" {
+		return nil
+	}
+	return s.saveToFile()
+}
+
 func (s *Storage) loadFromFile() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
@@ -131,12 +199,6 @@ func (s *Storage) saveToFile() error {
 	s.mu.Lock()
 	defer s.mu.Unlock()
 
-	file, err := os.Create(s.file)
-	if err != nil {
-		return err
-	}
-	defer file.Close()
-
 	metricsMap := make(map[string]json.RawMessage)
 	for key, metric := range s.metrics {
 		data, err := server.MarshalMetric(metric)
@@ -146,8 +208,26 @@ func (s *Storage) saveToFile() error {
 		metricsMap[key] = data
 	}
 
-	encoder := json.NewEncoder(file)
-	return encoder.Encode(metricsMap)
+	return writeFileAtomically(s.file, metricsMap)
+}
+
+// writeFileAtomically writes JSON to temporary file and renames it,
+// so readers never see partially written file
+func writeFileAtomically(path string, value any) error {
+	file, err := os.CreateTemp(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if err := json.NewEncoder(file).Encode(value); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
 }
 
 func (s *Storage) startAutoSave() {
@@ -30,3 +30,65 @@ func TestStorage_AutoSave(t *testing.T) {
 	assert.True(t, exists)
 	assert.Equal(t, counterMetric, foundMetric)
 }
+
+func TestStorage_Flush(t *testing.T) {
+	file := t.TempDir() + "/metrics.json"
+	// metrics are saved to file only by interval which doesn't pass during test
+	storage := New(file, false, time.Hour)
+
+	counterMetric := &server.Counter{BaseMetric: server.BaseMetric{Name: "requests", Type: common.Counter}, Value: 10}
+	_, err := storage.SaveMetrics(context.Background(), []server.Metric{counterMetric})
+	assert.NoError(t, err)
+	assert.NoError(t, storage.Flush())
+
+	loadedStorage := New(file, true, 0)
+	foundMetric, exists := loadedStorage.FindOneMetric(context.Background(), "requests", common.Counter)
+	assert.True(t, exists)
+	assert.Equal(t, counterMetric, foundMetric)
+}
+
+func TestStorage_Listeners(t *testing.T) {
+	file, err := os.CreateTemp("", "metrics_storage_listeners_test_*.json")
+	assert.NoError(t, err)
+	defer os.Remove(file.Name())
+
+	storage := New(file.Name(), false, 0)
+
+	var notified []server.Metric
+	storage.AddListener(func(metrics []server.Metric) {
+		notified = append(notified, metrics...)
+	})
+
+	counterMetric := &server.Counter{BaseMetric: server.BaseMetric{Name: "requests", Type: common.Counter}, Value: 10}
+	_, err = storage.SaveMetrics(context.Background(), []server.Metric{counterMetric})
+	assert.NoError(t, err)
+	_, err = storage.SaveMetrics(context.Background(), []server.Metric{&server.Counter{BaseMetric: server.BaseMetric{Name: "requests", Type: common.Counter}, Value: 5}})
+	assert.NoError(t, err)
+
+	assert.Len(t, notified, 2)
+	assert.Equal(t, int64(10), notified[0].(*server.Counter).Value)
+	assert.Equal(t, int64(15), notified[1].(*server.Counter).Value)
+}
+
+func TestStorage_ReplaceMetrics(t *testing.T) {
+	storage := New(t.TempDir()+"/metrics.json", false, 0)
+	requests := func(value int64) server.Metric {
+		return &server.Counter{BaseMetric: server.BaseMetric{Name: "requests", Type: common.Counter}, Value: value}
+	}
+	stale := &server.Gauge{BaseMetric: server.BaseMetric{Name: "stale", Type: common.Gauge}, Value: 1}
+
+	_, err := storage.SaveMetrics(context.Background(), []server.Metric{requests(10), stale})
+	assert.NoError(t, err)
+
+	// replicated value is applied twice without accumulation
+	assert.NoError(t, storage.ReplaceMetrics(context.Background(), []server.Metric{requests(25)}))
+	assert.NoError(t, storage.ReplaceMetrics(context.Background(), []server.Metric{requests(25)}))
+	found, _ := storage.FindOneMetric(context.Background(), "requests", common.Counter)
+	assert.Equal(t, int64(25), found.(*server.Counter).Value)
+
+	assert.NoError(t, storage.RestoreSnapshot(context.Background(), []server.Metric{requests(30)}))
+	_, exists := storage.FindOneMetric(context.Background(), "stale", common.Gauge)
+	assert.False(t, exists)
+	found, _ = storage.FindOneMetric(context.Background(), "requests", common.Counter)
+	assert.Equal(t, int64(30), found.(*server.Counter).Value)
+}
@@ -0,0 +1,79 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"log"
+	"os"
+	"sync"
+)
+
+// SilenceStorage keeps silences in memory and persists them to file on every change.
+// Persistence is disabled when file is empty
+type SilenceStorage struct {
+	mu       sync.Mutex
+	file     string
+	silences []server.Silence
+}
+
+func NewSilenceStorage(file string) *SilenceStorage {
+	storage := &SilenceStorage{file: file}
+	if file != "" {
+		if err := storage.loadFromFile(); err != nil {
+			log.Printf("Error during load silences from file: %v", err)
+		}
+	}
+	return storage
+}
+
+func (s *SilenceStorage) SaveSilence(ctx context.Context, silence server.Silence) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	replaced := false
+	for i := range s.silences {
+		if s.silences[i].ID == silence.ID {
+			s.silences[i] = silence
+			replaced = true
+			break
+		}
+	}
+	if !replaced {
+		s.silences = append(s.silences, silence)
+	}
+
+	if s.file == "" {
+		return nil
+	}
+	return writeFileAtomically(s.file, s.silences)
+}
+
+func (s *SilenceStorage) FindAllSilences(ctx context.Context) ([]server.Silence, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]server.Silence(nil), s.silences...), nil
+}
+
+// Flush writes silences to file again, so silences whose write has failed are not lost on shutdown
+func (s *SilenceStorage) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.file == "" {
+		return nil
+	}
+	return writeFileAtomically(s.file, s.silences)
+}
+
+func (s *SilenceStorage) loadFromFile() error {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(content, &s.silences)
+}
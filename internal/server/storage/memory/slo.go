@@ -0,0 +1,84 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"log"
+	"os"
+	"sync"
+	"time"
+)
+
+// SLOStorage keeps SLO checkpoints in memory and persists them to file on every change.
+// Persistence is disabled when file is empty
+type SLOStorage struct {
+	mu          sync.Mutex
+	file        string
+	checkpoints []server.SLOCheckpoint
+}
+
+func NewSLOStorage(file string) *SLOStorage {
+	storage := &SLOStorage{file: file}
+	if file != "" {
+		if err := storage.loadFromFile(); err != nil {
+			log.Printf("Error during load SLO checkpoints from file: %v", err)
+		}
+	}
+	return storage
+}
+
+func (s *SLOStorage) SaveSLOCheckpoint(ctx context.Context, checkpoint server.SLOCheckpoint) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.checkpoints = append(s.checkpoints, checkpoint)
+	return s.saveToFile()
+}
+
+func (s *SLOStorage) FindAllSLOCheckpoints(ctx context.Context) ([]server.SLOCheckpoint, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return append([]server.SLOCheckpoint(nil), s.checkpoints...), nil
+}
+
+func (s *SLOStorage) DeleteSLOCheckpoints(ctx context.Context, slo string, before time.Time) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	kept := s.checkpoints[:0]
+	for _, checkpoint := range s.checkpoints {
+		if checkpoint.SLO != slo || !checkpoint.Time.Before(before) {
+			kept = append(kept, checkpoint)
+		}
+	}
+	s.checkpoints = kept
+	return s.saveToFile()
+}
+
+// Flush writes checkpoints to file again, so checkpoints whose write has failed are not lost on shutdown
+func (s *SLOStorage) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveToFile()
+}
+
+func (s *SLOStorage) saveToFile() error {
+	if s.file == "" {
+		return nil
+	}
+	return writeFileAtomically(s.file, s.checkpoints)
+}
+
+func (s *SLOStorage) loadFromFile() error {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+	return json.Unmarshal(content, &s.checkpoints)
+}
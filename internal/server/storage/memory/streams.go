@@ -0,0 +1,93 @@
+package memory
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"log"
+	"os"
+	"sync"
+)
+
+// StreamPositionStorage keeps positions of agent streams in memory and persists them to file on every change.
+// Persistence is disabled when file is empty
+type StreamPositionStorage struct {
+	mu        sync.Mutex
+	file      string
+	positions map[string]server.StreamPosition
+}
+
+func NewStreamPositionStorage(file string) *StreamPositionStorage {
+	storage := &StreamPositionStorage{file: file, positions: make(map[string]server.StreamPosition)}
+	if file != "" {
+		if err := storage.loadFromFile(); err != nil {
+			log.Printf("Error during load stream positions from file: %v", err)
+		}
+	}
+	return storage
+}
+
+func (s *StreamPositionStorage) SaveStreamPosition(ctx context.Context, position server.StreamPosition) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.positions[position.AgentID] = position
+	return s.saveToFile()
+}
+
+func (s *StreamPositionStorage) FindAllStreamPositions(ctx context.Context) ([]server.StreamPosition, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	positions := make([]server.StreamPosition, 0, len(s.positions))
+	for _, position := range s.positions {
+		positions = append(positions, position)
+	}
+	return positions, nil
+}
+
+func (s *StreamPositionStorage) DeleteStreamPosition(ctx context.Context, agentID string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.positions, agentID)
+	return s.saveToFile()
+}
+
+// Flush writes positions to file again, so positions whose write has failed are not lost on shutdown
+func (s *StreamPositionStorage) Flush() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	return s.saveToFile()
+}
+
+func (s *StreamPositionStorage) saveToFile() error {
+	if s.file == "" {
+		return nil
+	}
+	positions := make([]server.StreamPosition, 0, len(s.positions))
+	for _, position := range s.positions {
+		positions = append(positions, position)
+	}
+	return writeFileAtomically(s.file, positions)
+}
+
+func (s *StreamPositionStorage) loadFromFile() error {
+	content, err := os.ReadFile(s.file)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil
+		}
+		return err
+	}
+
+	var positions []server.StreamPosition
+	if err := json.Unmarshal(content, &positions); err != nil {
+		return err
+	}
+	for _, position := range positions {
+		s.positions[position.AgentID] = position
+	}
+	return nil
+}
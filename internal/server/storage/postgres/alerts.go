@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+type AlertStorage struct {
+	pool   *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewAlertStorage(pool *pgxpool.Pool, logger *zap.Logger) *AlertStorage {
+	return &AlertStorage{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (s *AlertStorage) SaveAlertStates(ctx context.Context, states []server.AlertState) error {
+	tx, err := s.pool.Begin(ctx)
+	if err != nil {
+		return err
+	}
+
+	if _, err := tx.Exec(ctx, "DELETE FROM mtr_collector.alert_states"); err != nil {
+		return errors.Join(err, tx.Rollback(ctx))
+	}
+
+	query := `
+        INSERT INTO mtr_collector.alert_states (rule, series, status, value, active_at, fired_at, resolved_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7)
+    `
+	for _, state := range states {
+		_, err := tx.Exec(ctx, query, state.Rule, state.Series, state.Status, state.Value, state.ActiveAt, state.FiredAt, state.ResolvedAt)
+		if err != nil {
+			return errors.Join(err, tx.Rollback(ctx))
+		}
+	}
+
+	if err := tx.Commit(ctx); err != nil {
+		return errors.Join(err, tx.Rollback(ctx))
+	}
+	return nil
+}
+
+func (s *AlertStorage) FindAllAlertStates(ctx context.Context) ([]server.AlertState, error) {
+	query := "SELECT rule, series, status, value, active_at, fired_at, resolved_at FROM mtr_collector.alert_states"
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+	defer rows.Close()
+
+	var states []server.AlertState
+	for rows.Next() {
+		var state server.AlertState
+		if err := rows.Scan(&state.Rule, &state.Series, &state.Status, &state.Value, &state.ActiveAt, &state.FiredAt, &state.ResolvedAt); err != nil {
+			s.logger.Error("Error scanning row", zap.Error(err))
+			continue
+		}
+		states = append(states, state)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return states, nil
+}
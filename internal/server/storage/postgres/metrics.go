@@ -2,6 +2,7 @@ package postgres
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/desepticon55/metrics-collector/internal/common"
@@ -9,17 +10,41 @@ import (
 	"github.com/jackc/pgx/v4"
 	"github.com/jackc/pgx/v4/pgxpool"
 	"go.uber.org/zap"
+	"sync"
+	"time"
 )
 
+// Channel of notifications sent by trigger on every change of metrics table
+const changesChannel = "mtr_collector_metrics"
+
+const listenRetryDelay = 5 * time.Second
+
 type Storage struct {
-	pool   *pgxpool.Pool
-	logger *zap.Logger
+	pool      *pgxpool.Pool
+	logger    *zap.Logger
+	mu        sync.Mutex
+	listeners []server.MetricsListener
+
+	// deliveryMu serializes delivery of changes to listeners without blocking writes to database,
+	// versions keeps version of the last delivered change of every metric
+	deliveryMu sync.Mutex
+	versions   map[string]int64
+}
+
+// metricChange is changed row of metrics table. Version is taken from sequence on every change of row,
+// so later change of the same metric has greater version whatever order changes are received in
+type metricChange struct {
+	Name    string `json:"name"`
+	Type    string `json:"type"`
+	Value   string `json:"value"`
+	Version int64  `json:"version"`
 }
 
 func New(pool *pgxpool.Pool, logger *zap.Logger) *Storage {
 	return &Storage{
-		pool:   pool,
-		logger: logger,
+		pool:     pool,
+		logger:   logger,
+		versions: make(map[string]int64),
 	}
 }
 
@@ -95,44 +120,166 @@ func (s *Storage) FindAllMetrics(ctx context.Context) ([]server.Metric, error) {
 	return metrics, nil
 }
 
+// AddListener registers listener notified about every saved batch of metrics
+func (s *Storage) AddListener(listener server.MetricsListener) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	s.listeners = append(s.listeners, listener)
+}
+
 func (s *Storage) SaveMetrics(ctx context.Context, metrics []server.Metric) ([]server.Metric, error) {
-	savedMetrics, err := s.saveMetricsWithTx(ctx, metrics)
+	savedMetrics, changes, err := s.saveMetricsWithTx(ctx, metrics)
 	if err != nil {
 		s.logger.Error("Error saving metrics", zap.Error(err))
 		return nil, err
 	}
 
+	// change is delivered by whichever comes first of this call and notification of database,
+	// the other one is skipped by version
+	s.deliver(changes)
 	s.logger.Info("Successfully saved metrics", zap.Int("saved_metrics_count", len(savedMetrics)))
 	return savedMetrics, nil
 }
 
-func (s *Storage) saveMetricsWithTx(ctx context.Context, metrics []server.Metric) ([]server.Metric, error) {
+// Listen delivers changes of metrics table to listeners using LISTEN/NOTIFY, so changes written by other
+// servers sharing the database are received too. After every (re)connect rows changed since the last
+// delivered versions are read, so changes made while listening didn't work are not missed.
+// Listen reconnects after failures until context is done
+func (s *Storage) Listen(ctx context.Context) {
+	for {
+		err := s.listen(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		s.logger.Error("Error during listen metric changes", zap.Error(err))
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(listenRetryDelay):
+		}
+	}
+}
+
+func (s *Storage) listen(ctx context.Context) error {
+	// listening connection is blocked by waiting for notifications, so it's not taken from pool
+	conn, err := pgx.ConnectConfig(ctx, s.pool.Config().ConnConfig)
+	if err != nil {
+		return err
+	}
+	defer conn.Close(context.Background())
+
+	if _, err := conn.Exec(ctx, "LISTEN "+changesChannel); err != nil {
+		return err
+	}
+	// changes committed before LISTEN are not notified, so they are read from table
+	if err := s.resync(ctx, conn); err != nil {
+		return err
+	}
+
+	for {
+		notification, err := conn.WaitForNotification(ctx)
+		if err != nil {
+			return err
+		}
+
+		var change metricChange
+		if err := json.Unmarshal([]byte(notification.Payload), &change); err != nil {
+			s.logger.Error("Error during unmarshal metric change", zap.String("payload", notification.Payload), zap.Error(err))
+			continue
+		}
+		s.deliver([]metricChange{change})
+	}
+}
+
+func (s *Storage) resync(ctx context.Context, conn *pgx.Conn) error {
+	rows, err := conn.Query(ctx, "SELECT name, type, value, version FROM mtr_collector.metrics ORDER BY version")
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	var changes []metricChange
+	for rows.Next() {
+		var change metricChange
+		if err := rows.Scan(&change.Name, &change.Type, &change.Value, &change.Version); err != nil {
+			return err
+		}
+		changes = append(changes, change)
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	s.deliver(changes)
+	return nil
+}
+
+// deliver notifies listeners about changes newer than already delivered ones of the same metrics
+func (s *Storage) deliver(changes []metricChange) {
+	s.deliveryMu.Lock()
+	defer s.deliveryMu.Unlock()
+
+	var metrics []server.Metric
+	for _, change := range changes {
+		key := change.Name + "_" + change.Type
+		if change.Version <= s.versions[key] {
+			continue
+		}
+		metric, err := s.createMetricFromRow(change.Name, change.Type, change.Value)
+		if err != nil {
+			s.logger.Error("Error creating metric from change", zap.String("name", change.Name), zap.String("type", change.Type), zap.Error(err))
+			continue
+		}
+		s.versions[key] = change.Version
+		metrics = append(metrics, metric)
+	}
+	s.notifyListeners(metrics)
+}
+
+func (s *Storage) notifyListeners(metrics []server.Metric) {
+	s.mu.Lock()
+	listeners := s.listeners
+	s.mu.Unlock()
+
+	if len(listeners) == 0 || len(metrics) == 0 {
+		return
+	}
+	for _, listener := range listeners {
+		listener(metrics)
+	}
+}
+
+func (s *Storage) saveMetricsWithTx(ctx context.Context, metrics []server.Metric) ([]server.Metric, []metricChange, error) {
 	var savedMetrics []server.Metric
+	var changes []metricChange
 	tx, err := s.pool.Begin(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	for _, metric := range metrics {
-		savedMetric, e := s.saveMetricWithTx(ctx, tx, metric)
+		change, e := s.saveMetricWithTx(ctx, tx, metric)
 		if e != nil {
 			rollbackErr := tx.Rollback(ctx)
-			return nil, errors.Join(e, rollbackErr)
+			return nil, nil, errors.Join(e, rollbackErr)
 		}
-		savedMetrics = append(savedMetrics, savedMetric)
+		savedMetrics = append(savedMetrics, metric)
+		changes = append(changes, change)
 	}
 	err = tx.Commit(ctx)
 	if err != nil {
 		rollbackErr := tx.Rollback(ctx)
-		return nil, errors.Join(err, rollbackErr)
+		return nil, nil, errors.Join(err, rollbackErr)
 	}
-	return savedMetrics, nil
+	return savedMetrics, changes, nil
 }
 
-func (s *Storage) saveMetricWithTx(ctx context.Context, tx pgx.Tx, metric server.Metric) (server.Metric, error) {
+func (s *Storage) saveMetricWithTx(ctx context.Context, tx pgx.Tx, metric server.Metric) (metricChange, error) {
 	valueStr, err := metric.GetValueAsString()
 	if err != nil {
-		return nil, err
+		return metricChange{}, err
 	}
 
 	query := `
@@ -145,20 +292,20 @@ func (s *Storage) saveMetricWithTx(ctx context.Context, tx pgx.Tx, metric server
                 (CAST(metrics.value AS BIGINT) + CAST(EXCLUDED.value AS BIGINT))::TEXT
             ELSE EXCLUDED.value
         END
-        RETURNING value
+        RETURNING value, version
     `
-	var updatedValueStr string
-	err = tx.QueryRow(ctx, query, metric.GetName(), metric.GetType(), valueStr).Scan(&updatedValueStr)
+	change := metricChange{Name: metric.GetName(), Type: string(metric.GetType())}
+	err = tx.QueryRow(ctx, query, metric.GetName(), metric.GetType(), valueStr).Scan(&change.Value, &change.Version)
 	if err != nil {
-		return nil, err
+		return metricChange{}, err
 	}
 
-	err = metric.SetValueFromString(updatedValueStr)
+	err = metric.SetValueFromString(change.Value)
 	if err != nil {
-		return nil, err
+		return metricChange{}, err
 	}
 
-	return metric, nil
+	return change, nil
 }
 
 func (s *Storage) createMetricFromRow(name, metricType, valueStr string) (server.Metric, error) {
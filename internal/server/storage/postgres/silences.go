@@ -0,0 +1,91 @@
+package postgres
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+type SilenceStorage struct {
+	pool   *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewSilenceStorage(pool *pgxpool.Pool, logger *zap.Logger) *SilenceStorage {
+	return &SilenceStorage{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (s *SilenceStorage) SaveSilence(ctx context.Context, silence server.Silence) error {
+	labels, err := marshalJSONB(silence.Labels, len(silence.Labels) == 0)
+	if err != nil {
+		return err
+	}
+	recurrence, err := marshalJSONB(silence.Recurrence, silence.Recurrence == nil)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO mtr_collector.silences (id, rule, metric, labels, starts_at, ends_at, recurrence, created_by, comment, created_at)
+        VALUES ($1, $2, $3, $4, $5, $6, $7, $8, $9, $10)
+        ON CONFLICT (id) DO UPDATE SET starts_at = EXCLUDED.starts_at, ends_at = EXCLUDED.ends_at
+    `
+	_, err = s.pool.Exec(ctx, query, silence.ID, silence.Rule, silence.Metric, labels, silence.StartsAt, silence.EndsAt,
+		recurrence, silence.CreatedBy, silence.Comment, silence.CreatedAt)
+	return err
+}
+
+func (s *SilenceStorage) FindAllSilences(ctx context.Context) ([]server.Silence, error) {
+	query := "SELECT id, rule, metric, labels, starts_at, ends_at, recurrence, created_by, comment, created_at FROM mtr_collector.silences"
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+	defer rows.Close()
+
+	var silences []server.Silence
+	for rows.Next() {
+		var silence server.Silence
+		var labels, recurrence []byte
+		if err := rows.Scan(&silence.ID, &silence.Rule, &silence.Metric, &labels, &silence.StartsAt, &silence.EndsAt,
+			&recurrence, &silence.CreatedBy, &silence.Comment, &silence.CreatedAt); err != nil {
+			s.logger.Error("Error scanning row", zap.Error(err))
+			continue
+		}
+		if err := unmarshalJSONB(labels, &silence.Labels); err != nil {
+			s.logger.Error("Error unmarshal silence labels", zap.Error(err))
+			continue
+		}
+		if err := unmarshalJSONB(recurrence, &silence.Recurrence); err != nil {
+			s.logger.Error("Error unmarshal silence recurrence", zap.Error(err))
+			continue
+		}
+		silences = append(silences, silence)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return silences, nil
+}
+
+// marshalJSONB returns nil for empty values, so they are stored as NULL
+func marshalJSONB(value any, empty bool) ([]byte, error) {
+	if empty {
+		return nil, nil
+	}
+	return json.Marshal(value)
+}
+
+func unmarshalJSONB(content []byte, target any) error {
+	if content == nil {
+		return nil
+	}
+	return json.Unmarshal(content, target)
+}
@@ -0,0 +1,61 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+	"time"
+)
+
+type SLOStorage struct {
+	pool   *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewSLOStorage(pool *pgxpool.Pool, logger *zap.Logger) *SLOStorage {
+	return &SLOStorage{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (s *SLOStorage) SaveSLOCheckpoint(ctx context.Context, checkpoint server.SLOCheckpoint) error {
+	query := `
+        INSERT INTO mtr_collector.slo_checkpoints (slo, time, good, total)
+        VALUES ($1, $2, $3, $4)
+        ON CONFLICT (slo, time) DO UPDATE SET good = EXCLUDED.good, total = EXCLUDED.total
+    `
+	_, err := s.pool.Exec(ctx, query, checkpoint.SLO, checkpoint.Time, checkpoint.Good, checkpoint.Total)
+	return err
+}
+
+func (s *SLOStorage) FindAllSLOCheckpoints(ctx context.Context) ([]server.SLOCheckpoint, error) {
+	query := "SELECT slo, time, good, total FROM mtr_collector.slo_checkpoints ORDER BY time"
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+	defer rows.Close()
+
+	var checkpoints []server.SLOCheckpoint
+	for rows.Next() {
+		var checkpoint server.SLOCheckpoint
+		if err := rows.Scan(&checkpoint.SLO, &checkpoint.Time, &checkpoint.Good, &checkpoint.Total); err != nil {
+			s.logger.Error("Error scanning row", zap.Error(err))
+			continue
+		}
+		checkpoints = append(checkpoints, checkpoint)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return checkpoints, nil
+}
+
+func (s *SLOStorage) DeleteSLOCheckpoints(ctx context.Context, slo string, before time.Time) error {
+	_, err := s.pool.Exec(ctx, "DELETE FROM mtr_collector.slo_checkpoints WHERE slo = $1 AND time < $2", slo, before)
+	return err
+}
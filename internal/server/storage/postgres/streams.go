@@ -0,0 +1,73 @@
+package postgres
+
+import (
+	"context"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/internal/server"
+	"github.com/jackc/pgx/v4/pgxpool"
+	"go.uber.org/zap"
+)
+
+type StreamPositionStorage struct {
+	pool   *pgxpool.Pool
+	logger *zap.Logger
+}
+
+func NewStreamPositionStorage(pool *pgxpool.Pool, logger *zap.Logger) *StreamPositionStorage {
+	return &StreamPositionStorage{
+		pool:   pool,
+		logger: logger,
+	}
+}
+
+func (s *StreamPositionStorage) SaveStreamPosition(ctx context.Context, position server.StreamPosition) error {
+	unsaved, err := marshalJSONB(position.Unsaved, len(position.Unsaved) == 0)
+	if err != nil {
+		return err
+	}
+
+	query := `
+        INSERT INTO mtr_collector.stream_positions (agent_id, seq, partial_seq, unsaved, last_seen)
+        VALUES ($1, $2, $3, $4, $5)
+        ON CONFLICT (agent_id) DO UPDATE SET seq = EXCLUDED.seq, partial_seq = EXCLUDED.partial_seq,
+            unsaved = EXCLUDED.unsaved, last_seen = EXCLUDED.last_seen
+    `
+	_, err = s.pool.Exec(ctx, query, position.AgentID, int64(position.Seq), int64(position.PartialSeq), unsaved, position.LastSeen)
+	return err
+}
+
+func (s *StreamPositionStorage) FindAllStreamPositions(ctx context.Context) ([]server.StreamPosition, error) {
+	query := "SELECT agent_id, seq, partial_seq, unsaved, last_seen FROM mtr_collector.stream_positions"
+	rows, err := s.pool.Query(ctx, query)
+	if err != nil {
+		return nil, fmt.Errorf("error executing query: %w", err)
+	}
+	defer rows.Close()
+
+	var positions []server.StreamPosition
+	for rows.Next() {
+		var position server.StreamPosition
+		var seq, partialSeq int64
+		var unsaved []byte
+		if err := rows.Scan(&position.AgentID, &seq, &partialSeq, &unsaved, &position.LastSeen); err != nil {
+			s.logger.Error("Error scanning row", zap.Error(err))
+			continue
+		}
+		if err := unmarshalJSONB(unsaved, &position.Unsaved); err != nil {
+			s.logger.Error("Error unmarshal unsaved metrics of stream", zap.Error(err))
+			continue
+		}
+		position.Seq, position.PartialSeq = uint64(seq), uint64(partialSeq)
+		positions = append(positions, position)
+	}
+
+	if err := rows.Err(); err != nil {
+		return nil, fmt.Errorf("error iterating over rows: %w", err)
+	}
+	return positions, nil
+}
+
+func (s *StreamPositionStorage) DeleteStreamPosition(ctx context.Context, agentID string) error {
+	_, err := s.pool.Exec(ctx, "DELETE FROM mtr_collector.stream_positions WHERE agent_id = $1", agentID)
+	return err
+}
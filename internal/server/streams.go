@@ -0,0 +1,13 @@
+package server
+
+import "time"
+
+// StreamPosition is the last batch of agent stream applied by server. When batch after it was saved partially,
+// PartialSeq is its number and Unsaved lists its metrics which are saved when agent resends the batch
+type StreamPosition struct {
+	AgentID    string         `json:"agent_id"`
+	Seq        uint64         `json:"seq"`
+	PartialSeq uint64         `json:"partial_seq,omitempty"`
+	Unsaved    []FailedMetric `json:"unsaved,omitempty"`
+	LastSeen   time.Time      `json:"last_seen"`
+}
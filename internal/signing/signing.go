@@ -0,0 +1,155 @@
+package signing
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/desepticon55/metrics-collector/proto/metrics"
+	"google.golang.org/protobuf/proto"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// Signature timestamp may differ from clock of verifier not more than this, nonce is remembered for the same time
+const DefaultMaxSkew = 5 * time.Minute
+
+var (
+	ErrMissingSignature = errors.New("signature is missing")
+	ErrInvalidSignature = errors.New("signature is invalid")
+	ErrExpired          = errors.New("signature timestamp is out of allowed range")
+	ErrReplayed         = errors.New("signature nonce was already used")
+)
+
+// Signature is HMAC-SHA256 of payload made with timestamp (unix seconds) and random nonce
+type Signature struct {
+	Value     string
+	Timestamp int64
+	Nonce     string
+}
+
+// MetricsPayload is canonical serialization of metrics shared by HTTP and gRPC:
+// deterministic protobuf serialization of MetricsRequest containing only metrics
+func MetricsPayload(protoMetrics []*metrics.Metric) ([]byte, error) {
+	return MessagePayload(&metrics.MetricsRequest{Metrics: protoMetrics})
+}
+
+// MessagePayload is deterministic protobuf serialization of message
+func MessagePayload(message proto.Message) ([]byte, error) {
+	return proto.MarshalOptions{Deterministic: true}.Marshal(message)
+}
+
+// RequestPayload is payload of gRPC request. Requests carrying metrics are signed with their metrics only,
+// so signature doesn't depend on other fields
+func RequestPayload(request proto.Message) ([]byte, error) {
+	switch r := request.(type) {
+	case interface{ GetMetrics() []*metrics.Metric }:
+		return MetricsPayload(r.GetMetrics())
+	case interface{ GetMetric() *metrics.Metric }:
+		if r.GetMetric() == nil {
+			return MetricsPayload(nil)
+		}
+		return MetricsPayload([]*metrics.Metric{r.GetMetric()})
+	default:
+		return MessagePayload(request)
+	}
+}
+
+// Sign signs payload at current moment with new nonce
+func Sign(key string, payload []byte) Signature {
+	nonce := make([]byte, 16)
+	// crypto/rand doesn't fail on supported platforms
+	_, _ = rand.Read(nonce)
+
+	signature := Signature{Timestamp: time.Now().Unix(), Nonce: hex.EncodeToString(nonce)}
+	signature.Value = compute(key, signature.Timestamp, signature.Nonce, payload)
+	return signature
+}
+
+func compute(key string, timestamp int64, nonce string, payload []byte) string {
+	mac := hmac.New(sha256.New, []byte(key))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte{'\n'})
+	mac.Write([]byte(nonce))
+	mac.Write([]byte{'\n'})
+	mac.Write(payload)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// Verifier checks signatures and rejects replayed ones: signature is accepted only within allowed clock skew
+// and its nonce is accepted only once
+type Verifier struct {
+	key      string
+	maxSkew  time.Duration
+	now      func() time.Time
+	mu       sync.Mutex
+	nonces   map[string]time.Time
+	prunedAt time.Time
+}
+
+func NewVerifier(key string, maxSkew time.Duration) *Verifier {
+	return &Verifier{key: key, maxSkew: maxSkew, now: time.Now, nonces: make(map[string]time.Time)}
+}
+
+// NewDefaultVerifier makes verifier with default clock skew, nil is returned for empty key so signatures are not checked.
+// Process should have the only verifier shared by all APIs, otherwise nonce accepted by one API can be replayed to another
+func NewDefaultVerifier(key string) *Verifier {
+	if key == "" {
+		return nil
+	}
+	return NewVerifier(key, DefaultMaxSkew)
+}
+
+func (v *Verifier) Verify(signature Signature, payload []byte) error {
+	if signature.Value == "" || signature.Nonce == "" || signature.Timestamp == 0 {
+		return ErrMissingSignature
+	}
+	expected := compute(v.key, signature.Timestamp, signature.Nonce, payload)
+	if !hmac.Equal([]byte(signature.Value), []byte(expected)) {
+		return ErrInvalidSignature
+	}
+
+	now := v.now()
+	signedAt := time.Unix(signature.Timestamp, 0)
+	if signedAt.Before(now.Add(-v.maxSkew)) || signedAt.After(now.Add(v.maxSkew)) {
+		return fmt.Errorf("%w: signed at %s", ErrExpired, signedAt.UTC().Format(time.RFC3339))
+	}
+
+	v.mu.Lock()
+	defer v.mu.Unlock()
+
+	// nonce of expired signature can't be used anyway, so it's forgotten
+	if now.Sub(v.prunedAt) >= time.Second {
+		for nonce, expiresAt := range v.nonces {
+			if now.After(expiresAt) {
+				delete(v.nonces, nonce)
+			}
+		}
+		v.prunedAt = now
+	}
+	if _, ok := v.nonces[signature.Nonce]; ok {
+		return ErrReplayed
+	}
+	v.nonces[signature.Nonce] = signedAt.Add(v.maxSkew)
+	return nil
+}
+
+// SignedMessage is streamed message carrying signature of its metrics
+type SignedMessage interface {
+	GetMetrics() []*metrics.Metric
+	GetHash() string
+	GetTimestamp() int64
+	GetNonce() string
+}
+
+// VerifyMessage checks signature of streamed message
+func (v *Verifier) VerifyMessage(message SignedMessage) error {
+	payload, err := MetricsPayload(message.GetMetrics())
+	if err != nil {
+		return err
+	}
+	return v.Verify(Signature{Value: message.GetHash(), Timestamp: message.GetTimestamp(), Nonce: message.GetNonce()}, payload)
+}
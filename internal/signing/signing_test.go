@@ -0,0 +1,59 @@
+package signing
+
+import (
+	"github.com/desepticon55/metrics-collector/proto/metrics"
+	"github.com/stretchr/testify/assert"
+	"net/http"
+	"testing"
+	"time"
+)
+
+func TestVerifier_Verify(t *testing.T) {
+	payload, err := MetricsPayload([]*metrics.Metric{{Id: "Alloc", Type: "gauge", Value: 1}})
+	assert.NoError(t, err)
+	verifier := NewVerifier("key", time.Minute)
+
+	signature := Sign("key", payload)
+	assert.NoError(t, verifier.Verify(signature, payload))
+	assert.ErrorIs(t, verifier.Verify(signature, payload), ErrReplayed)
+
+	assert.ErrorIs(t, verifier.Verify(Sign("other", payload), payload), ErrInvalidSignature)
+	assert.ErrorIs(t, verifier.Verify(Sign("key", payload), append(payload, 1)), ErrInvalidSignature)
+	assert.ErrorIs(t, verifier.Verify(Signature{}, payload), ErrMissingSignature)
+
+	tampered := Sign("key", payload)
+	tampered.Timestamp++
+	assert.ErrorIs(t, verifier.Verify(tampered, payload), ErrInvalidSignature)
+
+	old := Sign("key", payload)
+	verifier.now = func() time.Time { return time.Now().Add(2 * time.Minute) }
+	assert.ErrorIs(t, verifier.Verify(old, payload), ErrExpired)
+}
+
+func TestMetricsPayload_IsSharedByRequests(t *testing.T) {
+	metric := &metrics.Metric{Id: "PollCount", Type: "counter", Delta: 5}
+	expected, err := MetricsPayload([]*metrics.Metric{metric})
+	assert.NoError(t, err)
+
+	for _, request := range []*metrics.MetricsRequest{
+		{Metrics: []*metrics.Metric{metric}},
+		{Metrics: []*metrics.Metric{metric}, Ip: "127.0.0.1"},
+	} {
+		payload, err := RequestPayload(request)
+		assert.NoError(t, err)
+		assert.Equal(t, expected, payload)
+	}
+
+	batch := &metrics.MetricsBatch{AgentId: "agent", Seq: 1, Metrics: []*metrics.Metric{metric}}
+	signature := Sign("key", expected)
+	batch.Hash, batch.Timestamp, batch.Nonce = signature.Value, signature.Timestamp, signature.Nonce
+	assert.NoError(t, NewVerifier("key", DefaultMaxSkew).VerifyMessage(batch))
+}
+
+func TestSignature_Headers(t *testing.T) {
+	signature := Sign("key", []byte("payload"))
+	header := make(http.Header)
+	signature.SetHeaders(header)
+	assert.Equal(t, signature.Value, header.Get("HashSHA256"))
+	assert.Equal(t, signature, FromHeaders(header))
+}
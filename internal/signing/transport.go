@@ -0,0 +1,78 @@
+package signing
+
+import (
+	"context"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/protobuf/proto"
+	"net/http"
+	"strconv"
+)
+
+// HTTP headers of signature. gRPC metadata keys are the same names in lower case
+const (
+	SignatureHeader = "HashSHA256"
+	TimestampHeader = "X-Signature-Timestamp"
+	NonceHeader     = "X-Signature-Nonce"
+)
+
+var (
+	signatureKey = http.CanonicalHeaderKey(SignatureHeader)
+	timestampKey = http.CanonicalHeaderKey(TimestampHeader)
+	nonceKey     = http.CanonicalHeaderKey(NonceHeader)
+)
+
+func (s Signature) SetHeaders(header http.Header) {
+	header.Set(signatureKey, s.Value)
+	header.Set(timestampKey, strconv.FormatInt(s.Timestamp, 10))
+	header.Set(nonceKey, s.Nonce)
+}
+
+func FromHeaders(header http.Header) Signature {
+	timestamp, _ := strconv.ParseInt(header.Get(timestampKey), 10, 64)
+	return Signature{Value: header.Get(signatureKey), Timestamp: timestamp, Nonce: header.Get(nonceKey)}
+}
+
+func (s Signature) appendToOutgoingContext(ctx context.Context) context.Context {
+	return metadata.AppendToOutgoingContext(ctx,
+		signatureKey, s.Value,
+		timestampKey, strconv.FormatInt(s.Timestamp, 10),
+		nonceKey, s.Nonce)
+}
+
+// FromIncomingContext returns signature of gRPC call from its metadata
+func FromIncomingContext(ctx context.Context) Signature {
+	md, _ := metadata.FromIncomingContext(ctx)
+	header := make(http.Header, 3)
+	for _, key := range []string{signatureKey, timestampKey, nonceKey} {
+		if values := md.Get(key); len(values) > 0 {
+			header.Set(key, values[0])
+		}
+	}
+	return FromHeaders(header)
+}
+
+// DialOptions sign every call of client connection. Unary call is signed with RequestPayload of its request,
+// streaming call is signed with its method name. Nothing is signed without key
+func DialOptions(key string) []grpc.DialOption {
+	if key == "" {
+		return nil
+	}
+
+	return []grpc.DialOption{
+		grpc.WithChainUnaryInterceptor(func(ctx context.Context, method string, req, reply any, cc *grpc.ClientConn, invoker grpc.UnaryInvoker, opts ...grpc.CallOption) error {
+			message, ok := req.(proto.Message)
+			if !ok {
+				return invoker(ctx, method, req, reply, cc, opts...)
+			}
+			payload, err := RequestPayload(message)
+			if err != nil {
+				return err
+			}
+			return invoker(Sign(key, payload).appendToOutgoingContext(ctx), method, req, reply, cc, opts...)
+		}),
+		grpc.WithChainStreamInterceptor(func(ctx context.Context, desc *grpc.StreamDesc, cc *grpc.ClientConn, method string, streamer grpc.Streamer, opts ...grpc.CallOption) (grpc.ClientStream, error) {
+			return streamer(Sign(key, []byte(method)).appendToOutgoingContext(ctx), desc, cc, method, opts...)
+		}),
+	}
+}
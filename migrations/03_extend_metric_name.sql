@@ -0,0 +1,5 @@
+-- +goose Up
+ALTER TABLE mtr_collector.metrics ALTER COLUMN name TYPE VARCHAR(255);
+
+-- +goose Down
+ALTER TABLE mtr_collector.metrics ALTER COLUMN name TYPE VARCHAR(50);
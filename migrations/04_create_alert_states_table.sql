@@ -0,0 +1,15 @@
+-- +goose Up
+CREATE TABLE mtr_collector.alert_states
+(
+    rule        VARCHAR(255),
+    series      VARCHAR(255),
+    status      VARCHAR(20)      NOT NULL,
+    value       DOUBLE PRECISION NOT NULL,
+    active_at   TIMESTAMPTZ      NOT NULL,
+    fired_at    TIMESTAMPTZ,
+    resolved_at TIMESTAMPTZ,
+    PRIMARY KEY (rule, series)
+);
+
+-- +goose Down
+DROP TABLE mtr_collector.alert_states;
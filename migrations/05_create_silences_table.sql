@@ -0,0 +1,17 @@
+-- +goose Up
+CREATE TABLE mtr_collector.silences
+(
+    id         VARCHAR(32) PRIMARY KEY,
+    rule       VARCHAR(255) NOT NULL,
+    metric     VARCHAR(255) NOT NULL,
+    labels     JSONB,
+    starts_at  TIMESTAMPTZ  NOT NULL,
+    ends_at    TIMESTAMPTZ  NOT NULL,
+    recurrence JSONB,
+    created_by VARCHAR(255) NOT NULL,
+    comment    TEXT         NOT NULL,
+    created_at TIMESTAMPTZ  NOT NULL
+);
+
+-- +goose Down
+DROP TABLE mtr_collector.silences;
@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE mtr_collector.slo_checkpoints
+(
+    slo   VARCHAR(255),
+    time  TIMESTAMPTZ,
+    good  DOUBLE PRECISION NOT NULL,
+    total DOUBLE PRECISION NOT NULL,
+    PRIMARY KEY (slo, time)
+);
+
+-- +goose Down
+DROP TABLE mtr_collector.slo_checkpoints;
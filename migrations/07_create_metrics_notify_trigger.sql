@@ -0,0 +1,20 @@
+-- +goose Up
+-- +goose StatementBegin
+CREATE FUNCTION mtr_collector.notify_metric_change() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_notify('mtr_collector_metrics', json_build_object('name', NEW.name, 'type', NEW.type, 'value', NEW.value)::TEXT);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER metrics_notify_change
+    AFTER INSERT OR UPDATE
+    ON mtr_collector.metrics
+    FOR EACH ROW
+EXECUTE FUNCTION mtr_collector.notify_metric_change();
+
+-- +goose Down
+DROP TRIGGER metrics_notify_change ON mtr_collector.metrics;
+DROP FUNCTION mtr_collector.notify_metric_change();
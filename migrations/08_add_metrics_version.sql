@@ -0,0 +1,46 @@
+-- +goose Up
+CREATE SEQUENCE mtr_collector.metrics_version_seq;
+ALTER TABLE mtr_collector.metrics ADD COLUMN version BIGINT NOT NULL DEFAULT nextval('mtr_collector.metrics_version_seq');
+
+-- +goose StatementBegin
+CREATE FUNCTION mtr_collector.set_metric_version() RETURNS TRIGGER AS
+$$
+BEGIN
+    NEW.version := nextval('mtr_collector.metrics_version_seq');
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+CREATE TRIGGER metrics_set_version
+    BEFORE INSERT OR UPDATE
+    ON mtr_collector.metrics
+    FOR EACH ROW
+EXECUTE FUNCTION mtr_collector.set_metric_version();
+
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION mtr_collector.notify_metric_change() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_notify('mtr_collector_metrics',
+                      json_build_object('name', NEW.name, 'type', NEW.type, 'value', NEW.value, 'version', NEW.version)::TEXT);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+-- +goose Down
+-- +goose StatementBegin
+CREATE OR REPLACE FUNCTION mtr_collector.notify_metric_change() RETURNS TRIGGER AS
+$$
+BEGIN
+    PERFORM pg_notify('mtr_collector_metrics', json_build_object('name', NEW.name, 'type', NEW.type, 'value', NEW.value)::TEXT);
+    RETURN NEW;
+END;
+$$ LANGUAGE plpgsql;
+-- +goose StatementEnd
+
+DROP TRIGGER metrics_set_version ON mtr_collector.metrics;
+DROP FUNCTION mtr_collector.set_metric_version();
+ALTER TABLE mtr_collector.metrics DROP COLUMN version;
+DROP SEQUENCE mtr_collector.metrics_version_seq;
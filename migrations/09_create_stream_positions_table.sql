@@ -0,0 +1,12 @@
+-- +goose Up
+CREATE TABLE mtr_collector.stream_positions
+(
+    agent_id    VARCHAR(512) PRIMARY KEY,
+    seq         BIGINT      NOT NULL,
+    partial_seq BIGINT      NOT NULL,
+    unsaved     JSONB,
+    last_seen   TIMESTAMPTZ NOT NULL
+);
+
+-- +goose Down
+DROP TABLE mtr_collector.stream_positions;
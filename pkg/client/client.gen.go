@@ -0,0 +1,524 @@
+// Code generated by openapi-client-gen. DO NOT EDIT.
+
+package client
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// AlertRuleStatus schema
+type AlertRuleStatus struct {
+	Alerts     []AlertState `json:"alerts"`
+	Expr       string       `json:"expr"`
+	Hysteresis *float64     `json:"hysteresis,omitempty"`
+	Name       string       `json:"name"`
+}
+
+// AlertState schema
+type AlertState struct {
+	ActiveAt   time.Time   `json:"active_at"`
+	FiredAt    *time.Time  `json:"fired_at,omitempty"`
+	ResolvedAt *time.Time  `json:"resolved_at,omitempty"`
+	Rule       string      `json:"rule"`
+	Series     string      `json:"series"`
+	Status     AlertStatus `json:"status"`
+	Value      float64     `json:"value"`
+}
+
+// AlertStatus schema
+type AlertStatus string
+
+const (
+	AlertStatusPending  AlertStatus = "pending"
+	AlertStatusFiring   AlertStatus = "firing"
+	AlertStatusResolved AlertStatus = "resolved"
+)
+
+// AnomalyBaseline schema
+type AnomalyBaseline struct {
+	Anomalous bool       `json:"anomalous"`
+	Mean      float64    `json:"mean"`
+	Metric    string     `json:"metric"`
+	Samples   int        `json:"samples"`
+	Since     *time.Time `json:"since,omitempty"`
+	StdDev    float64    `json:"std_dev"`
+	Value     float64    `json:"value"`
+	ZScore    float64    `json:"z_score"`
+}
+
+// FailedMetric schema
+type FailedMetric struct {
+	Error string     `json:"error"`
+	ID    string     `json:"id"`
+	Type  MetricType `json:"type"`
+}
+
+// FieldError schema
+type FieldError struct {
+	Field string `json:"field"`
+	// Position of metric in batch request
+	Index   *int   `json:"index,omitempty"`
+	Message string `json:"message"`
+	Rule    string `json:"rule"`
+}
+
+// ForecastResponse schema
+//
+// Slope is change of value per second. Crossing is filled when threshold is requested and trend reaches it
+type ForecastResponse struct {
+	At        time.Time  `json:"at"`
+	CrossesAt *time.Time `json:"crosses_at,omitempty"`
+	CrossesIn *string    `json:"crosses_in,omitempty"`
+	ID        string     `json:"id"`
+	Method    string     `json:"method"`
+	Samples   int        `json:"samples"`
+	Slope     float64    `json:"slope"`
+	Threshold *float64   `json:"threshold,omitempty"`
+	Type      MetricType `json:"type"`
+	Value     float64    `json:"value"`
+	Window    string     `json:"window"`
+}
+
+// MetricRequest schema
+//
+// Delta is required for counter, value is required for gauge
+type MetricRequest struct {
+	Delta *int64     `json:"delta,omitempty"`
+	ID    string     `json:"id"`
+	Type  MetricType `json:"type"`
+	Value *float64   `json:"value,omitempty"`
+}
+
+// MetricRequestV2 schema
+//
+// Delta is required and allowed only for counter, value is required and allowed only for gauge
+type MetricRequestV2 struct {
+	Delta *int64     `json:"delta,omitempty"`
+	ID    string     `json:"id"`
+	Type  MetricType `json:"type"`
+	Value *float64   `json:"value,omitempty"`
+}
+
+// MetricResponse schema
+type MetricResponse struct {
+	Delta *int64 `json:"delta,omitempty"`
+	ID    string `json:"id"`
+	// Per-second rate of counter, filled when history is available
+	Rate  *float64   `json:"rate,omitempty"`
+	Type  MetricType `json:"type"`
+	Value *float64   `json:"value,omitempty"`
+}
+
+// MetricType schema
+type MetricType string
+
+const (
+	MetricTypeGauge   MetricType = "gauge"
+	MetricTypeCounter MetricType = "counter"
+)
+
+// NotificationRecord schema
+type NotificationRecord struct {
+	Alerts    []string  `json:"alerts"`
+	Attempts  int       `json:"attempts"`
+	Channel   string    `json:"channel"`
+	Delivered bool      `json:"delivered"`
+	Error     *string   `json:"error,omitempty"`
+	Rule      string    `json:"rule"`
+	Time      time.Time `json:"time"`
+}
+
+// PartialSave schema
+type PartialSave struct {
+	Failed []FailedMetric   `json:"failed"`
+	Saved  []MetricResponse `json:"saved"`
+}
+
+// Problem schema
+type Problem struct {
+	Code      string       `json:"code"`
+	Detail    *string      `json:"detail,omitempty"`
+	Errors    []FieldError `json:"errors,omitempty"`
+	Instance  *string      `json:"instance,omitempty"`
+	RequestID *string      `json:"request_id,omitempty"`
+	Status    int          `json:"status"`
+	Title     string       `json:"title"`
+	Type      string       `json:"type"`
+}
+
+// RateResponse schema
+type RateResponse struct {
+	ID      string     `json:"id"`
+	Rate    float64    `json:"rate"`
+	Samples int        `json:"samples"`
+	Type    MetricType `json:"type"`
+	Window  string     `json:"window"`
+}
+
+// Recurrence schema
+type Recurrence struct {
+	Duration string   `json:"duration"`
+	Start    string   `json:"start"`
+	Weekdays []string `json:"weekdays,omitempty"`
+}
+
+// ReplicationStatus schema
+type ReplicationStatus struct {
+	Connected     bool       `json:"connected"`
+	Epoch         *string    `json:"epoch,omitempty"`
+	LastAppliedAt *time.Time `json:"last_applied_at,omitempty"`
+	LastSeq       uint64     `json:"last_seq"`
+	Primary       *string    `json:"primary,omitempty"`
+	Role          string     `json:"role"`
+}
+
+// SLOStatus schema
+type SLOStatus struct {
+	Attainment           float64            `json:"attainment"`
+	BurnRates            map[string]float64 `json:"burn_rates"`
+	ErrorBudgetRemaining float64            `json:"error_budget_remaining"`
+	Good                 string             `json:"good"`
+	Name                 string             `json:"name"`
+	Target               float64            `json:"target"`
+	Total                string             `json:"total"`
+	UpdatedAt            time.Time          `json:"updated_at"`
+	Window               string             `json:"window"`
+}
+
+// Silence schema
+//
+// Silence mutes alerts matching rule, metric and labels. Empty matchers match everything
+type Silence struct {
+	Comment    *string           `json:"comment,omitempty"`
+	CreatedAt  *time.Time        `json:"created_at,omitempty"`
+	CreatedBy  *string           `json:"created_by,omitempty"`
+	EndsAt     time.Time         `json:"ends_at"`
+	ID         *string           `json:"id,omitempty"`
+	Labels     map[string]string `json:"labels,omitempty"`
+	Metric     *string           `json:"metric,omitempty"`
+	Recurrence *Recurrence       `json:"recurrence,omitempty"`
+	Rule       *string           `json:"rule,omitempty"`
+	StartsAt   *time.Time        `json:"starts_at,omitempty"`
+}
+
+// FindAllMetrics calls GET /
+//
+// Returns all metrics. JSON is sent with text/html content type unless Accept header is set
+func (c *Client) FindAllMetrics(ctx context.Context) ([]MetricResponse, error) {
+	req := newRequest(http.MethodGet, "/")
+	return decodeJSON[[]MetricResponse](c.do(ctx, req))
+}
+
+// FindAllAlerts calls GET /alerts
+//
+// Returns alert rules with their alerts
+func (c *Client) FindAllAlerts(ctx context.Context) ([]AlertRuleStatus, error) {
+	req := newRequest(http.MethodGet, "/alerts")
+	return decodeJSON[[]AlertRuleStatus](c.do(ctx, req))
+}
+
+// FindAllAnomalies calls GET /anomalies
+//
+// Returns baselines of gauges checked for anomalies
+func (c *Client) FindAllAnomalies(ctx context.Context) ([]AnomalyBaseline, error) {
+	req := newRequest(http.MethodGet, "/anomalies")
+	return decodeJSON[[]AnomalyBaseline](c.do(ctx, req))
+}
+
+// ListMetricsV2 calls GET /api/v2/metrics
+//
+// Returns all metrics
+func (c *Client) ListMetricsV2(ctx context.Context) ([]MetricResponse, error) {
+	req := newRequest(http.MethodGet, "/api/v2/metrics")
+	return decodeJSON[[]MetricResponse](c.do(ctx, req))
+}
+
+// SaveMetricsV2Params is query and header parameters of SaveMetricsV2
+type SaveMetricsV2Params struct {
+	// HMAC-SHA256 of timestamp, nonce and deterministic protobuf serialization of metrics
+	HashSHA256 *string
+	// Unix seconds of signature
+	XSignatureTimestamp *int64
+	// Random nonce of signature, it's accepted only once
+	XSignatureNonce *string
+}
+
+// SaveMetricsV2 calls POST /api/v2/metrics
+//
+// Saves batch of metrics, nothing is saved when any metric is invalid. Request is signed when server has key
+func (c *Client) SaveMetricsV2(ctx context.Context, body []MetricRequestV2, params *SaveMetricsV2Params) ([]MetricResponse, error) {
+	req := newRequest(http.MethodPost, "/api/v2/metrics")
+	req.contentType = "application/json"
+	req.body = body
+	if params != nil {
+		if params.HashSHA256 != nil {
+			req.header.Set("HashSHA256", *params.HashSHA256)
+		}
+		if params.XSignatureTimestamp != nil {
+			req.header.Set("X-Signature-Timestamp", fmt.Sprint(*params.XSignatureTimestamp))
+		}
+		if params.XSignatureNonce != nil {
+			req.header.Set("X-Signature-Nonce", *params.XSignatureNonce)
+		}
+	}
+	return decodeJSON[[]MetricResponse](c.do(ctx, req))
+}
+
+// FindMetricV2 calls GET /api/v2/metrics/{type}/{name}
+//
+// Returns metric
+func (c *Client) FindMetricV2(ctx context.Context, metricType MetricType, name string) (*MetricResponse, error) {
+	req := newRequest(http.MethodGet, "/api/v2/metrics/"+url.PathEscape(string(metricType))+"/"+url.PathEscape(name))
+	return decodeJSON[*MetricResponse](c.do(ctx, req))
+}
+
+// ForecastGaugeParams is query and header parameters of ForecastGauge
+type ForecastGaugeParams struct {
+	Method *string
+	// Go duration of history, 1h by default
+	Window *string
+	// Go duration of projection, 1h by default
+	Ahead     *string
+	Threshold *float64
+}
+
+// ForecastGauge calls GET /forecast/gauge/{name}
+//
+// Projects gauge using its recent history
+func (c *Client) ForecastGauge(ctx context.Context, name string, params *ForecastGaugeParams) (*ForecastResponse, error) {
+	req := newRequest(http.MethodGet, "/forecast/gauge/"+url.PathEscape(name))
+	if params != nil {
+		if params.Method != nil {
+			req.query.Set("method", *params.Method)
+		}
+		if params.Window != nil {
+			req.query.Set("window", *params.Window)
+		}
+		if params.Ahead != nil {
+			req.query.Set("ahead", *params.Ahead)
+		}
+		if params.Threshold != nil {
+			req.query.Set("threshold", fmt.Sprint(*params.Threshold))
+		}
+	}
+	return decodeJSON[*ForecastResponse](c.do(ctx, req))
+}
+
+// FindAllNotifications calls GET /notifications
+//
+// Returns recent notifications
+func (c *Client) FindAllNotifications(ctx context.Context) ([]NotificationRecord, error) {
+	req := newRequest(http.MethodGet, "/notifications")
+	return decodeJSON[[]NotificationRecord](c.do(ctx, req))
+}
+
+// GetOpenAPI calls GET /openapi.json
+//
+// Returns this specification
+func (c *Client) GetOpenAPI(ctx context.Context) (map[string]any, error) {
+	req := newRequest(http.MethodGet, "/openapi.json")
+	return decodeJSON[map[string]any](c.do(ctx, req))
+}
+
+// Ping calls GET /ping
+//
+// Checks database connection
+func (c *Client) Ping(ctx context.Context) error {
+	req := newRequest(http.MethodGet, "/ping")
+	return discard(c.do(ctx, req))
+}
+
+// FindCounterRateParams is query and header parameters of FindCounterRate
+type FindCounterRateParams struct {
+	// Go duration, 1m by default
+	Window *string
+}
+
+// FindCounterRate calls GET /rate/counter/{name}
+//
+// Returns per-second rate of counter over window
+func (c *Client) FindCounterRate(ctx context.Context, name string, params *FindCounterRateParams) (*RateResponse, error) {
+	req := newRequest(http.MethodGet, "/rate/counter/"+url.PathEscape(name))
+	if params != nil {
+		if params.Window != nil {
+			req.query.Set("window", *params.Window)
+		}
+	}
+	return decodeJSON[*RateResponse](c.do(ctx, req))
+}
+
+// FindReplicationStatus calls GET /replication
+//
+// Returns replication status of follower
+func (c *Client) FindReplicationStatus(ctx context.Context) (*ReplicationStatus, error) {
+	req := newRequest(http.MethodGet, "/replication")
+	return decodeJSON[*ReplicationStatus](c.do(ctx, req))
+}
+
+// Promote calls POST /replication/promote
+//
+// Promotes follower to primary
+func (c *Client) Promote(ctx context.Context) (*ReplicationStatus, error) {
+	req := newRequest(http.MethodPost, "/replication/promote")
+	return decodeJSON[*ReplicationStatus](c.do(ctx, req))
+}
+
+// FindAllSilencesParams is query and header parameters of FindAllSilences
+type FindAllSilencesParams struct {
+	// Include expired silences
+	All *bool
+}
+
+// FindAllSilences calls GET /silences
+//
+// Returns not expired silences
+func (c *Client) FindAllSilences(ctx context.Context, params *FindAllSilencesParams) ([]Silence, error) {
+	req := newRequest(http.MethodGet, "/silences")
+	if params != nil {
+		if params.All != nil {
+			req.query.Set("all", fmt.Sprint(*params.All))
+		}
+	}
+	return decodeJSON[[]Silence](c.do(ctx, req))
+}
+
+// CreateSilence calls POST /silences
+//
+// Creates silence of alerts
+func (c *Client) CreateSilence(ctx context.Context, body Silence) (*Silence, error) {
+	req := newRequest(http.MethodPost, "/silences")
+	req.contentType = "application/json"
+	req.body = body
+	return decodeJSON[*Silence](c.do(ctx, req))
+}
+
+// ExpireSilence calls DELETE /silences/{id}
+//
+// Expires silence
+func (c *Client) ExpireSilence(ctx context.Context, id string) (*Silence, error) {
+	req := newRequest(http.MethodDelete, "/silences/"+url.PathEscape(id))
+	return decodeJSON[*Silence](c.do(ctx, req))
+}
+
+// FindAllSLOs calls GET /slo
+//
+// Returns statuses of service level objectives
+func (c *Client) FindAllSLOs(ctx context.Context) ([]SLOStatus, error) {
+	req := newRequest(http.MethodGet, "/slo")
+	return decodeJSON[[]SLOStatus](c.do(ctx, req))
+}
+
+// StreamMetricsParams is query and header parameters of StreamMetrics
+type StreamMetricsParams struct {
+	// Metric name pattern in path.Match syntax
+	Name *string
+	Type *MetricType
+	// ID of the last received event
+	Resume *uint64
+	// Used instead of resume by SSE clients
+	LastEventID *uint64
+}
+
+// StreamMetrics calls GET /stream
+//
+// Streams changes of metrics as server-sent events, WebSocket is used when connection upgrade is requested
+func (c *Client) StreamMetrics(ctx context.Context, params *StreamMetricsParams) (*http.Response, error) {
+	req := newRequest(http.MethodGet, "/stream")
+	if params != nil {
+		if params.Name != nil {
+			req.query.Set("name", *params.Name)
+		}
+		if params.Type != nil {
+			req.query.Set("type", string(*params.Type))
+		}
+		if params.Resume != nil {
+			req.query.Set("resume", fmt.Sprint(*params.Resume))
+		}
+		if params.LastEventID != nil {
+			req.header.Set("Last-Event-ID", fmt.Sprint(*params.LastEventID))
+		}
+	}
+	req.header.Set("Accept", "text/event-stream")
+	return c.do(ctx, req)
+}
+
+// CreateMetricFromJSON calls POST /update/
+//
+// Sets gauge or adds delta to counter and returns new value
+func (c *Client) CreateMetricFromJSON(ctx context.Context, body MetricRequest) (*MetricResponse, error) {
+	req := newRequest(http.MethodPost, "/update/")
+	req.contentType = "application/json"
+	req.body = body
+	return decodeJSON[*MetricResponse](c.do(ctx, req))
+}
+
+// CreateMetric calls POST /update/{type}/{name}/{value}
+//
+// Sets gauge or adds delta to counter
+func (c *Client) CreateMetric(ctx context.Context, metricType MetricType, name string, value string) error {
+	req := newRequest(http.MethodPost, "/update/"+url.PathEscape(string(metricType))+"/"+url.PathEscape(name)+"/"+url.PathEscape(value))
+	return discard(c.do(ctx, req))
+}
+
+// CreateMetricsParams is query and header parameters of CreateMetrics
+type CreateMetricsParams struct {
+	// HMAC-SHA256 of timestamp, nonce and deterministic protobuf serialization of metrics
+	HashSHA256 *string
+	// Unix seconds of signature
+	XSignatureTimestamp *int64
+	// Random nonce of signature, it's accepted only once
+	XSignatureNonce *string
+}
+
+// CreateMetrics calls POST /updates/
+//
+// Saves batch of metrics. Request is signed when server has key, signed body is read before saving and limited to 32 MiB
+func (c *Client) CreateMetrics(ctx context.Context, body []MetricRequest, params *CreateMetricsParams) ([]MetricResponse, error) {
+	req := newRequest(http.MethodPost, "/updates/")
+	req.contentType = "application/json"
+	req.body = body
+	if params != nil {
+		if params.HashSHA256 != nil {
+			req.header.Set("HashSHA256", *params.HashSHA256)
+		}
+		if params.XSignatureTimestamp != nil {
+			req.header.Set("X-Signature-Timestamp", fmt.Sprint(*params.XSignatureTimestamp))
+		}
+		if params.XSignatureNonce != nil {
+			req.header.Set("X-Signature-Nonce", *params.XSignatureNonce)
+		}
+	}
+	return decodeJSON[[]MetricResponse](c.do(ctx, req))
+}
+
+// FindOneMetric calls POST /value/
+//
+// Returns metric, counter is returned with its rate when history is available
+func (c *Client) FindOneMetric(ctx context.Context, body MetricRequest) (*MetricResponse, error) {
+	req := newRequest(http.MethodPost, "/value/")
+	req.contentType = "application/json"
+	req.body = body
+	return decodeJSON[*MetricResponse](c.do(ctx, req))
+}
+
+// FindMetricValue calls GET /value/{type}/{name}
+//
+// Returns value of metric. Metric is returned when Accept header is set
+func (c *Client) FindMetricValue(ctx context.Context, metricType MetricType, name string) (float64, error) {
+	req := newRequest(http.MethodGet, "/value/"+url.PathEscape(string(metricType))+"/"+url.PathEscape(name))
+	return decodeJSON[float64](c.do(ctx, req))
+}
+
+// WriteLineProtocol calls POST /write
+//
+// Saves metrics in InfluxDB line protocol, integer fields are cumulative totals saved as counter increments, float and boolean fields are gauges. Timestamps are not stored, metrics get the time they are saved
+func (c *Client) WriteLineProtocol(ctx context.Context, body string) error {
+	req := newRequest(http.MethodPost, "/write")
+	req.contentType = "text/plain"
+	req.body = body
+	return discard(c.do(ctx, req))
+}
@@ -0,0 +1,172 @@
+// Package client is typed client of metrics server HTTP API. Types and methods of operations are
+// generated from OpenAPI specification of server, this file contains transport used by them
+package client
+
+//go:generate go run ../../cmd/openapi-client-gen -o client.gen.go -package client
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"strings"
+)
+
+// HTTPDoer executes HTTP requests, it's implemented by http.Client
+type HTTPDoer interface {
+	Do(request *http.Request) (*http.Response, error)
+}
+
+// RequestEditor changes request before it's sent, for example adds authentication headers
+type RequestEditor func(ctx context.Context, request *http.Request) error
+
+type Option func(client *Client)
+
+// WithHTTPClient sets client used to execute requests, http.DefaultClient is used by default
+func WithHTTPClient(doer HTTPDoer) Option {
+	return func(client *Client) {
+		client.httpClient = doer
+	}
+}
+
+// WithRequestEditor adds editor applied to every request
+func WithRequestEditor(editor RequestEditor) Option {
+	return func(client *Client) {
+		client.editors = append(client.editors, editor)
+	}
+}
+
+type Client struct {
+	server     string
+	httpClient HTTPDoer
+	editors    []RequestEditor
+}
+
+// NewClient makes client of server with base URL, for example http://localhost:8080
+func NewClient(server string, options ...Option) *Client {
+	client := &Client{server: strings.TrimSuffix(server, "/"), httpClient: http.DefaultClient}
+	for _, option := range options {
+		option(client)
+	}
+	return client
+}
+
+// APIError is response with unsuccessful status. Problem is filled when server responds with problem details,
+// Partial is filled when only part of metrics is saved (207 status)
+type APIError struct {
+	StatusCode int
+	Body       string
+	Problem    *Problem
+	Partial    *PartialSave
+}
+
+func (e *APIError) Error() string {
+	if e.Problem != nil && e.Problem.Detail != nil {
+		return fmt.Sprintf("server responded with status %d: %s", e.StatusCode, *e.Problem.Detail)
+	}
+	return fmt.Sprintf("server responded with status %d: %s", e.StatusCode, strings.TrimSpace(e.Body))
+}
+
+type request struct {
+	method      string
+	path        string
+	query       url.Values
+	header      http.Header
+	contentType string
+	body        any
+}
+
+func newRequest(method string, path string) *request {
+	return &request{method: method, path: path, query: url.Values{}, header: http.Header{}}
+}
+
+// do sends request and returns response with successful status, response body should be closed by caller
+func (c *Client) do(ctx context.Context, req *request) (*http.Response, error) {
+	var body io.Reader
+	switch value := req.body.(type) {
+	case nil:
+	case string:
+		body = strings.NewReader(value)
+	default:
+		data, err := json.Marshal(value)
+		if err != nil {
+			return nil, fmt.Errorf("error during encode request body: %w", err)
+		}
+		body = bytes.NewReader(data)
+	}
+
+	target := c.server + req.path
+	if len(req.query) > 0 {
+		target += "?" + req.query.Encode()
+	}
+	request, err := http.NewRequestWithContext(ctx, req.method, target, body)
+	if err != nil {
+		return nil, err
+	}
+	for name, values := range req.header {
+		request.Header[name] = values
+	}
+	if req.contentType != "" {
+		request.Header.Set("Content-Type", req.contentType)
+	}
+	for _, editor := range c.editors {
+		if err := editor(ctx, request); err != nil {
+			return nil, err
+		}
+	}
+
+	response, err := c.httpClient.Do(request)
+	if err != nil {
+		return nil, err
+	}
+	if response.StatusCode >= 200 && response.StatusCode < 300 && response.StatusCode != http.StatusMultiStatus {
+		return response, nil
+	}
+
+	defer response.Body.Close()
+	data, err := io.ReadAll(response.Body)
+	if err != nil {
+		return nil, fmt.Errorf("error during read response with status %d: %w", response.StatusCode, err)
+	}
+	apiError := &APIError{StatusCode: response.StatusCode, Body: string(data)}
+	if response.StatusCode == http.StatusMultiStatus {
+		var partial PartialSave
+		if json.Unmarshal(data, &partial) == nil {
+			apiError.Partial = &partial
+		}
+	}
+	if mediaType, _, err := mime.ParseMediaType(response.Header.Get("Content-Type")); err == nil && mediaType == "application/problem+json" {
+		var problem Problem
+		if json.Unmarshal(data, &problem) == nil {
+			apiError.Problem = &problem
+		}
+	}
+	return nil, apiError
+}
+
+func decodeJSON[T any](response *http.Response, err error) (T, error) {
+	var result T
+	if err != nil {
+		return result, err
+	}
+	defer response.Body.Close()
+
+	if err := json.NewDecoder(response.Body).Decode(&result); err != nil {
+		return result, fmt.Errorf("error during decode response: %w", err)
+	}
+	return result, nil
+}
+
+func discard(response *http.Response, err error) error {
+	if err != nil {
+		return err
+	}
+	defer response.Body.Close()
+
+	_, err = io.Copy(io.Discard, response.Body)
+	return err
+}
@@ -0,0 +1,73 @@
+package client
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestClient(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(writer http.ResponseWriter, request *http.Request) {
+		switch {
+		case request.Method == http.MethodPost && request.URL.Path == "/update/":
+			assert.Equal(t, "application/json", request.Header.Get("Content-Type"))
+			assert.Equal(t, "token", request.Header.Get("Authorization"))
+			var metric MetricRequest
+			require.NoError(t, json.NewDecoder(request.Body).Decode(&metric))
+			writer.Header().Set("Content-Type", "application/json")
+			_ = json.NewEncoder(writer).Encode(MetricResponse{ID: metric.ID, Type: metric.Type, Delta: metric.Delta})
+		case request.Method == http.MethodGet && request.URL.Path == "/rate/counter/Poll Count":
+			assert.Equal(t, "5m", request.URL.Query().Get("window"))
+			writer.Header().Set("Content-Type", "application/json")
+			_, _ = io.WriteString(writer, `{"id":"Poll Count","type":"counter","window":"5m0s","rate":0.5,"samples":3}`)
+		case request.Method == http.MethodGet && request.URL.Path == "/api/v2/metrics/gauge/Alloc":
+			writer.Header().Set("Content-Type", "application/problem+json")
+			writer.WriteHeader(http.StatusNotFound)
+			_, _ = io.WriteString(writer, `{"type":"about:blank","title":"Not Found","status":404,"detail":"Metric is not found","code":"metric_not_found"}`)
+		case request.Method == http.MethodPost && request.URL.Path == "/write":
+			assert.Equal(t, "text/plain", request.Header.Get("Content-Type"))
+			writer.WriteHeader(http.StatusNoContent)
+		default:
+			http.Error(writer, "not found", http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL+"/", WithRequestEditor(func(ctx context.Context, request *http.Request) error {
+		request.Header.Set("Authorization", "token")
+		return nil
+	}))
+	ctx := context.Background()
+
+	delta := int64(5)
+	metric, err := client.CreateMetricFromJSON(ctx, MetricRequest{ID: "PollCount", Type: MetricTypeCounter, Delta: &delta})
+	require.NoError(t, err)
+	assert.Equal(t, "PollCount", metric.ID)
+	assert.Equal(t, delta, *metric.Delta)
+
+	window := "5m"
+	rate, err := client.FindCounterRate(ctx, "Poll Count", &FindCounterRateParams{Window: &window})
+	require.NoError(t, err)
+	assert.Equal(t, 0.5, rate.Rate)
+
+	_, err = client.FindMetricV2(ctx, MetricTypeGauge, "Alloc")
+	var apiError *APIError
+	require.True(t, errors.As(err, &apiError))
+	assert.Equal(t, http.StatusNotFound, apiError.StatusCode)
+	require.NotNil(t, apiError.Problem)
+	assert.Equal(t, "metric_not_found", apiError.Problem.Code)
+	assert.EqualError(t, err, "server responded with status 404: Metric is not found")
+
+	assert.NoError(t, client.WriteLineProtocol(ctx, "cpu usage=0.5"))
+
+	err = client.Ping(ctx)
+	require.True(t, errors.As(err, &apiError))
+	assert.Nil(t, apiError.Problem)
+	assert.EqualError(t, err, "server responded with status 404: not found")
+}